@@ -4,7 +4,6 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"image"
 	"image/gif"
 	"image/jpeg"
 	"log/slog"
@@ -14,8 +13,6 @@ import (
 	"github.com/ViBiOh/flags"
 	"github.com/ViBiOh/httputils/v4/pkg/logger"
 	"github.com/ViBiOh/kitten/pkg/kitten"
-	"github.com/ViBiOh/kitten/pkg/klipy"
-	"github.com/ViBiOh/kitten/pkg/unsplash"
 )
 
 const mode = 0o600
@@ -37,7 +34,7 @@ func main() {
 
 	logger.Init(ctx, loggerConfig)
 
-	kittenService := kitten.New(kittenConfig, unsplash.Service{}, klipy.Service{}, nil, nil, nil, "")
+	kittenService := kitten.NewCaptioner(kittenConfig)
 
 	if len(*input) == 0 {
 		slog.ErrorContext(ctx, "input filename is required")
@@ -82,7 +79,7 @@ func main() {
 }
 
 func generateGif(ctx context.Context, kittenService kitten.Service, input, output *os.File, caption string) error {
-	inputContent, err := gif.DecodeAll(input)
+	inputContent, err := kittenService.DecodeGif(input)
 	if err != nil {
 		return fmt.Errorf("decode gif: %w", err)
 	}
@@ -96,7 +93,7 @@ func generateGif(ctx context.Context, kittenService kitten.Service, input, outpu
 }
 
 func generateImage(ctx context.Context, kittenService kitten.Service, input, output *os.File, caption string) error {
-	inputContent, _, err := image.Decode(input)
+	inputContent, err := kittenService.DecodeImage(input)
 	if err != nil {
 		return fmt.Errorf("decode image: %w", err)
 	}
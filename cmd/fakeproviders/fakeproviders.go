@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ViBiOh/flags"
+	"github.com/ViBiOh/httputils/v4/pkg/logger"
+	"github.com/ViBiOh/kitten/pkg/fakeproviders"
+)
+
+func main() {
+	fs := flag.NewFlagSet("fakeproviders", flag.ExitOnError)
+	fs.Usage = flags.Usage(fs)
+
+	loggerConfig := logger.Flags(fs, "logger")
+
+	address := fs.String("address", "127.0.0.1", "listen address")
+	port := fs.Uint("port", 1081, "listen port")
+
+	_ = fs.Parse(os.Args[1:])
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Init(ctx, loggerConfig)
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", *address, *port),
+		Handler:           fakeproviders.New().Handler(),
+		ReadHeaderTimeout: time.Second * 5,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second*5)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.LogAttrs(shutdownCtx, slog.LevelError, "shutdown", slog.Any("error", err))
+		}
+	}()
+
+	slog.LogAttrs(ctx, slog.LevelInfo, "fake providers listening", slog.String("address", server.Addr))
+
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		logger.FatalfOnErr(ctx, err, "listen")
+	}
+}
@@ -17,8 +17,14 @@ import (
 	"github.com/ViBiOh/httputils/v4/pkg/renderer"
 	"github.com/ViBiOh/httputils/v4/pkg/server"
 	"github.com/ViBiOh/httputils/v4/pkg/telemetry"
+	"github.com/ViBiOh/kitten/pkg/apikey"
+	"github.com/ViBiOh/kitten/pkg/fetch"
+	"github.com/ViBiOh/kitten/pkg/giphy"
 	"github.com/ViBiOh/kitten/pkg/kitten"
 	"github.com/ViBiOh/kitten/pkg/klipy"
+	"github.com/ViBiOh/kitten/pkg/library"
+	"github.com/ViBiOh/kitten/pkg/ratelimit"
+	"github.com/ViBiOh/kitten/pkg/rpc"
 	"github.com/ViBiOh/kitten/pkg/unsplash"
 )
 
@@ -30,15 +36,21 @@ type configuration struct {
 	health    *health.Config
 
 	server   *server.Config
+	grpc     *rpc.Config
 	owasp    *owasp.Config
 	cors     *cors.Config
 	renderer *renderer.Config
 
-	redis *redis.Config
+	redis     *redis.Config
+	rateLimit *ratelimit.Config
+	apiKey    *apikey.Config
 
 	kitten   *kitten.Config
+	fetch    *fetch.Config
 	unsplash *unsplash.Config
 	klipy    *klipy.Config
+	giphy    *giphy.Config
+	library  *library.Config
 	slack    *slack.Config
 	discord  *discord.Config
 }
@@ -55,15 +67,21 @@ func newConfig() configuration {
 		health:    health.Flags(fs, ""),
 
 		server:   server.Flags(fs, ""),
+		grpc:     rpc.Flags(fs, "grpc"),
 		owasp:    owasp.Flags(fs, "", flags.NewOverride("Csp", "default-src 'self'; base-uri 'self'; script-src 'self' 'httputils-nonce'; style-src 'self' 'httputils-nonce'; img-src 'self' platform.slack-edge.com")),
 		cors:     cors.Flags(fs, "cors"),
 		renderer: renderer.Flags(fs, "", flags.NewOverride("Title", "KittenBot"), flags.NewOverride("PublicURL", "https://kitten.vibioh.fr")),
 
-		redis: redis.Flags(fs, "redis"),
+		redis:     redis.Flags(fs, "redis"),
+		rateLimit: ratelimit.Flags(fs, "rateLimit"),
+		apiKey:    apikey.Flags(fs, "apiKey"),
 
 		kitten:   kitten.Flags(fs, ""),
+		fetch:    fetch.Flags(fs, "fetch"),
 		unsplash: unsplash.Flags(fs, "unsplash"),
 		klipy:    klipy.Flags(fs, "klipy"),
+		giphy:    giphy.Flags(fs, "giphy"),
+		library:  library.Flags(fs, "library"),
 		slack:    slack.Flags(fs, "slack"),
 		discord:  discord.Flags(fs, "discord"),
 	}
@@ -25,8 +25,10 @@ func main() {
 
 	port := newPort(clients, services)
 
+	go services.kitten.Start(clients.health.DoneCtx())
 	go services.server.Start(clients.health.EndCtx(), port)
+	go services.grpc.Start(clients.health.EndCtx())
 
 	clients.health.WaitForTermination(services.server.Done())
-	health.WaitAll(services.server.Done())
+	health.WaitAll(services.server.Done(), services.grpc.Done(), services.kitten.Done())
 }
@@ -2,23 +2,52 @@ package main
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/ViBiOh/httputils/v4/pkg/httputils"
 	"github.com/ViBiOh/httputils/v4/pkg/renderer"
+	"github.com/ViBiOh/kitten/pkg/apikey"
+	"github.com/ViBiOh/kitten/pkg/kitten"
 )
 
 func newPort(clients clients, services services) http.Handler {
 	mux := http.NewServeMux()
+	limit := services.rateLimit.Middleware
+	render := services.apiKey.Middleware(apikey.Render, services.kitten.Signed)
+	search := services.apiKey.Middleware(apikey.Search, nil)
+	keyed := services.apiKey.Required(apikey.Render)
+	preview := services.apiKey.Middleware(apikey.Render, services.kitten.PreviewSigned)
 
-	mux.Handle("/search", services.kitten.SearchHandler())
-	mux.Handle("/gif/{content...}", services.kitten.GifHandler())
-	mux.Handle("/api/{content...}", services.kitten.Handler())
+	mux.Handle("/search", limit(search(services.kitten.SearchHandler())))
+	mux.Handle("/gif/{content...}", limit(render(services.kitten.GifHandler())))
+	mux.Handle("/api/{content...}", limit(render(services.kitten.Handler())))
+	mux.Handle("/api/render", limit(keyed(services.kitten.RenderHandler())))
+	mux.Handle("/api/render/{file}", limit(services.kitten.RenderedHandler()))
+	mux.Handle("/api/upload", limit(keyed(services.kitten.UploadHandler())))
+	mux.Handle("/api/batch", limit(keyed(services.kitten.BatchHandler())))
+	mux.Handle("/m/{id}", limit(services.kitten.ShortLinkHandler()))
+	mux.Handle("GET /preview", limit(preview(services.kitten.PreviewHandler())))
+	mux.Handle("POST /preview", limit(services.kitten.PreviewLinkHandler()))
+	mux.Handle("/oembed", limit(services.kitten.OEmbedHandler()))
+	mux.Handle(kitten.SharePrefix+"/", limit(services.renderer.Handler(func(w http.ResponseWriter, r *http.Request) (renderer.Page, error) {
+		meme, err := services.kitten.Meme(r.Context(), strings.TrimPrefix(r.URL.Path, kitten.SharePrefix))
+		if err != nil {
+			return renderer.Page{}, err
+		}
+
+		return renderer.NewPage("meme", http.StatusOK, map[string]any{
+			"Meme": meme,
+		}), nil
+	})))
+	mux.Handle("/admin/", http.StripPrefix("/admin", services.apiKey.Optional(apikey.Admin)(services.kitten.AdminHandler())))
 
 	mux.Handle("/slack/", http.StripPrefix("/slack", services.slack.NewServeMux()))
 	mux.Handle("/discord/", http.StripPrefix("/discord", services.discord.NewServeMux()))
 
 	services.renderer.RegisterMux(mux, func(w http.ResponseWriter, r *http.Request) (renderer.Page, error) {
-		return renderer.NewPage("public", http.StatusOK, nil), nil
+		return renderer.NewPage("public", http.StatusOK, map[string]any{
+			"Editor": services.kitten.Editor(r),
+		}), nil
 	})
 
 	return httputils.Handler(
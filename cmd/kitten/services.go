@@ -12,8 +12,14 @@ import (
 	"github.com/ViBiOh/httputils/v4/pkg/owasp"
 	"github.com/ViBiOh/httputils/v4/pkg/renderer"
 	"github.com/ViBiOh/httputils/v4/pkg/server"
+	"github.com/ViBiOh/kitten/pkg/apikey"
+	"github.com/ViBiOh/kitten/pkg/fetch"
+	"github.com/ViBiOh/kitten/pkg/giphy"
 	"github.com/ViBiOh/kitten/pkg/kitten"
 	"github.com/ViBiOh/kitten/pkg/klipy"
+	"github.com/ViBiOh/kitten/pkg/library"
+	"github.com/ViBiOh/kitten/pkg/ratelimit"
+	"github.com/ViBiOh/kitten/pkg/rpc"
 	"github.com/ViBiOh/kitten/pkg/unsplash"
 )
 
@@ -21,10 +27,13 @@ import (
 var content embed.FS
 
 type services struct {
-	server   *server.Server
-	owasp    owasp.Service
-	cors     cors.Service
-	renderer *renderer.Service
+	server    *server.Server
+	grpc      rpc.Service
+	owasp     owasp.Service
+	cors      cors.Service
+	renderer  *renderer.Service
+	rateLimit ratelimit.Service
+	apiKey    apikey.Service
 
 	discord discord.Service
 	slack   slack.Service
@@ -44,19 +53,41 @@ func newServices(ctx context.Context, config configuration, clients clients) (se
 		return output, fmt.Errorf("renderer: %w", err)
 	}
 
-	unsplashService := unsplash.New(ctx, config.unsplash, clients.redis, clients.telemetry.TracerProvider())
-	klipyService := klipy.New(ctx, config.klipy, clients.redis, clients.telemetry.TracerProvider())
+	output.rateLimit, err = ratelimit.New(config.rateLimit, clients.redis)
+	if err != nil {
+		return output, fmt.Errorf("rate limit: %w", err)
+	}
+
+	output.apiKey, err = apikey.New(config.apiKey, clients.redis, clients.telemetry.MeterProvider())
+	if err != nil {
+		return output, fmt.Errorf("api key: %w", err)
+	}
+
+	libraryService, err := library.New(config.library)
+	if err != nil {
+		return output, fmt.Errorf("library: %w", err)
+	}
+
+	providers := kitten.NewRegistry(config.kitten.ImageProviders, config.kitten.GifProviders).
+		WithImage(kitten.NewUnsplashProvider(unsplash.New(ctx, config.unsplash, clients.redis, clients.telemetry.TracerProvider()))).
+		WithImage(kitten.NewLibraryImageProvider(libraryService)).
+		WithGif(kitten.NewKlipyProvider(klipy.New(ctx, config.klipy, clients.redis, clients.telemetry.TracerProvider()))).
+		WithGif(kitten.NewGiphyProvider(giphy.New(ctx, config.giphy, clients.redis, clients.telemetry.TracerProvider()))).
+		WithGif(kitten.NewLibraryGifProvider(libraryService))
 
 	output.kitten = kitten.New(
 		config.kitten,
-		unsplashService,
-		klipyService,
+		providers,
+		output.rateLimit,
+		fetch.New(config.fetch, clients.telemetry.MeterProvider(), clients.telemetry.TracerProvider()),
 		clients.redis,
 		clients.telemetry.MeterProvider(),
 		clients.telemetry.TracerProvider(),
 		output.renderer.PublicURL(""),
 	)
 
+	output.grpc = rpc.New(config.grpc, output.kitten, output.apiKey)
+
 	output.discord, err = discord.New(config.discord, output.renderer.PublicURL(""), output.kitten.DiscordHandler, clients.telemetry.TracerProvider())
 	if err != nil {
 		return output, fmt.Errorf("discord: %w", err)
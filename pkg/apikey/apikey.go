@@ -0,0 +1,263 @@
+package apikey
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/ViBiOh/flags"
+	"github.com/ViBiOh/httputils/v4/pkg/httperror"
+	"github.com/ViBiOh/httputils/v4/pkg/model"
+	"github.com/ViBiOh/httputils/v4/pkg/redis"
+	"github.com/ViBiOh/kitten/pkg/version"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const Header = "X-Api-Key"
+
+type Scope string
+
+const (
+	Render Scope = "render"
+	Search Scope = "search"
+	Admin  Scope = "admin"
+)
+
+var (
+	ErrMissingKey   = errors.New("missing api key")
+	ErrUnknownKey   = errors.New("unknown api key")
+	ErrMissingScope = errors.New("api key is missing scope")
+	ErrUnavailable  = errors.New("api keys are unavailable")
+)
+
+type ctxKey struct{}
+
+type Key struct {
+	Name   string  `json:"name"`
+	Scopes []Scope `json:"scopes"`
+}
+
+// Allows checks if the key has the given scope
+func (k Key) Allows(scope Scope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+type Service struct {
+	redisClient redis.Client
+	usageMetric metric.Int64Counter
+	keys        map[string]Key
+	enabled     bool
+}
+
+type Config struct {
+	file    string
+	enabled bool
+}
+
+func Flags(fs *flag.FlagSet, prefix string, overrides ...flags.Override) *Config {
+	var config Config
+
+	flags.New("Enabled", "Require an API key on the render API").Prefix(prefix).DocPrefix("apiKey").BoolVar(fs, &config.enabled, false, overrides)
+	flags.New("File", "File of hashed API keys, one name:sha256:scopes per line").Prefix(prefix).DocPrefix("apiKey").StringVar(fs, &config.file, "", overrides)
+
+	return &config
+}
+
+func New(config *Config, redisClient redis.Client, meterProvider metric.MeterProvider) (Service, error) {
+	service := Service{
+		redisClient: redisClient,
+		enabled:     config.enabled,
+	}
+
+	var err error
+
+	if service.keys, err = loadKeys(config.file); err != nil {
+		return service, fmt.Errorf("load keys: %w", err)
+	}
+
+	if meterProvider != nil {
+		service.usageMetric, err = meterProvider.Meter("github.com/ViBiOh/kitten/pkg/apikey").Int64Counter("kitten.api_key_usage")
+		if err != nil {
+			slog.LogAttrs(context.Background(), slog.LevelError, "create api key usage counter", slog.Any("error", err))
+		}
+	}
+
+	return service, nil
+}
+
+func loadKeys(filename string) (map[string]Key, error) {
+	keys := make(map[string]Key)
+	if len(filename) == 0 {
+		return keys, nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			slog.LogAttrs(context.Background(), slog.LevelWarn, "close api keys file", slog.Any("error", closeErr))
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid line `%s`", line)
+		}
+
+		key := Key{Name: parts[0]}
+		for scope := range strings.SplitSeq(parts[2], ",") {
+			key.Scopes = append(key.Scopes, Scope(strings.TrimSpace(scope)))
+		}
+
+		keys[strings.ToLower(parts[1])] = key
+	}
+
+	return keys, scanner.Err()
+}
+
+// Hash computes the hash under which an API key is stored
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// FromContext retrieves the API key authenticated by the middleware
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(ctxKey{}).(Key)
+	return key, ok
+}
+
+func (s Service) lookup(ctx context.Context, rawKey string) (Key, error) {
+	hashed := Hash(rawKey)
+
+	if key, ok := s.keys[hashed]; ok {
+		return key, nil
+	}
+
+	if model.IsNil(s.redisClient) || !s.redisClient.Enabled() {
+		return Key{}, ErrUnknownKey
+	}
+
+	content, err := s.redisClient.Load(ctx, version.Redis("apikey:"+hashed))
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: load key: %w", ErrUnavailable, err)
+	}
+
+	if len(content) == 0 {
+		return Key{}, ErrUnknownKey
+	}
+
+	var key Key
+	if err = json.Unmarshal(content, &key); err != nil {
+		return Key{}, fmt.Errorf("parse key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Middleware requires an API key with the given scope, unless the bypass accepts the request
+func (s Service) Middleware(scope Scope, bypass func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.enabled || (bypass != nil && bypass(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			s.authenticate(w, r, next, scope, true)
+		})
+	}
+}
+
+// Required requires an API key with the given scope, even when API keys aren't enabled on the render API
+func (s Service) Required(scope Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.authenticate(w, r, next, scope, true)
+		})
+	}
+}
+
+// Optional authenticates the API key when provided, leaving authentication to the handler otherwise
+func (s Service) Optional(scope Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.authenticate(w, r, next, scope, false)
+		})
+	}
+}
+
+func (s Service) authenticate(w http.ResponseWriter, r *http.Request, next http.Handler, scope Scope, required bool) {
+	rawKey := r.Header.Get(Header)
+	if len(rawKey) == 0 && !required {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	ctx, err := s.Authenticate(r.Context(), rawKey, scope)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMissingKey), errors.Is(err, ErrUnknownKey):
+			httperror.Unauthorized(ctx, w, err)
+		case errors.Is(err, ErrMissingScope):
+			httperror.Forbidden(ctx, w)
+		case errors.Is(err, ErrUnavailable):
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		default:
+			httperror.InternalServerError(ctx, w, err)
+		}
+
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// Enabled reports if API keys are required
+func (s Service) Enabled() bool {
+	return s.enabled
+}
+
+// Authenticate checks the raw key has the given scope, returning a context carrying it
+func (s Service) Authenticate(ctx context.Context, rawKey string, scope Scope) (context.Context, error) {
+	if len(rawKey) == 0 {
+		return ctx, ErrMissingKey
+	}
+
+	key, err := s.lookup(ctx, rawKey)
+	if err != nil {
+		return ctx, err
+	}
+
+	if !key.Allows(scope) {
+		return ctx, ErrMissingScope
+	}
+
+	if !model.IsNil(s.usageMetric) {
+		s.usageMetric.Add(ctx, 1, metric.WithAttributes(attribute.String("key", key.Name), attribute.String("scope", string(scope))))
+	}
+
+	return context.WithValue(ctx, ctxKey{}, key), nil
+}
@@ -0,0 +1,330 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ViBiOh/httputils/v4/pkg/redis"
+	"github.com/ViBiOh/kitten/pkg/version"
+)
+
+var errRedisDown = errors.New("redis down")
+
+// fakeRedis serves the stored keys, or fails every load with its error
+type fakeRedis struct {
+	redis.Client
+	err     error
+	content map[string]string
+}
+
+func (fr fakeRedis) Enabled() bool {
+	return true
+}
+
+func (fr fakeRedis) Load(_ context.Context, key string) ([]byte, error) {
+	if fr.err != nil {
+		return nil, fr.err
+	}
+
+	return []byte(fr.content[key]), nil
+}
+
+func writeKeys(t *testing.T, content string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatalf("write keys: %s", err)
+	}
+
+	return filename
+}
+
+func TestLoadKeys(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		content string
+		want    map[string]Key
+		wantErr bool
+	}{
+		"empty": {
+			"",
+			map[string]Key{},
+			false,
+		},
+		"comments and blank lines": {
+			"# render keys\n\n  \nbot:ABC123:render\n",
+			map[string]Key{"abc123": {Name: "bot", Scopes: []Scope{Render}}},
+			false,
+		},
+		"scopes": {
+			"admin:def456:render, search ,admin",
+			map[string]Key{"def456": {Name: "admin", Scopes: []Scope{Render, Search, Admin}}},
+			false,
+		},
+		"invalid line": {
+			"bot:abc123",
+			nil,
+			true,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := loadKeys(writeKeys(t, testCase.content))
+
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("loadKeys() = `%v`, want error %t", err, testCase.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, testCase.want) {
+				t.Errorf("loadKeys() = %+v, want %+v", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		scopes []Scope
+		scope  Scope
+		want   bool
+	}{
+		"no scope": {
+			nil,
+			Render,
+			false,
+		},
+		"granted": {
+			[]Scope{Search, Render},
+			Render,
+			true,
+		},
+		"other scope": {
+			[]Scope{Search},
+			Admin,
+			false,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			if got := (Key{Scopes: testCase.scopes}).Allows(testCase.scope); got != testCase.want {
+				t.Errorf("Allows() = %t, want %t", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	fileKeys := map[string]Key{Hash("file"): {Name: "file", Scopes: []Scope{Render}}}
+	stored := map[string]string{
+		version.Redis("apikey:" + Hash("stored")):  `{"name":"stored","scopes":["search"]}`,
+		version.Redis("apikey:" + Hash("invalid")): `{`,
+	}
+
+	cases := map[string]struct {
+		redisClient redis.Client
+		rawKey      string
+		want        string
+		wantErr     bool
+		wantIs      error
+	}{
+		"file key": {
+			fakeRedis{err: errRedisDown},
+			"file",
+			"file",
+			false,
+			nil,
+		},
+		"no redis": {
+			nil,
+			"stored",
+			"",
+			true,
+			ErrUnknownKey,
+		},
+		"stored key": {
+			fakeRedis{content: stored},
+			"stored",
+			"stored",
+			false,
+			nil,
+		},
+		"unknown key": {
+			fakeRedis{content: stored},
+			"unknown",
+			"",
+			true,
+			ErrUnknownKey,
+		},
+		"invalid stored key": {
+			fakeRedis{content: stored},
+			"invalid",
+			"",
+			true,
+			nil,
+		},
+		"redis down": {
+			fakeRedis{err: errRedisDown},
+			"stored",
+			"",
+			true,
+			ErrUnavailable,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			instance := Service{keys: fileKeys, redisClient: testCase.redisClient}
+
+			got, err := instance.lookup(context.Background(), testCase.rawKey)
+
+			if (err != nil) != testCase.wantErr {
+				t.Fatalf("lookup() = `%v`, want error %t", err, testCase.wantErr)
+			}
+
+			if testCase.wantIs != nil && !errors.Is(err, testCase.wantIs) {
+				t.Errorf("lookup() = `%s`, want `%s`", err, testCase.wantIs)
+			}
+
+			if got.Name != testCase.want {
+				t.Errorf("lookup() = `%s`, want `%s`", got.Name, testCase.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	keys := "render:" + Hash("render") + ":render\nsearch:" + Hash("search") + ":search\n"
+	bypass := func(r *http.Request) bool {
+		return r.URL.Query().Has("signature")
+	}
+
+	cases := map[string]struct {
+		enabled     bool
+		redisClient redis.Client
+		target      string
+		rawKey      string
+		wantStatus  int
+		wantKey     string
+	}{
+		"disabled": {
+			enabled:    false,
+			target:     "/api",
+			wantStatus: http.StatusNoContent,
+		},
+		"bypass": {
+			enabled:    true,
+			target:     "/api?signature=abc",
+			wantStatus: http.StatusNoContent,
+		},
+		"missing key": {
+			enabled:    true,
+			target:     "/api",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"unknown key": {
+			enabled:    true,
+			target:     "/api",
+			rawKey:     "unknown",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"missing scope": {
+			enabled:    true,
+			target:     "/api",
+			rawKey:     "search",
+			wantStatus: http.StatusForbidden,
+		},
+		"valid key": {
+			enabled:    true,
+			target:     "/api",
+			rawKey:     "render",
+			wantStatus: http.StatusNoContent,
+			wantKey:    "render",
+		},
+		"key despite bypass": {
+			enabled:    true,
+			target:     "/api?signature=abc",
+			rawKey:     "unknown",
+			wantStatus: http.StatusNoContent,
+		},
+		"redis down": {
+			enabled:     true,
+			redisClient: fakeRedis{err: errRedisDown},
+			target:      "/api",
+			rawKey:      "unknown",
+			wantStatus:  http.StatusServiceUnavailable,
+		},
+		"file key with redis down": {
+			enabled:     true,
+			redisClient: fakeRedis{err: errRedisDown},
+			target:      "/api",
+			rawKey:      "render",
+			wantStatus:  http.StatusNoContent,
+			wantKey:     "render",
+		},
+	}
+
+	filename := writeKeys(t, keys)
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			instance, err := New(&Config{enabled: testCase.enabled, file: filename}, testCase.redisClient, nil)
+			if err != nil {
+				t.Fatalf("New() = `%s`", err)
+			}
+
+			var gotKey string
+
+			handler := instance.Middleware(Render, bypass)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if key, ok := FromContext(r.Context()); ok {
+					gotKey = key.Name
+				}
+
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			request := httptest.NewRequest(http.MethodGet, testCase.target, nil)
+			if len(testCase.rawKey) != 0 {
+				request.Header.Set(Header, testCase.rawKey)
+			}
+
+			writer := httptest.NewRecorder()
+			handler.ServeHTTP(writer, request)
+
+			if writer.Code != testCase.wantStatus {
+				t.Errorf("status = %d, want %d", writer.Code, testCase.wantStatus)
+			}
+
+			if gotKey != testCase.wantKey {
+				t.Errorf("FromContext() = `%s`, want `%s`", gotKey, testCase.wantKey)
+			}
+
+			if testCase.wantStatus == http.StatusServiceUnavailable && len(writer.Header().Get("Retry-After")) == 0 {
+				t.Error("Retry-After is missing")
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Package fakeproviders emulates the APIs of the image and gif providers, for running kitten without network nor API keys
+package fakeproviders
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// RateLimitQuery is a search or an id answered as if the API key had exhausted its quota
+	RateLimitQuery = "ratelimit"
+	// NotFoundQuery is a search or an id matching nothing
+	NotFoundQuery = "notfound"
+)
+
+// Share is a share reported to a provider, e.g. an Unsplash download or a Klipy registershare
+type Share struct {
+	Provider string
+	ID       string
+	Query    string
+}
+
+type Server struct {
+	shares []Share
+	mutex  sync.RWMutex
+}
+
+func New() *Server {
+	return &Server{}
+}
+
+// Handler serves the providers under their name, e.g. `/unsplash/photos/random`, and the medias they reference under `/media`
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /unsplash/photos/random", s.unsplashRandom)
+	mux.HandleFunc("GET /unsplash/photos/{id}", s.unsplashPhoto)
+	mux.HandleFunc("GET /unsplash/photos/{id}/download", s.unsplashDownload)
+
+	mux.HandleFunc("GET /klipy/search", s.klipySearch)
+	mux.HandleFunc("GET /klipy/posts", s.klipyPosts)
+	mux.HandleFunc("GET /klipy/registershare", s.klipyRegisterShare)
+
+	mux.HandleFunc("GET /giphy/gifs/search", s.giphySearch)
+	mux.HandleFunc("GET /giphy/gifs/{id}", s.giphyGif)
+	mux.HandleFunc("GET /giphy/pingback/{id}", s.giphyPingback)
+
+	mux.HandleFunc("GET /media/{name}", s.media)
+
+	return mux
+}
+
+// Shares returns the shares reported so far, in order
+func (s *Server) Shares() []Share {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return slices.Clone(s.shares)
+}
+
+func (s *Server) share(ctx context.Context, share Share) {
+	slog.LogAttrs(ctx, slog.LevelInfo, "share reported", slog.String("provider", share.Provider), slog.String("id", share.ID), slog.String("query", share.Query))
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.shares = append(s.shares, share)
+}
+
+// baseURL is the root of the server as seen by the client, so medias are reachable whatever the listen address
+func baseURL(r *http.Request) string {
+	if r.TLS != nil {
+		return "https://" + r.Host
+	}
+
+	return "http://" + r.Host
+}
+
+// mediaID derives a stable id from the search, so the same search always gives the same media
+func mediaID(parts ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+
+	return hex.EncodeToString(hash[:6])
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		slog.LogAttrs(context.Background(), slog.LevelError, "encode fake response", slog.Any("error", err))
+	}
+}
+
+func isRateLimited(values ...string) bool {
+	return slices.ContainsFunc(values, func(value string) bool {
+		return strings.Contains(value, RateLimitQuery)
+	})
+}
+
+func isNotFound(values ...string) bool {
+	return slices.ContainsFunc(values, func(value string) bool {
+		return strings.Contains(value, NotFoundQuery)
+	})
+}
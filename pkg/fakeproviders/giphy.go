@@ -0,0 +1,71 @@
+package fakeproviders
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// giphyResults is the total count of every Giphy search
+const giphyResults = 5
+
+func (s *Server) giphySearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	switch {
+	case isRateLimited(query):
+		w.WriteHeader(http.StatusTooManyRequests)
+	case isNotFound(query), offset >= giphyResults:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"data":       []any{},
+			"pagination": map[string]int{"total_count": 0, "count": 0, "offset": offset},
+		})
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"data":       []any{giphyGif(baseURL(r), mediaID("giphy", query, strconv.Itoa(offset)))},
+			"pagination": map[string]int{"total_count": giphyResults, "count": 1, "offset": offset},
+		})
+	}
+}
+
+func (s *Server) giphyGif(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch {
+	case isRateLimited(id):
+		w.WriteHeader(http.StatusTooManyRequests)
+	case isNotFound(id):
+		writeJSON(w, http.StatusNotFound, map[string]any{"data": map[string]any{}})
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{"data": giphyGif(baseURL(r), id)})
+	}
+}
+
+func (s *Server) giphyPingback(w http.ResponseWriter, r *http.Request) {
+	s.share(r.Context(), Share{Provider: "giphy", ID: r.PathValue("id")})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func giphyGif(root, id string) map[string]any {
+	return map[string]any{
+		"id":       id,
+		"url":      fmt.Sprintf("%s/giphy/gifs/%s", root, id),
+		"username": "fake",
+		"user": map[string]string{
+			"display_name": "Fake Animator",
+			"profile_url":  root + "/giphy/@fake",
+		},
+		"images": map[string]any{
+			"downsized": map[string]string{"url": fmt.Sprintf("%s/media/%s.gif", root, id), "size": strconv.Itoa(1 << 20)},
+		},
+		"analytics": map[string]any{
+			"onsent": map[string]string{"url": fmt.Sprintf("%s/giphy/pingback/%s", root, id)},
+		},
+	}
+}
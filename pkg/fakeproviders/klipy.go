@@ -0,0 +1,77 @@
+package fakeproviders
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// klipyResults is the length of every Klipy search, the last result having no next position
+const klipyResults = 5
+
+func (s *Server) klipySearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+
+	pos, err := strconv.Atoi(r.URL.Query().Get("pos"))
+	if err != nil || pos < 0 {
+		pos = 0
+	}
+
+	switch {
+	case isRateLimited(query):
+		w.WriteHeader(http.StatusTooManyRequests)
+	case isNotFound(query), pos >= klipyResults:
+		writeJSON(w, http.StatusOK, map[string]any{"results": []any{}, "next": ""})
+	default:
+		var next string
+		if pos+1 < klipyResults {
+			next = strconv.Itoa(pos + 1)
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"results": []any{klipyGif(baseURL(r), mediaID("klipy", query, strconv.Itoa(pos)))},
+			"next":    next,
+		})
+	}
+}
+
+func (s *Server) klipyPosts(w http.ResponseWriter, r *http.Request) {
+	ids := r.URL.Query().Get("ids")
+
+	switch {
+	case isRateLimited(ids):
+		w.WriteHeader(http.StatusTooManyRequests)
+	case isNotFound(ids):
+		writeJSON(w, http.StatusOK, map[string]any{"results": []any{}})
+	default:
+		var results []any
+
+		for id := range strings.SplitSeq(ids, ",") {
+			if len(id) != 0 {
+				results = append(results, klipyGif(baseURL(r), id))
+			}
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{"results": results})
+	}
+}
+
+func (s *Server) klipyRegisterShare(w http.ResponseWriter, r *http.Request) {
+	s.share(r.Context(), Share{Provider: "klipy", ID: r.URL.Query().Get("id"), Query: r.URL.Query().Get("q")})
+
+	writeJSON(w, http.StatusOK, map[string]any{})
+}
+
+func klipyGif(root, id string) map[string]any {
+	url := fmt.Sprintf("%s/media/%s.gif", root, id)
+
+	return map[string]any{
+		"id":      id,
+		"itemurl": fmt.Sprintf("%s/klipy/gifs/%s", root, id),
+		"media_formats": map[string]any{
+			"mediumgif": map[string]any{"url": url, "size": 1 << 20},
+			"tinygif":   map[string]any{"url": url, "size": 256 << 10},
+		},
+	}
+}
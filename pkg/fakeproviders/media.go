@@ -0,0 +1,112 @@
+package fakeproviders
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const (
+	imageWidth  = 800
+	imageHeight = 533
+	gifWidth    = 320
+	gifHeight   = 240
+	gifFrames   = 4
+)
+
+// media generates a plain image or gif, its colors derived from the id so each media looks different
+func (s *Server) media(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	id := strings.TrimSuffix(name, path.Ext(name))
+
+	if isNotFound(id) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var (
+		buffer      bytes.Buffer
+		contentType string
+		err         error
+	)
+
+	switch path.Ext(name) {
+	case ".jpg":
+		contentType = "image/jpeg"
+		err = jpeg.Encode(&buffer, stripes(imageWidth, imageHeight, mediaColors(id), 0), &jpeg.Options{Quality: 80})
+	case ".gif":
+		contentType = "image/gif"
+		err = gif.EncodeAll(&buffer, animation(mediaColors(id)))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		slog.LogAttrs(r.Context(), slog.LevelError, "encode fake media", slog.String("name", name), slog.Any("error", err))
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+
+	if _, err = buffer.WriteTo(w); err != nil {
+		slog.LogAttrs(r.Context(), slog.LevelError, "write fake media", slog.Any("error", err))
+	}
+}
+
+func mediaColors(id string) []color.RGBA {
+	hash := sha256.Sum256([]byte(id))
+
+	colors := make([]color.RGBA, 4)
+	for index := range colors {
+		colors[index] = color.RGBA{R: hash[index*3], G: hash[index*3+1], B: hash[index*3+2], A: 0xff}
+	}
+
+	return colors
+}
+
+// stripes draws vertical stripes of the given colors, shifted by offset stripes
+func stripes(width, height int, colors []color.RGBA, offset int) *image.RGBA {
+	output := image.NewRGBA(image.Rect(0, 0, width, height))
+	stripeWidth := width / len(colors)
+
+	for index := range colors {
+		bounds := image.Rect(index*stripeWidth, 0, (index+1)*stripeWidth, height)
+		if index == len(colors)-1 {
+			bounds.Max.X = width
+		}
+
+		draw.Draw(output, bounds, image.NewUniform(colors[(index+offset)%len(colors)]), image.Point{}, draw.Src)
+	}
+
+	return output
+}
+
+// animation rotates the stripes at each frame
+func animation(colors []color.RGBA) *gif.GIF {
+	output := &gif.GIF{
+		Image: make([]*image.Paletted, gifFrames),
+		Delay: make([]int, gifFrames),
+	}
+
+	for index := range gifFrames {
+		frame := image.NewPaletted(image.Rect(0, 0, gifWidth, gifHeight), palette.Plan9)
+		draw.Draw(frame, frame.Bounds(), stripes(gifWidth, gifHeight, colors, index), image.Point{}, draw.Src)
+
+		output.Image[index] = frame
+		output.Delay[index] = 25
+	}
+
+	return output
+}
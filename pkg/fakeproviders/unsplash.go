@@ -0,0 +1,70 @@
+package fakeproviders
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// unsplashRateLimit mimics Unsplash, answering a forbidden with a plain text body once the hourly quota is exhausted
+func unsplashRateLimit(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusForbidden)
+	_, _ = fmt.Fprint(w, "Rate Limit Exceeded")
+}
+
+func unsplashNotFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, map[string][]string{"errors": {"No photos found."}})
+}
+
+func (s *Server) unsplashRandom(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("query")
+
+	switch {
+	case isRateLimited(query):
+		unsplashRateLimit(w)
+	case isNotFound(query):
+		unsplashNotFound(w)
+	default:
+		writeJSON(w, http.StatusOK, unsplashPhoto(baseURL(r), mediaID("unsplash", query)))
+	}
+}
+
+func (s *Server) unsplashPhoto(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch {
+	case isRateLimited(id):
+		unsplashRateLimit(w)
+	case isNotFound(id):
+		unsplashNotFound(w)
+	default:
+		writeJSON(w, http.StatusOK, unsplashPhoto(baseURL(r), id))
+	}
+}
+
+func (s *Server) unsplashDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.share(r.Context(), Share{Provider: "unsplash", ID: id})
+
+	writeJSON(w, http.StatusOK, map[string]string{"url": fmt.Sprintf("%s/media/%s.jpg", baseURL(r), id)})
+}
+
+func unsplashPhoto(root, id string) map[string]any {
+	return map[string]any{
+		"id": id,
+		"urls": map[string]string{
+			"raw": fmt.Sprintf("%s/media/%s.jpg", root, id),
+		},
+		"links": map[string]string{
+			"html":              fmt.Sprintf("%s/unsplash/photos/%s", root, id),
+			"download_location": fmt.Sprintf("%s/unsplash/photos/%s/download", root, id),
+		},
+		"user": map[string]any{
+			"name": "Fake Photographer",
+			"links": map[string]string{
+				"html": root + "/unsplash/@fake",
+			},
+		},
+	}
+}
@@ -0,0 +1,214 @@
+package fetch
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ViBiOh/flags"
+	"github.com/ViBiOh/httputils/v4/pkg/request"
+	"github.com/ViBiOh/httputils/v4/pkg/telemetry"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	maxRedirects = 5
+	sniffLength  = 512
+)
+
+var (
+	ErrForbiddenScheme  = errors.New("scheme is not allowed")
+	ErrForbiddenHost    = errors.New("host is not allowed")
+	ErrForbiddenAddress = errors.New("address is not allowed")
+	ErrNotAnImage       = errors.New("content is not an image")
+	ErrNotConfigured    = errors.New("fetcher is not configured")
+
+	reservedPrefixes = []netip.Prefix{
+		netip.MustParsePrefix("0.0.0.0/8"),
+		netip.MustParsePrefix("100.64.0.0/10"),
+		netip.MustParsePrefix("192.0.0.0/24"),
+		netip.MustParsePrefix("198.18.0.0/15"),
+		netip.MustParsePrefix("240.0.0.0/4"),
+		netip.MustParsePrefix("64:ff9b::/96"),
+		netip.MustParsePrefix("fec0::/10"),
+	}
+)
+
+type Service struct {
+	req            request.Request
+	allowedSchemes []string
+	allowedHosts   []string
+	allowPrivate   bool
+}
+
+type Config struct {
+	allowedSchemes []string
+	allowedHosts   []string
+	timeout        time.Duration
+	allowPrivate   bool
+}
+
+func Flags(fs *flag.FlagSet, prefix string, overrides ...flags.Override) *Config {
+	var config Config
+
+	flags.New("AllowedSchemes", "URL schemes allowed for downloading images").Prefix(prefix).DocPrefix("fetch").StringSliceVar(fs, &config.allowedSchemes, []string{"https"}, overrides)
+	flags.New("AllowedHosts", "Hosts allowed for downloading images, subdomains included with a leading dot, any if empty").Prefix(prefix).DocPrefix("fetch").StringSliceVar(fs, &config.allowedHosts, nil, overrides)
+	flags.New("AllowPrivate", "Allow downloading images from private, loopback and link-local addresses").Prefix(prefix).DocPrefix("fetch").BoolVar(fs, &config.allowPrivate, false, overrides)
+	flags.New("Timeout", "Timeout for downloading an image").Prefix(prefix).DocPrefix("fetch").DurationVar(fs, &config.timeout, time.Second*30, overrides)
+
+	return &config
+}
+
+func New(config *Config, meterProvider metric.MeterProvider, tracerProvider trace.TracerProvider) Service {
+	service := Service{
+		allowedSchemes: config.allowedSchemes,
+		allowedHosts:   config.allowedHosts,
+		allowPrivate:   config.allowPrivate,
+	}
+
+	dialer := &net.Dialer{
+		Timeout: time.Second * 5,
+		Control: service.checkAddress,
+	}
+
+	client := &http.Client{
+		Timeout: config.timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			TLSHandshakeTimeout:   time.Second * 5,
+			ResponseHeaderTimeout: time.Second * 10,
+			IdleConnTimeout:       time.Minute,
+			MaxIdleConnsPerHost:   4,
+		},
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+
+			return service.checkURL(r.URL)
+		},
+	}
+
+	service.req = request.New().WithClient(telemetry.AddOpenTelemetryToClient(client, meterProvider, tracerProvider))
+
+	return service
+}
+
+// Get downloads the image at the given URL, once checked against the allowlists and sniffed as an image
+func (s Service) Get(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	if len(s.allowedSchemes) == 0 {
+		return nil, ErrNotConfigured
+	}
+
+	imageURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse URL: %w", err)
+	}
+
+	if err = s.checkURL(imageURL); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.req.Get(imageURL.String()).Send(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch URL `%s`: %w", rawURL, err)
+	}
+
+	reader := bufio.NewReaderSize(resp.Body, sniffLength)
+
+	head, err := reader.Peek(sniffLength)
+	if err != nil && !errors.Is(err, io.EOF) {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("sniff content: %w", err)
+	}
+
+	if contentType := http.DetectContentType(head); !strings.HasPrefix(contentType, "image/") {
+		_ = request.DiscardBody(resp.Body)
+		return nil, fmt.Errorf("%w: `%s`", ErrNotAnImage, contentType)
+	}
+
+	return readCloser{Reader: reader, Closer: resp.Body}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (s Service) checkURL(imageURL *url.URL) error {
+	if !slices.Contains(s.allowedSchemes, imageURL.Scheme) {
+		return fmt.Errorf("%w: `%s`", ErrForbiddenScheme, imageURL.Scheme)
+	}
+
+	if !s.hostAllowed(imageURL.Hostname()) {
+		return fmt.Errorf("%w: `%s`", ErrForbiddenHost, imageURL.Hostname())
+	}
+
+	return nil
+}
+
+func (s Service) hostAllowed(host string) bool {
+	if len(s.allowedHosts) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(host)
+
+	for _, allowed := range s.allowedHosts {
+		if strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed) || host == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkAddress is called once the host is resolved, blocking DNS answers pointing to internal networks
+func (s Service) checkAddress(_, address string, _ syscall.RawConn) error {
+	if s.allowPrivate {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("split address: %w", err)
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("parse address: %w", err)
+	}
+
+	if !isPublic(addr.Unmap()) {
+		return fmt.Errorf("%w: `%s`", ErrForbiddenAddress, addr)
+	}
+
+	return nil
+}
+
+func isPublic(addr netip.Addr) bool {
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
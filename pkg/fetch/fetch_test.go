@@ -0,0 +1,200 @@
+package fetch
+
+import (
+	"errors"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		addr string
+		want bool
+	}{
+		"public ipv4":       {"93.184.216.34", true},
+		"public ipv6":       {"2606:2800:220:1:248:1893:25c8:1946", true},
+		"loopback":          {"127.0.0.1", false},
+		"loopback ipv6":     {"::1", false},
+		"private class a":   {"10.1.2.3", false},
+		"private class b":   {"172.16.0.1", false},
+		"private class c":   {"192.168.1.1", false},
+		"unique local ipv6": {"fd00::1", false},
+		"link local":        {"169.254.169.254", false},
+		"link local ipv6":   {"fe80::1", false},
+		"unspecified":       {"0.0.0.0", false},
+		"unspecified ipv6":  {"::", false},
+		"this network":      {"0.1.2.3", false},
+		"carrier grade nat": {"100.64.0.1", false},
+		"benchmarking":      {"198.18.0.1", false},
+		"reserved":          {"240.0.0.1", false},
+		"multicast":         {"224.0.0.1", false},
+		"nat64":             {"64:ff9b::a00:1", false},
+		"site local ipv6":   {"fec0::1", false},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isPublic(netip.MustParseAddr(testCase.addr)); got != testCase.want {
+				t.Errorf("isPublic(%s) = %t, want %t", testCase.addr, got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		service Service
+		address string
+		wantErr error
+		invalid bool
+	}{
+		"public": {
+			service: Service{},
+			address: "93.184.216.34:443",
+		},
+		"public ipv6": {
+			service: Service{},
+			address: "[2606:2800:220:1:248:1893:25c8:1946]:443",
+		},
+		"loopback": {
+			service: Service{},
+			address: "127.0.0.1:443",
+			wantErr: ErrForbiddenAddress,
+		},
+		"private": {
+			service: Service{},
+			address: "10.0.0.1:80",
+			wantErr: ErrForbiddenAddress,
+		},
+		"ipv6 loopback": {
+			service: Service{},
+			address: "[::1]:443",
+			wantErr: ErrForbiddenAddress,
+		},
+		"ipv6 mapped loopback": {
+			service: Service{},
+			address: "[::ffff:127.0.0.1]:443",
+			wantErr: ErrForbiddenAddress,
+		},
+		"ipv6 mapped private": {
+			service: Service{},
+			address: "[::ffff:192.168.0.1]:443",
+			wantErr: ErrForbiddenAddress,
+		},
+		"ipv6 mapped metadata": {
+			service: Service{},
+			address: "[::ffff:169.254.169.254]:80",
+			wantErr: ErrForbiddenAddress,
+		},
+		"private allowed": {
+			service: Service{allowPrivate: true},
+			address: "127.0.0.1:443",
+		},
+		"missing port": {
+			service: Service{},
+			address: "127.0.0.1",
+			invalid: true,
+		},
+		"hostname": {
+			service: Service{},
+			address: "localhost:443",
+			invalid: true,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			err := testCase.service.checkAddress("tcp", testCase.address, nil)
+
+			if testCase.invalid {
+				if err == nil || errors.Is(err, ErrForbiddenAddress) {
+					t.Errorf("checkAddress(%s) = `%v`, want a parse error", testCase.address, err)
+				}
+
+				return
+			}
+
+			if !errors.Is(err, testCase.wantErr) {
+				t.Errorf("checkAddress(%s) = `%v`, want `%v`", testCase.address, err, testCase.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	t.Parallel()
+
+	service := Service{allowedSchemes: []string{"https"}, allowedHosts: []string{"images.example.com", ".cdn.example.com"}}
+
+	cases := map[string]struct {
+		service Service
+		url     string
+		wantErr error
+	}{
+		"allowed host": {
+			service: service,
+			url:     "https://images.example.com/cat.jpg",
+		},
+		"allowed subdomain": {
+			service: service,
+			url:     "https://eu.cdn.example.com/cat.jpg",
+		},
+		"host case": {
+			service: service,
+			url:     "https://IMAGES.example.com/cat.jpg",
+		},
+		"any host": {
+			service: Service{allowedSchemes: []string{"https"}},
+			url:     "https://example.org/cat.jpg",
+		},
+		"forbidden scheme": {
+			service: service,
+			url:     "http://images.example.com/cat.jpg",
+			wantErr: ErrForbiddenScheme,
+		},
+		"file scheme": {
+			service: service,
+			url:     "file:///etc/passwd",
+			wantErr: ErrForbiddenScheme,
+		},
+		"forbidden host": {
+			service: service,
+			url:     "https://example.org/cat.jpg",
+			wantErr: ErrForbiddenHost,
+		},
+		"suffix without dot": {
+			service: service,
+			url:     "https://evilcdn.example.com/cat.jpg",
+			wantErr: ErrForbiddenHost,
+		},
+		"allowed host as suffix": {
+			service: service,
+			url:     "https://images.example.com.evil.org/cat.jpg",
+			wantErr: ErrForbiddenHost,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			imageURL, err := url.Parse(testCase.url)
+			if err != nil {
+				t.Fatalf("parse url: %s", err)
+			}
+
+			if err = testCase.service.checkURL(imageURL); !errors.Is(err, testCase.wantErr) {
+				t.Errorf("checkURL(%s) = `%v`, want `%v`", testCase.url, err, testCase.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package giphy
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ViBiOh/flags"
+	"github.com/ViBiOh/httputils/v4/pkg/cache"
+	"github.com/ViBiOh/httputils/v4/pkg/httperror"
+	"github.com/ViBiOh/httputils/v4/pkg/httpjson"
+	"github.com/ViBiOh/httputils/v4/pkg/redis"
+	"github.com/ViBiOh/httputils/v4/pkg/request"
+	"github.com/ViBiOh/kitten/pkg/rediscache"
+	"github.com/ViBiOh/kitten/pkg/version"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const maxFileSize = 4 << 20
+
+var (
+	ErrNotFound          = errors.New("no gif found")
+	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+	cacheDuration = time.Hour * 24 * 7
+
+	// renditions are tried from the best to the lightest one
+	renditions = []string{"downsized_large", "downsized_medium", "downsized", "fixed_width", "fixed_width_downsampled"}
+)
+
+type image struct {
+	URL  string `json:"url"`
+	Size string `json:"size"`
+}
+
+type user struct {
+	DisplayName string `json:"display_name"`
+	ProfileURL  string `json:"profile_url"`
+}
+
+type analytics struct {
+	OnSent struct {
+		URL string `json:"url"`
+	} `json:"onsent"`
+}
+
+type ResponseObject struct {
+	Images    map[string]image `json:"images"`
+	User      *user            `json:"user"`
+	Analytics analytics        `json:"analytics"`
+	URL       string           `json:"url"`
+	ID        string           `json:"id"`
+	Username  string           `json:"username"`
+}
+
+// GetImageURL returns the best rendition lighter than the size limit
+func (ro ResponseObject) GetImageURL() string {
+	for _, name := range renditions {
+		rendition, ok := ro.Images[name]
+		if !ok || len(rendition.URL) == 0 {
+			continue
+		}
+
+		if size, err := strconv.ParseUint(rendition.Size, 10, 64); err == nil && size < maxFileSize {
+			return rendition.URL
+		}
+	}
+
+	return ro.Images["fixed_width_small"].URL
+}
+
+// Author returns the name and the profile URL of the uploader, if any
+func (ro ResponseObject) Author() (string, string) {
+	if ro.User != nil && len(ro.User.DisplayName) != 0 {
+		return ro.User.DisplayName, ro.User.ProfileURL
+	}
+
+	return ro.Username, ""
+}
+
+type pagination struct {
+	TotalCount int `json:"total_count"`
+	Count      int `json:"count"`
+	Offset     int `json:"offset"`
+}
+
+type searchResponse struct {
+	Data       []ResponseObject `json:"data"`
+	Pagination pagination       `json:"pagination"`
+}
+
+type getResponse struct {
+	Data ResponseObject `json:"data"`
+}
+
+type Service struct {
+	cache     *cache.Cache[string, ResponseObject]
+	index     rediscache.Index[ResponseObject]
+	apiKey    string
+	req       request.Request
+	analytics request.Request
+}
+
+type Config struct {
+	apiKey string
+	url    string
+}
+
+func Flags(fs *flag.FlagSet, prefix string, overrides ...flags.Override) *Config {
+	var config Config
+
+	flags.New("ApiKey", "API Key").Prefix(prefix).DocPrefix("giphy").StringVar(fs, &config.apiKey, "", overrides)
+	flags.New("URL", "API base URL").Prefix(prefix).DocPrefix("giphy").StringVar(fs, &config.url, "https://api.giphy.com/v1", overrides)
+
+	return &config
+}
+
+func New(ctx context.Context, config *Config, redisClient redis.Client, tracerProvider trace.TracerProvider) Service {
+	service := Service{
+		req:       request.Get(strings.TrimSuffix(config.url, "/")).WithClient(request.CreateClient(time.Second*30, request.NoRedirection)),
+		analytics: request.New().WithClient(request.CreateClient(time.Second*10, request.NoRedirection)),
+		apiKey:    url.QueryEscape(config.apiKey),
+	}
+
+	service.cache = cache.New(redisClient, cacheID, func(ctx context.Context, id string) (ResponseObject, error) {
+		resp, err := service.req.Path("/gifs/%s?api_key=%s", url.PathEscape(id), service.apiKey).Send(ctx, nil)
+		if err != nil {
+			return ResponseObject{}, handleError(resp, fmt.Errorf("get gif: %w", err))
+		}
+
+		result, err := httpjson.Read[getResponse](resp)
+		if err != nil {
+			return ResponseObject{}, fmt.Errorf("parse gif response: %w", err)
+		}
+
+		if len(result.Data.ID) == 0 {
+			return ResponseObject{}, ErrNotFound
+		}
+
+		return result.Data, nil
+	}, tracerProvider).
+		WithTTL(cacheDuration).
+		WithExtendOnHit(ctx, cacheDuration/4, 50).
+		WithClientSideCaching(ctx, "kitten_giphy", 50)
+
+	service.index = rediscache.New(redisClient, service.cache, cacheID)
+
+	return service
+}
+
+func (s Service) Enabled() bool {
+	return len(s.apiKey) != 0
+}
+
+// Search returns the gif at the offset given by pos and the offset of the next one
+func (s Service) Search(ctx context.Context, query, pos string) (ResponseObject, string, error) {
+	offset, err := strconv.Atoi(pos)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	resp, err := s.req.Path("/gifs/search?api_key=%s&q=%s&limit=1&offset=%d&rating=pg-13", s.apiKey, url.QueryEscape(query), offset).Send(ctx, nil)
+	if err != nil {
+		return ResponseObject{}, "", handleError(resp, fmt.Errorf("search gif: %w", err))
+	}
+
+	search, err := httpjson.Read[searchResponse](resp)
+	if err != nil {
+		return ResponseObject{}, "", fmt.Errorf("parse gif response: %w", err)
+	}
+
+	if len(search.Data) == 0 {
+		return ResponseObject{}, "", ErrNotFound
+	}
+
+	gif := search.Data[0]
+
+	go func(ctx context.Context) {
+		if err := s.cache.Store(ctx, gif.ID, gif); err != nil {
+			slog.LogAttrs(ctx, slog.LevelError, "save gif in cache", slog.Any("error", err))
+		}
+	}(context.WithoutCancel(ctx))
+
+	var next string
+	if nextOffset := search.Pagination.Offset + len(search.Data); nextOffset < search.Pagination.TotalCount {
+		next = strconv.Itoa(nextOffset)
+	}
+
+	return gif, next, nil
+}
+
+func (s Service) Get(ctx context.Context, id string) (ResponseObject, error) {
+	return s.cache.Get(ctx, id)
+}
+
+// SendAnalytics pings the onsent URL of the gif, as required when a gif is shared
+func (s Service) SendAnalytics(ctx context.Context, content ResponseObject) {
+	if len(content.Analytics.OnSent.URL) == 0 {
+		return
+	}
+
+	pingback, err := url.Parse(content.Analytics.OnSent.URL)
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "parse giphy pingback url", slog.Any("error", err))
+		return
+	}
+
+	query := pingback.Query()
+	query.Set("ts", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	pingback.RawQuery = query.Encode()
+
+	resp, err := s.analytics.Get(pingback.String()).Send(ctx, nil)
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "send pingback to giphy", slog.Any("error", err))
+		return
+	}
+
+	if err = request.DiscardBody(resp.Body); err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "discard pingback from giphy", slog.Any("error", err))
+	}
+}
+
+// Cached lists the ids stored in cache
+func (s Service) Cached(ctx context.Context) ([]string, error) {
+	return s.index.Cached(ctx)
+}
+
+// Evict removes the given ids from cache
+func (s Service) Evict(ctx context.Context, ids ...string) error {
+	return s.index.Evict(ctx, ids...)
+}
+
+func handleError(resp *http.Response, err error) error {
+	if httpError, ok := errors.AsType[request.Error](err); ok {
+		switch httpError.StatusCode {
+		case http.StatusNotFound:
+			return ErrNotFound
+		case http.StatusTooManyRequests:
+			return ErrRateLimitExceeded
+		}
+	}
+
+	return httperror.FromResponse(resp, err)
+}
+
+func cacheID(id string) string {
+	return version.Redis("giphy:" + id)
+}
@@ -0,0 +1,192 @@
+package giphy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestGetImageURL(t *testing.T) {
+	t.Parallel()
+
+	small := strconv.Itoa(maxFileSize / 2)
+	large := strconv.Itoa(maxFileSize * 2)
+
+	cases := map[string]struct {
+		images map[string]image
+		want   string
+	}{
+		"best rendition": {
+			map[string]image{
+				"downsized_large":   {URL: "large", Size: small},
+				"downsized_medium":  {URL: "medium", Size: small},
+				"fixed_width_small": {URL: "small", Size: small},
+			},
+			"large",
+		},
+		"too heavy": {
+			map[string]image{
+				"downsized_large":  {URL: "large", Size: large},
+				"downsized_medium": {URL: "medium", Size: small},
+			},
+			"medium",
+		},
+		"exactly the limit": {
+			map[string]image{
+				"downsized_large": {URL: "large", Size: strconv.Itoa(maxFileSize)},
+				"downsized":       {URL: "downsized", Size: small},
+			},
+			"downsized",
+		},
+		"missing size": {
+			map[string]image{
+				"downsized_large": {URL: "large"},
+				"fixed_width":     {URL: "fixed", Size: small},
+			},
+			"fixed",
+		},
+		"missing url": {
+			map[string]image{
+				"downsized_large":         {Size: small},
+				"fixed_width_downsampled": {URL: "downsampled", Size: small},
+			},
+			"downsampled",
+		},
+		"every rendition too heavy": {
+			map[string]image{
+				"downsized_large":   {URL: "large", Size: large},
+				"fixed_width":       {URL: "fixed", Size: large},
+				"fixed_width_small": {URL: "small", Size: large},
+			},
+			"small",
+		},
+		"no rendition": {
+			nil,
+			"",
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			if got := (ResponseObject{Images: testCase.images}).GetImageURL(); got != testCase.want {
+				t.Errorf("GetImageURL() = `%s`, want `%s`", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	const total = 3
+
+	cases := map[string]struct {
+		pos        string
+		status     int
+		empty      bool
+		wantOffset string
+		wantID     string
+		wantNext   string
+		wantErr    error
+	}{
+		"first page": {
+			wantOffset: "0",
+			wantID:     "gif-0",
+			wantNext:   "1",
+		},
+		"cursor": {
+			pos:        "1",
+			wantOffset: "1",
+			wantID:     "gif-1",
+			wantNext:   "2",
+		},
+		"last result": {
+			pos:        "2",
+			wantOffset: "2",
+			wantID:     "gif-2",
+		},
+		"invalid cursor": {
+			pos:        "next",
+			wantOffset: "0",
+			wantID:     "gif-0",
+			wantNext:   "1",
+		},
+		"negative cursor": {
+			pos:        "-1",
+			wantOffset: "0",
+			wantID:     "gif-0",
+			wantNext:   "1",
+		},
+		"no result": {
+			pos:        "3",
+			empty:      true,
+			wantOffset: "3",
+			wantErr:    ErrNotFound,
+		},
+		"rate limited": {
+			status:     http.StatusTooManyRequests,
+			wantOffset: "0",
+			wantErr:    ErrRateLimitExceeded,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+
+				if got := query.Get("offset"); got != testCase.wantOffset {
+					t.Errorf("offset = `%s`, want `%s`", got, testCase.wantOffset)
+				}
+
+				if got := query.Get("q"); got != "funny cat" {
+					t.Errorf("q = `%s`, want `funny cat`", got)
+				}
+
+				if testCase.status != 0 {
+					w.WriteHeader(testCase.status)
+					return
+				}
+
+				offset, _ := strconv.Atoi(query.Get("offset"))
+
+				response := searchResponse{Pagination: pagination{TotalCount: total, Offset: offset}}
+				if !testCase.empty {
+					response.Data = []ResponseObject{{ID: "gif-" + strconv.Itoa(offset)}}
+					response.Pagination.Count = 1
+				}
+
+				_ = json.NewEncoder(w).Encode(response)
+			}))
+			t.Cleanup(server.Close)
+
+			fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+			config := Flags(fs, "giphy")
+
+			if err := fs.Parse([]string{"-giphyURL", server.URL, "-giphyApiKey", "fake"}); err != nil {
+				t.Fatalf("parse flags: %s", err)
+			}
+
+			service := New(context.Background(), config, nil, nil)
+
+			got, next, err := service.Search(context.Background(), "funny cat", testCase.pos)
+
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("Search() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if got.ID != testCase.wantID || next != testCase.wantNext {
+				t.Errorf("Search() = (`%s`, `%s`), want (`%s`, `%s`)", got.ID, next, testCase.wantID, testCase.wantNext)
+			}
+		})
+	}
+}
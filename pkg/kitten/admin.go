@@ -0,0 +1,307 @@
+package kitten
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ViBiOh/httputils/v4/pkg/hash"
+	"github.com/ViBiOh/httputils/v4/pkg/httperror"
+	"github.com/ViBiOh/httputils/v4/pkg/httpjson"
+	"github.com/ViBiOh/kitten/pkg/apikey"
+)
+
+const metadataExtension = ".json"
+
+type metadata struct {
+	ID          string `json:"id"`
+	CaptionHash string `json:"caption_hash"`
+}
+
+type CacheEntry struct {
+	CreatedAt   time.Time `json:"created_at"`
+	ID          string    `json:"id"`
+	CaptionHash string    `json:"caption_hash"`
+	Kind        string    `json:"kind"`
+	Age         string    `json:"age"`
+	Size        int64     `json:"size"`
+}
+
+type CacheUsage struct {
+	Count int   `json:"count"`
+	Size  int64 `json:"size"`
+	Limit int64 `json:"limit,omitempty"`
+}
+
+type CacheListing struct {
+	Providers map[string][]string `json:"providers"`
+	Renders   []CacheEntry        `json:"renders"`
+}
+
+type CachePurge struct {
+	Providers map[string]int `json:"providers"`
+	Renders   int            `json:"renders"`
+	Sources   int            `json:"sources"`
+}
+
+func storeMetadata(ctx context.Context, filename string, spec renderSpec) {
+	if err := writeCacheFile(filename+metadataExtension, func(writer io.Writer) error {
+		return json.NewEncoder(writer).Encode(metadata{ID: spec.source.id, CaptionHash: hash.String(spec.caption)})
+	}); err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "write metadata to local cache", slog.Any("error", err))
+	}
+}
+
+// AdminHandler serves the cache administration API, authenticated with the admin token or an admin API key
+func (s Service) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /cache", s.listCache)
+	mux.HandleFunc("DELETE /cache", s.purgeCache)
+	mux.HandleFunc("GET /cache/usage", s.cacheUsage)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, ok := apikey.FromContext(r.Context()); ok && key.Allows(apikey.Admin) {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		if len(s.adminToken) == 0 {
+			httperror.NotFound(r.Context(), w, errors.New("admin is disabled"))
+			return
+		}
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			httperror.Unauthorized(r.Context(), w, errors.New("invalid admin token"))
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (s Service) listCache(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	renders, err := s.listRenders()
+	if err != nil {
+		httperror.InternalServerError(ctx, w, err)
+		return
+	}
+
+	output := CacheListing{
+		Renders: make([]CacheEntry, 0, len(renders)),
+	}
+
+	now := time.Now()
+
+	for _, render := range renders {
+		output.Renders = append(output.Renders, CacheEntry{
+			ID:          render.ID,
+			CaptionHash: render.CaptionHash,
+			Kind:        string(render.kind()),
+			Size:        render.info.Size(),
+			CreatedAt:   render.info.ModTime(),
+			Age:         now.Sub(render.info.ModTime()).Truncate(time.Second).String(),
+		})
+	}
+
+	output.Providers = make(map[string][]string)
+
+	for _, provider := range s.providers.all() {
+		cacheProvider, ok := provider.(CacheProvider)
+		if !ok {
+			continue
+		}
+
+		if output.Providers[provider.Name()], err = cacheProvider.Cached(ctx); err != nil {
+			httperror.InternalServerError(ctx, w, err)
+			return
+		}
+	}
+
+	httpjson.Write(ctx, w, http.StatusOK, output)
+}
+
+func (s Service) purgeCache(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	id := strings.TrimSpace(query.Get(idParam))
+	caption := strings.TrimSpace(query.Get(captionParam))
+	all := query.Get("all") == "true"
+
+	if len(id) == 0 && len(caption) == 0 && !all {
+		httperror.BadRequest(ctx, w, errors.New("id, caption or all param is required"))
+		return
+	}
+
+	output, err := s.purge(ctx, id, caption, all)
+	if err != nil {
+		httperror.InternalServerError(ctx, w, err)
+		return
+	}
+
+	httpjson.Write(ctx, w, http.StatusOK, output)
+}
+
+func (s Service) purge(ctx context.Context, id, caption string, all bool) (CachePurge, error) {
+	output := CachePurge{Providers: make(map[string]int)}
+
+	renders, err := s.listRenders()
+	if err != nil {
+		return output, err
+	}
+
+	captionHash := hash.String(caption)
+
+	for _, render := range renders {
+		if !all && (len(id) == 0 || render.ID != id) && (len(caption) == 0 || render.CaptionHash != captionHash) {
+			continue
+		}
+
+		if err = removeRender(render.filename); err != nil {
+			return output, err
+		}
+
+		output.Renders++
+	}
+
+	if all {
+		output.Sources, err = s.sources.purge()
+		if err != nil {
+			return output, err
+		}
+	} else if len(id) != 0 {
+		for kind, provider := range s.providers.all() {
+			if err = os.Remove(s.sources.filename(newProviderSource(kind, provider.Name(), id))); err == nil {
+				output.Sources++
+			} else if !os.IsNotExist(err) {
+				return output, fmt.Errorf("remove source: %w", err)
+			}
+		}
+	}
+
+	if len(id) == 0 && !all {
+		return output, nil
+	}
+
+	for _, provider := range s.providers.all() {
+		cacheProvider, ok := provider.(CacheProvider)
+		if !ok {
+			continue
+		}
+
+		ids := []string{id}
+
+		if all {
+			if ids, err = cacheProvider.Cached(ctx); err != nil {
+				return output, err
+			}
+		}
+
+		if err = cacheProvider.Evict(ctx, ids...); err != nil {
+			return output, err
+		}
+
+		output.Providers[provider.Name()] = len(ids)
+	}
+
+	return output, nil
+}
+
+func (s Service) cacheUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	renders, err := s.listRenders()
+	if err != nil {
+		httperror.InternalServerError(ctx, w, err)
+		return
+	}
+
+	var rendersUsage CacheUsage
+	for _, render := range renders {
+		rendersUsage.Count++
+		rendersUsage.Size += render.info.Size()
+	}
+
+	sourcesUsage, err := s.sources.usage()
+	if err != nil {
+		httperror.InternalServerError(ctx, w, err)
+		return
+	}
+
+	httpjson.Write(ctx, w, http.StatusOK, map[string]CacheUsage{
+		"renders": rendersUsage,
+		"sources": sourcesUsage,
+	})
+}
+
+type renderFile struct {
+	info     os.FileInfo
+	filename string
+	metadata
+}
+
+func (rf renderFile) kind() memeKind {
+	if filepath.Ext(rf.filename) == ".gif" {
+		return gifKind
+	}
+
+	return imageKind
+}
+
+// listRenders returns the renders of the renders folder, with their metadata when written
+func (s Service) listRenders() ([]renderFile, error) {
+	entries, err := os.ReadDir(s.rendersFolder)
+	if err != nil {
+		return nil, fmt.Errorf("list renders: %w", err)
+	}
+
+	var output []renderFile
+
+	for _, entry := range entries {
+		if entry.IsDir() || !renderedFilename.MatchString(entry.Name()) {
+			continue
+		}
+
+		filename := filepath.Join(s.rendersFolder, entry.Name())
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		render := renderFile{filename: filename, info: info}
+
+		if content, err := os.ReadFile(filename + metadataExtension); err == nil {
+			if err = json.Unmarshal(content, &render.metadata); err != nil {
+				slog.LogAttrs(context.Background(), slog.LevelWarn, "parse render metadata", slog.String("filename", filename), slog.Any("error", err))
+			}
+		}
+
+		output = append(output, render)
+	}
+
+	return output, nil
+}
+
+func removeRender(filename string) error {
+	for _, name := range []string{filename, filename + metadataExtension} {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove render: %w", err)
+		}
+	}
+
+	return nil
+}
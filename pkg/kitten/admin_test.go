@@ -0,0 +1,274 @@
+package kitten
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ViBiOh/httputils/v4/pkg/hash"
+	"github.com/ViBiOh/kitten/pkg/fetch"
+)
+
+const adminToken = "secret"
+
+// cachingProvider is a provider caching the given ids, recording the ones evicted
+type cachingProvider struct {
+	*stubProvider
+	err     error
+	cached  []string
+	evicted []string
+	mutex   sync.Mutex
+}
+
+func (cp *cachingProvider) Cached(context.Context) ([]string, error) {
+	return cp.cached, cp.err
+}
+
+func (cp *cachingProvider) Evict(_ context.Context, ids ...string) error {
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+
+	cp.evicted = append(cp.evicted, ids...)
+
+	return nil
+}
+
+// newAdminService has two renders, of `cat` captioned `hello` and of `dog` captioned `world`, and both their sources cached
+func newAdminService(t *testing.T, cacheErr error) (Service, *cachingProvider) {
+	t.Helper()
+
+	provider := &cachingProvider{stubProvider: &stubProvider{name: "cache"}, cached: []string{"cat", "dog"}, err: cacheErr}
+
+	instance := Service{
+		rendersFolder: t.TempDir(),
+		adminToken:    adminToken,
+		providers:     NewRegistry([]string{provider.name, "other"}, nil).WithImage(provider).WithImage(&stubProvider{name: "other"}),
+		sources:       newSourceCache(fetch.Service{}, t.TempDir(), 1000, limits{}, nil),
+	}
+
+	renders := map[string]metadata{
+		"abc.jpeg": {ID: "cat", CaptionHash: hash.String("hello")},
+		"def.jpeg": {ID: "dog", CaptionHash: hash.String("world")},
+	}
+
+	for name, content := range renders {
+		filename := filepath.Join(instance.rendersFolder, name)
+
+		if err := os.WriteFile(filename, []byte("render"), 0o600); err != nil {
+			t.Fatalf("write render: %s", err)
+		}
+
+		payload, err := json.Marshal(content)
+		if err != nil {
+			t.Fatalf("marshal metadata: %s", err)
+		}
+
+		if err = os.WriteFile(filename+metadataExtension, payload, 0o600); err != nil {
+			t.Fatalf("write metadata: %s", err)
+		}
+
+		if err = os.WriteFile(instance.sources.filename(newProviderSource(imageKind, provider.name, content.ID)), []byte("source"), 0o600); err != nil {
+			t.Fatalf("write source: %s", err)
+		}
+	}
+
+	return instance, provider
+}
+
+func serveAdmin(instance Service, method, target, token string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, nil)
+	if len(token) != 0 {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	writer := httptest.NewRecorder()
+	instance.AdminHandler().ServeHTTP(writer, request)
+
+	return writer
+}
+
+func TestListCache(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		token         string
+		disabled      bool
+		cacheErr      error
+		wantStatus    int
+		wantRenders   []string
+		wantProviders map[string][]string
+	}{
+		"disabled": {
+			token:      adminToken,
+			disabled:   true,
+			wantStatus: http.StatusNotFound,
+		},
+		"missing token": {
+			wantStatus: http.StatusUnauthorized,
+		},
+		"invalid token": {
+			token:      "guess",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"listed": {
+			token:         adminToken,
+			wantStatus:    http.StatusOK,
+			wantRenders:   []string{"cat", "dog"},
+			wantProviders: map[string][]string{"cache": {"cat", "dog"}},
+		},
+		"provider error": {
+			token:      adminToken,
+			cacheErr:   errProviderDown,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			instance, _ := newAdminService(t, testCase.cacheErr)
+			if testCase.disabled {
+				instance.adminToken = ""
+			}
+
+			writer := serveAdmin(instance, http.MethodGet, "/cache", testCase.token)
+
+			if writer.Code != testCase.wantStatus {
+				t.Fatalf("status = %d, want %d", writer.Code, testCase.wantStatus)
+			}
+
+			if testCase.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got CacheListing
+			if err := json.NewDecoder(writer.Body).Decode(&got); err != nil {
+				t.Fatalf("decode listing: %s", err)
+			}
+
+			var renders []string
+			for _, render := range got.Renders {
+				renders = append(renders, render.ID)
+			}
+
+			slices.Sort(renders)
+
+			if !slices.Equal(renders, testCase.wantRenders) {
+				t.Errorf("renders = %v, want %v", renders, testCase.wantRenders)
+			}
+
+			if !reflect.DeepEqual(got.Providers, testCase.wantProviders) {
+				t.Errorf("providers = %v, want %v", got.Providers, testCase.wantProviders)
+			}
+		})
+	}
+}
+
+func TestPurgeCache(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		query       string
+		cacheErr    error
+		wantStatus  int
+		want        CachePurge
+		wantKept    []string
+		wantEvicted []string
+	}{
+		"missing param": {
+			query:      "",
+			wantStatus: http.StatusBadRequest,
+			wantKept:   []string{"abc.jpeg", "def.jpeg"},
+		},
+		"by id": {
+			query:       "?id=cat",
+			wantStatus:  http.StatusOK,
+			want:        CachePurge{Providers: map[string]int{"cache": 1}, Renders: 1, Sources: 1},
+			wantKept:    []string{"def.jpeg"},
+			wantEvicted: []string{"cat"},
+		},
+		"by caption": {
+			query:      "?caption=world",
+			wantStatus: http.StatusOK,
+			want:       CachePurge{Providers: map[string]int{}, Renders: 1},
+			wantKept:   []string{"abc.jpeg"},
+		},
+		"unknown id": {
+			query:       "?id=bird",
+			wantStatus:  http.StatusOK,
+			want:        CachePurge{Providers: map[string]int{"cache": 1}},
+			wantKept:    []string{"abc.jpeg", "def.jpeg"},
+			wantEvicted: []string{"bird"},
+		},
+		"all": {
+			query:       "?all=true",
+			wantStatus:  http.StatusOK,
+			want:        CachePurge{Providers: map[string]int{"cache": 2}, Renders: 2, Sources: 2},
+			wantEvicted: []string{"cat", "dog"},
+		},
+		"provider error": {
+			query:      "?all=true",
+			cacheErr:   errProviderDown,
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			instance, provider := newAdminService(t, testCase.cacheErr)
+
+			writer := serveAdmin(instance, http.MethodDelete, "/cache"+testCase.query, adminToken)
+
+			if writer.Code != testCase.wantStatus {
+				t.Fatalf("status = %d, want %d", writer.Code, testCase.wantStatus)
+			}
+
+			if testCase.wantStatus != http.StatusOK && testCase.wantStatus != http.StatusBadRequest {
+				return
+			}
+
+			if testCase.wantStatus == http.StatusOK {
+				var got CachePurge
+				if err := json.NewDecoder(writer.Body).Decode(&got); err != nil {
+					t.Fatalf("decode purge: %s", err)
+				}
+
+				if !reflect.DeepEqual(got, testCase.want) {
+					t.Errorf("purge = %+v, want %+v", got, testCase.want)
+				}
+			}
+
+			renders, err := instance.listRenders()
+			if err != nil {
+				t.Fatalf("listRenders() = `%s`", err)
+			}
+
+			var kept []string
+			for _, render := range renders {
+				kept = append(kept, filepath.Base(render.filename))
+
+				if _, err = os.Stat(render.filename + metadataExtension); err != nil {
+					t.Errorf("metadata of `%s` = `%s`", render.filename, err)
+				}
+			}
+
+			if !slices.Equal(kept, testCase.wantKept) {
+				t.Errorf("kept %v, want %v", kept, testCase.wantKept)
+			}
+
+			if !slices.Equal(provider.evicted, testCase.wantEvicted) {
+				t.Errorf("evicted %v, want %v", provider.evicted, testCase.wantEvicted)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package kitten
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ViBiOh/httputils/v4/pkg/httperror"
+	"github.com/ViBiOh/httputils/v4/pkg/httpjson"
+	"github.com/ViBiOh/kitten/pkg/fetch"
+)
+
+const (
+	maxRenderRequestSize int64 = 64 << 10
+	maxRenderWidth             = 2048
+	maxStroke                  = 10
+)
+
+var (
+	errInvalidRender = errors.New("invalid render request")
+	renderedFilename = regexp.MustCompile(`^[a-zA-Z0-9]+\.(jpeg|png|gif)$`)
+)
+
+type RenderSource struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	URL      string `json:"url"`
+}
+
+type RenderRequest struct {
+	Style    *Style       `json:"style"`
+	Source   RenderSource `json:"source"`
+	Layout   string       `json:"layout"`
+	Format   string       `json:"format"`
+	Captions []string     `json:"captions"`
+	Width    int          `json:"width"`
+	Quality  int          `json:"quality"`
+}
+
+type Attribution struct {
+	Provider  string `json:"provider,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Author    string `json:"author,omitempty"`
+	AuthorURL string `json:"author_url,omitempty"`
+}
+
+type RenderMetadata struct {
+	Attribution Attribution `json:"attribution"`
+	URL         string      `json:"url"`
+	CacheKey    string      `json:"cache_key"`
+	Format      string      `json:"format"`
+	Width       int         `json:"width"`
+	Height      int         `json:"height"`
+	Size        int64       `json:"size"`
+}
+
+type SearchResult struct {
+	Attribution Attribution  `json:"attribution"`
+	Source      RenderSource `json:"source"`
+	Kind        string       `json:"kind"`
+	Next        string       `json:"next,omitempty"`
+	Fallback    bool         `json:"fallback,omitempty"`
+}
+
+// RenderHandler renders a meme from a JSON render spec, answering the image or its metadata
+func (s Service) RenderHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+
+		var request RenderRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRenderRequestSize)).Decode(&request); err != nil {
+			httperror.BadRequest(ctx, w, fmt.Errorf("parse render request: %w", err))
+			return
+		}
+
+		spec, attribution, err := s.resolveRenderRequest(ctx, request, true)
+		if err != nil {
+			handleRenderError(ctx, w, err)
+			return
+		}
+
+		s.serveRender(w, r, spec, attribution)
+	})
+}
+
+// RenderedHandler serves a previously rendered meme from the render cache
+func (s Service) RenderedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isReadMethod(r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+
+		name := r.PathValue("file")
+		if !renderedFilename.MatchString(name) {
+			httperror.BadRequest(ctx, w, fmt.Errorf("invalid render name `%s`", name))
+			return
+		}
+
+		if !s.serveFile(w, r, filepath.Join(s.rendersFolder, name)) {
+			httperror.NotFound(ctx, w, fmt.Errorf("render `%s` not found", name))
+		}
+	})
+}
+
+func (s Service) serveRender(w http.ResponseWriter, r *http.Request, spec renderSpec, attribution Attribution) {
+	ctx := r.Context()
+
+	filename, size, err := s.generateAndStore(ctx, spec)
+	if err != nil {
+		handleRenderError(ctx, w, err)
+		return
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), "application/json") {
+		if !s.serveFile(w, r, filename) {
+			httperror.InternalServerError(ctx, w, errors.New("render is not available"))
+		}
+
+		return
+	}
+
+	metadata, err := s.getRenderMetadata(filename, spec, attribution)
+	if err != nil {
+		httperror.InternalServerError(ctx, w, err)
+		return
+	}
+
+	metadata.Size = size
+
+	httpjson.Write(ctx, w, http.StatusOK, metadata)
+}
+
+// Render renders a meme from a render spec into the writer, gifs being written frame by frame and flushed as they come
+func (s Service) Render(ctx context.Context, request RenderRequest, writer io.Writer, flush func() error) (RenderMetadata, error) {
+	spec, attribution, err := s.resolveRenderRequest(ctx, request, true)
+	if err != nil {
+		return RenderMetadata{}, err
+	}
+
+	filename := s.getCacheFilename(spec)
+
+	if _, err = os.Stat(filename); spec.kind() == gifKind && os.IsNotExist(err) {
+		err = s.pipeGif(ctx, spec, writer, flush)
+	} else {
+		err = s.copyRender(ctx, spec, writer, flush)
+	}
+
+	if err != nil {
+		return RenderMetadata{}, err
+	}
+
+	metadata, err := s.getRenderMetadata(filename, spec, attribution)
+	if err != nil {
+		return RenderMetadata{}, err
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return RenderMetadata{}, fmt.Errorf("stat render: %w", err)
+	}
+
+	metadata.Size = info.Size()
+
+	return metadata, nil
+}
+
+func (s Service) copyRender(ctx context.Context, spec renderSpec, writer io.Writer, flush func() error) error {
+	filename, _, err := s.generateAndStore(ctx, spec)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("open render: %w", err)
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			slog.LogAttrs(ctx, slog.LevelWarn, "close render", slog.Any("error", closeErr))
+		}
+	}()
+
+	if _, err = io.Copy(writer, file); err != nil {
+		return fmt.Errorf("copy render: %w", err)
+	}
+
+	return flush()
+}
+
+// Search finds a source of the given kind, image or gif, for the query
+func (s Service) Search(ctx context.Context, kind, query, pos string) (SearchResult, error) {
+	memeKind := parseKind(kind)
+	if memeKind == unkownKind {
+		return SearchResult{}, fmt.Errorf("%w: unknown kind `%s`", errInvalidRender, kind)
+	}
+
+	result, err := s.search(ctx, memeKind, query, pos)
+	if err != nil {
+		return SearchResult{}, fmt.Errorf("search %s: %w", memeKind, err)
+	}
+
+	return SearchResult{
+		Kind:        string(memeKind),
+		Source:      RenderSource{Provider: result.provider.Name(), ID: result.media.ID},
+		Attribution: result.media.Attribution,
+		Next:        result.next,
+		Fallback:    result.fallback,
+	}, nil
+}
+
+func (s Service) getRenderMetadata(filename string, spec renderSpec, attribution Attribution) (RenderMetadata, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return RenderMetadata{}, fmt.Errorf("open render: %w", err)
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			slog.LogAttrs(context.Background(), slog.LevelWarn, "close render", slog.Any("error", closeErr))
+		}
+	}()
+
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return RenderMetadata{}, fmt.Errorf("decode render config: %w", err)
+	}
+
+	return RenderMetadata{
+		URL:         fmt.Sprintf("%s/api/render/%s", s.website, filepath.Base(filename)),
+		CacheKey:    spec.key(),
+		Format:      spec.format,
+		Width:       config.Width,
+		Height:      config.Height,
+		Attribution: attribution,
+	}, nil
+}
+
+func (s Service) resolveRenderRequest(ctx context.Context, request RenderRequest, track bool) (renderSpec, Attribution, error) {
+	var from source
+	var attribution Attribution
+
+	switch request.Source.Provider {
+	case "":
+		if len(request.Source.URL) == 0 {
+			return renderSpec{}, attribution, fmt.Errorf("%w: source provider or url is required", errInvalidRender)
+		}
+
+		if !s.urlSources {
+			return renderSpec{}, attribution, fmt.Errorf("%w: url source is disabled", errInvalidRender)
+		}
+
+		format := jpegFormat
+		if request.Format == gifFormat {
+			format = gifFormat
+		}
+
+		from = newURLSource(request.Source.URL, format)
+
+	default:
+		if len(request.Source.ID) == 0 {
+			return renderSpec{}, attribution, fmt.Errorf("%w: source id is required", errInvalidRender)
+		}
+
+		provider, kind, media, err := s.providers.get(ctx, request.Source.Provider, request.Source.ID)
+		if err != nil {
+			return renderSpec{}, attribution, fmt.Errorf("get source: %w", err)
+		}
+
+		if track {
+			go provider.Report(context.WithoutCancel(ctx), media, "")
+		}
+
+		from = newMediaSource(kind, provider, media)
+		attribution = media.Attribution
+	}
+
+	spec, err := newRenderSpecFromRequest(from, request)
+
+	return spec, attribution, err
+}
+
+func newRenderSpecFromRequest(from source, request RenderRequest) (renderSpec, error) {
+	var captions []string
+	for _, caption := range request.Captions {
+		if caption = strings.TrimSpace(caption); len(caption) != 0 {
+			captions = append(captions, caption)
+		}
+	}
+
+	if len(captions) == 0 {
+		return renderSpec{}, fmt.Errorf("%w: captions are required", errInvalidRender)
+	}
+
+	spec := newRenderSpec(from, strings.Join(captions, " "))
+
+	switch request.Layout {
+	case "", topLayout, bottomLayout:
+		if len(request.Layout) != 0 {
+			spec.layout = request.Layout
+		}
+	case splitLayout:
+		spec.layout = splitLayout
+		spec.caption = captions[0] + "\n" + strings.Join(captions[1:], " ")
+	default:
+		return renderSpec{}, fmt.Errorf("%w: unknown layout `%s`", errInvalidRender, request.Layout)
+	}
+
+	if request.Style != nil {
+		if _, err := fs.Stat(content, fmt.Sprintf("fonts/%s.ttf", request.Style.Font)); err != nil {
+			return renderSpec{}, fmt.Errorf("%w: unknown font `%s`", errInvalidRender, request.Style.Font)
+		}
+
+		if request.Style.Stroke < 0 || request.Style.Stroke > maxStroke {
+			return renderSpec{}, fmt.Errorf("%w: stroke must be between 0 and %d", errInvalidRender, maxStroke)
+		}
+
+		spec.style = *request.Style
+	}
+
+	switch request.Format {
+	case "":
+	case jpegFormat, pngFormat:
+		if from.format == gifFormat {
+			return renderSpec{}, fmt.Errorf("%w: gif source can only be rendered as gif", errInvalidRender)
+		}
+
+		spec.format = request.Format
+	case gifFormat:
+		if from.format != gifFormat {
+			return renderSpec{}, fmt.Errorf("%w: image source can't be rendered as gif", errInvalidRender)
+		}
+	default:
+		return renderSpec{}, fmt.Errorf("%w: unknown format `%s`", errInvalidRender, request.Format)
+	}
+
+	if request.Width < 0 || request.Width > maxRenderWidth {
+		return renderSpec{}, fmt.Errorf("%w: width must be between 0 and %d", errInvalidRender, maxRenderWidth)
+	}
+
+	if request.Width != 0 && spec.format == gifFormat {
+		return renderSpec{}, fmt.Errorf("%w: gif can't be resized", errInvalidRender)
+	}
+
+	spec.width = request.Width
+
+	if request.Quality < 0 || request.Quality > 100 {
+		return renderSpec{}, fmt.Errorf("%w: quality must be between 0 and 100", errInvalidRender)
+	}
+
+	if request.Quality != 0 {
+		spec.quality = request.Quality
+	}
+
+	return spec, nil
+}
+
+func handleRenderError(ctx context.Context, w http.ResponseWriter, err error) {
+	if errors.Is(err, context.Canceled) {
+		slog.LogAttrs(ctx, slog.LevelDebug, "render canceled", slog.Any("error", err))
+		return
+	}
+
+	switch RenderErrorStatus(err) {
+	case http.StatusBadRequest:
+		httperror.BadRequest(ctx, w, err)
+	case http.StatusNotFound:
+		httperror.NotFound(ctx, w, err)
+	case http.StatusServiceUnavailable:
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		httperror.InternalServerError(ctx, w, err)
+	}
+}
+
+// RenderErrorStatus returns the HTTP status matching a render error
+func RenderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errInvalidRender), errors.Is(err, ErrLimitExceeded):
+		return http.StatusBadRequest
+	case errors.Is(err, fetch.ErrForbiddenScheme), errors.Is(err, fetch.ErrForbiddenHost), errors.Is(err, fetch.ErrForbiddenAddress), errors.Is(err, fetch.ErrNotAnImage):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrOverloaded), errors.Is(err, ErrUnavailable), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
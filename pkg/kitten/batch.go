@@ -0,0 +1,176 @@
+package kitten
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ViBiOh/httputils/v4/pkg/concurrent"
+	"github.com/ViBiOh/httputils/v4/pkg/httperror"
+	"github.com/ViBiOh/httputils/v4/pkg/httpjson"
+	"github.com/ViBiOh/kitten/pkg/ratelimit"
+)
+
+const (
+	maxBatchRequestSize int64 = 1 << 20
+	batchManifest             = "manifest.json"
+)
+
+type BatchItem struct {
+	Metadata *RenderMetadata `json:"metadata,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	filename string
+	Index    int `json:"index"`
+}
+
+// BatchHandler renders a list of JSON render specs, answering a zip archive or the list of rendered URLs
+func (s Service) BatchHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+
+		var requests []RenderRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchRequestSize)).Decode(&requests); err != nil {
+			httperror.BadRequest(ctx, w, fmt.Errorf("parse batch request: %w", err))
+			return
+		}
+
+		if len(requests) == 0 {
+			httperror.BadRequest(ctx, w, fmt.Errorf("%w: batch is empty", errInvalidRender))
+			return
+		}
+
+		if len(requests) > s.batchSize {
+			httperror.BadRequest(ctx, w, fmt.Errorf("%w: batch exceeds %d items", errInvalidRender, s.batchSize))
+			return
+		}
+
+		items := s.renderBatch(ctx, requests, s.rateLimiter.ClientIP(r))
+
+		if strings.Contains(r.Header.Get("Accept"), "application/json") {
+			httpjson.Write(ctx, w, http.StatusOK, items)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="memes.zip"`)
+		w.WriteHeader(http.StatusOK)
+
+		if err := writeBatchArchive(w, items); err != nil {
+			slog.LogAttrs(ctx, slog.LevelError, "write batch archive", slog.Any("error", err))
+		}
+	})
+}
+
+// renderBatch renders the requests, each one charged to the rate limit of the client like a single render
+func (s Service) renderBatch(ctx context.Context, requests []RenderRequest, clientIP string) []BatchItem {
+	items := make([]BatchItem, len(requests))
+	limiter := concurrent.NewLimiter(s.batchWorkers)
+
+	for index, request := range requests {
+		// the first item is charged by the rate limit of the request itself
+		if index > 0 {
+			if ok, retryAfter := s.rateLimiter.Allow(ctx, ratelimit.IP, clientIP); !ok {
+				items[index] = BatchItem{Index: index, Error: fmt.Sprintf("rate limit exceeded, retry in %d seconds", ratelimit.RetryAfterSeconds(retryAfter))}
+				continue
+			}
+		}
+
+		limiter.Go(func() {
+			items[index] = s.renderBatchItem(ctx, index, request)
+		})
+	}
+
+	limiter.Wait()
+
+	return items
+}
+
+func (s Service) renderBatchItem(ctx context.Context, index int, request RenderRequest) BatchItem {
+	item := BatchItem{Index: index}
+
+	spec, attribution, err := s.resolveRenderRequest(ctx, request, true)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+
+	filename, size, err := s.generateAndStore(ctx, spec)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+
+	metadata, err := s.getRenderMetadata(filename, spec, attribution)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+
+	metadata.Size = size
+
+	item.Metadata = &metadata
+	item.filename = filename
+
+	return item
+}
+
+func writeBatchArchive(writer io.Writer, items []BatchItem) error {
+	archive := zip.NewWriter(writer)
+
+	for _, item := range items {
+		if len(item.filename) == 0 {
+			continue
+		}
+
+		if err := addToArchive(archive, fmt.Sprintf("%03d-%s", item.Index+1, filepath.Base(item.filename)), item.filename); err != nil {
+			return err
+		}
+	}
+
+	manifest, err := archive.Create(batchManifest)
+	if err != nil {
+		return fmt.Errorf("create manifest: %w", err)
+	}
+
+	if err = json.NewEncoder(manifest).Encode(items); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+
+	return archive.Close()
+}
+
+func addToArchive(archive *zip.Writer, name, filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("open render: %w", err)
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			slog.LogAttrs(context.Background(), slog.LevelWarn, "close render", slog.Any("error", closeErr))
+		}
+	}()
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return fmt.Errorf("create archive entry: %w", err)
+	}
+
+	if _, err = io.Copy(entry, file); err != nil {
+		return fmt.Errorf("copy render to archive: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package kitten
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ViBiOh/httputils/v4/pkg/redis"
+	"github.com/ViBiOh/kitten/pkg/ratelimit"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type tokenRedis struct {
+	redis.Client
+	tokens atomic.Int64
+}
+
+func (tr *tokenRedis) Enabled() bool {
+	return true
+}
+
+func (tr *tokenRedis) Eval(_ context.Context, _ *goredis.Script, _ []string, _ []any) (any, error) {
+	if tr.tokens.Add(-1) < 0 {
+		return []any{int64(0), int64(1000)}, nil
+	}
+
+	return []any{int64(1), int64(0)}, nil
+}
+
+func TestRenderBatch(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		items       int
+		tokens      int64
+		wantLimited int
+	}{
+		"single item": {
+			1,
+			0,
+			0,
+		},
+		"enough tokens": {
+			3,
+			2,
+			0,
+		},
+		"charged per item": {
+			4,
+			1,
+			2,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			redisClient := &tokenRedis{}
+			redisClient.tokens.Store(testCase.tokens)
+
+			rateLimiter, err := ratelimit.New(ratelimit.Flags(flag.NewFlagSet(intention, flag.ContinueOnError), ""), redisClient)
+			if err != nil {
+				t.Fatalf("create rate limiter: %s", err)
+			}
+
+			instance := Service{rateLimiter: rateLimiter, batchWorkers: 1}
+
+			items := instance.renderBatch(context.Background(), make([]RenderRequest, testCase.items), "192.0.2.1")
+
+			var limited int
+			for index, item := range items {
+				if item.Index != index {
+					t.Errorf("items[%d].Index = %d", index, item.Index)
+				}
+
+				if strings.HasPrefix(item.Error, "rate limit exceeded") {
+					limited++
+				}
+			}
+
+			if limited != testCase.wantLimited {
+				t.Errorf("renderBatch() limited %d items, want %d", limited, testCase.wantLimited)
+			}
+		})
+	}
+}
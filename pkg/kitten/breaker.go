@@ -0,0 +1,92 @@
+package kitten
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker skips a provider after consecutive failures, letting a single call through each cooldown to probe it
+type breaker struct {
+	states    map[string]*breakerState
+	cooldown  time.Duration
+	threshold int
+	mutex     sync.Mutex
+}
+
+type breakerState struct {
+	openUntil time.Time
+	failures  int
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &breaker{
+		states:    make(map[string]*breakerState),
+		cooldown:  cooldown,
+		threshold: threshold,
+	}
+}
+
+// allow tells if the provider can be called, delaying the next probe by a cooldown when the circuit is open
+func (b *breaker) allow(name string) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state, ok := b.states[name]
+	if !ok || state.openUntil.IsZero() {
+		return true
+	}
+
+	now := time.Now()
+	if now.Before(state.openUntil) {
+		return false
+	}
+
+	state.openUntil = now.Add(b.cooldown)
+
+	return true
+}
+
+// success closes the circuit of the provider
+func (b *breaker) success(name string) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.states, name)
+}
+
+// failure counts a failure of the provider and tells if its circuit is now open, at once when it is rate limited
+func (b *breaker) failure(name string, rateLimited bool) bool {
+	if b == nil {
+		return false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state, ok := b.states[name]
+	if !ok {
+		state = &breakerState{}
+		b.states[name] = state
+	}
+
+	state.failures++
+
+	if rateLimited || state.failures >= b.threshold {
+		state.openUntil = time.Now().Add(b.cooldown)
+		return true
+	}
+
+	return false
+}
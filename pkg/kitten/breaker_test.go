@@ -0,0 +1,143 @@
+package kitten
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	t.Parallel()
+
+	const name = "unsplash"
+
+	cases := map[string]struct {
+		threshold int
+		events    []string
+		want      bool
+	}{
+		"closed": {
+			3,
+			nil,
+			true,
+		},
+		"under threshold": {
+			3,
+			[]string{"failure", "failure"},
+			true,
+		},
+		"open on threshold": {
+			3,
+			[]string{"failure", "failure", "failure"},
+			false,
+		},
+		"open on rate limit": {
+			3,
+			[]string{"rateLimited"},
+			false,
+		},
+		"half open after cooldown": {
+			3,
+			[]string{"rateLimited", "cooldown"},
+			true,
+		},
+		"single probe when half open": {
+			3,
+			[]string{"rateLimited", "cooldown", "allow"},
+			false,
+		},
+		"open on probe failure": {
+			3,
+			[]string{"rateLimited", "cooldown", "allow", "failure"},
+			false,
+		},
+		"close on probe success": {
+			3,
+			[]string{"rateLimited", "cooldown", "allow", "success"},
+			true,
+		},
+		"success resets failures": {
+			3,
+			[]string{"failure", "failure", "success", "failure", "failure"},
+			true,
+		},
+		"disabled": {
+			0,
+			[]string{"rateLimited", "failure", "failure", "failure"},
+			true,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			instance := newBreaker(testCase.threshold, time.Hour)
+
+			for _, event := range testCase.events {
+				switch event {
+				case "failure":
+					instance.failure(name, false)
+				case "rateLimited":
+					instance.failure(name, true)
+				case "success":
+					instance.success(name)
+				case "allow":
+					instance.allow(name)
+				case "cooldown":
+					instance.states[name].openUntil = time.Now().Add(-time.Second)
+				}
+			}
+
+			if got := instance.allow(name); got != testCase.want {
+				t.Errorf("allow() = %t, want %t", got, testCase.want)
+			}
+
+			if instance != nil && !instance.allow("giphy") {
+				t.Error("allow() = false for another provider, want true")
+			}
+		})
+	}
+}
+
+func TestBreakerFailure(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		failures    int
+		rateLimited bool
+		want        bool
+	}{
+		"first failure": {
+			1,
+			false,
+			false,
+		},
+		"threshold reached": {
+			2,
+			false,
+			true,
+		},
+		"rate limited": {
+			1,
+			true,
+			true,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			instance := newBreaker(2, time.Hour)
+
+			var got bool
+			for range testCase.failures {
+				got = instance.failure("klipy", testCase.rateLimited)
+			}
+
+			if got != testCase.want {
+				t.Errorf("failure() = %t, want %t", got, testCase.want)
+			}
+		})
+	}
+}
@@ -1,28 +1,36 @@
 package kitten
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"image"
-	"image/jpeg"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
-
-	"github.com/ViBiOh/httputils/v4/pkg/hash"
+	"slices"
+	"strings"
+	"time"
 )
 
-func (s Service) serveCached(ctx context.Context, w http.ResponseWriter, id, caption string, gif bool) bool {
-	var filename string
-	if gif {
-		filename = s.getGifCacheFilename(id, caption)
-	} else {
-		filename = s.getCacheFilename(id, caption)
+// rendersPruneInterval is the delay between two checks of the renders age and size
+const rendersPruneInterval = time.Minute * 5
+
+// serveCached answers the render of the spec if it exists, conditional requests included
+func (s Service) serveCached(w http.ResponseWriter, r *http.Request, spec renderSpec) bool {
+	if !s.serveFile(w, r, s.getCacheFilename(spec)) {
+		return false
 	}
 
+	s.increaseCached(r.Context())
+
+	return true
+}
+
+func (s Service) serveFile(w http.ResponseWriter, r *http.Request, filename string) bool {
+	ctx := r.Context()
+
 	file, err := os.OpenFile(filename, os.O_RDONLY, 0o600)
 	if err != nil {
 		if !os.IsNotExist(err) {
@@ -32,41 +40,104 @@ func (s Service) serveCached(ctx context.Context, w http.ResponseWriter, id, cap
 		return false
 	}
 
-	buffer := bufferPool.Get().(*bytes.Buffer)
-	defer bufferPool.Put(buffer)
-
-	w.Header().Add("Cache-Control", cacheControlDuration)
-	if gif {
-		w.Header().Set("Content-Type", "image/gif")
-	} else {
-		w.Header().Set("Content-Type", "image/jpeg")
-	}
-	w.WriteHeader(http.StatusOK)
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			slog.LogAttrs(ctx, slog.LevelWarn, "close file from local cache", slog.Any("error", closeErr))
+		}
+	}()
 
-	if _, err = io.CopyBuffer(w, file, buffer.Bytes()); err != nil {
-		slog.LogAttrs(ctx, slog.LevelError, "write file from local cache", slog.Any("error", err))
+	info, err := file.Stat()
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "stat file from local cache", slog.Any("error", err))
 		return false
 	}
 
-	s.increaseCached(ctx)
+	s.serveContent(w, r, filename, info.ModTime(), file)
 
 	return true
 }
 
-func (s Service) storeInCache(ctx context.Context, id, caption string, image image.Image) {
-	if file, err := os.OpenFile(s.getCacheFilename(id, caption), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600); err != nil {
-		slog.LogAttrs(ctx, slog.LevelError, "open image to local cache", slog.Any("error", err))
-	} else if err := jpeg.Encode(file, image, &jpeg.Options{Quality: 80}); err != nil {
+func (s Service) serveContent(w http.ResponseWriter, r *http.Request, filename string, modTime time.Time, content io.ReadSeeker) {
+	setContentHeaders(w.Header(), filename)
+
+	http.ServeContent(w, r, "", modTime, content)
+}
+
+func setContentHeaders(header http.Header, filename string) {
+	header.Add("Cache-Control", cacheControlDuration)
+	header.Set("ETag", getETag(filename))
+	header.Set("Content-Type", getContentType(filename))
+}
+
+func getETag(filename string) string {
+	return `"` + strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)) + `"`
+}
+
+func getContentType(filename string) string {
+	switch filepath.Ext(filename) {
+	case "." + gifFormat:
+		return "image/gif"
+	case "." + pngFormat:
+		return "image/png"
+	default:
+		return "image/jpeg"
+	}
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+func (s Service) storeInCache(ctx context.Context, spec renderSpec, image image.Image) {
+	filename := s.getCacheFilename(spec)
+
+	if err := writeCacheFile(filename, func(writer io.Writer) error {
+		return spec.encode(writer, image)
+	}); err != nil {
 		slog.LogAttrs(ctx, slog.LevelError, "write image to local cache", slog.Any("error", err))
+		return
+	}
+
+	storeMetadata(ctx, filename, spec)
+}
+
+func writeCacheFile(filename string, write func(io.Writer) error) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), "*"+tmpSuffix)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+
+	err = write(file)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), filename)
+	}
+
+	if err != nil {
+		_ = os.Remove(file.Name())
 	}
+
+	return err
+}
+
+func (s Service) getCacheFilename(spec renderSpec) string {
+	return filepath.Join(s.rendersFolder, spec.key()+"."+spec.format)
 }
 
-func (s Service) getCacheFilename(id, caption string) string {
-	return filepath.Join(s.tmpFolder, hash.String(fmt.Sprintf("%s:%s", id, caption))+".jpeg")
+func (s Service) generateAndStore(ctx context.Context, spec renderSpec) (string, int64, error) {
+	if spec.kind() == gifKind {
+		return s.generateAndStoreGif(ctx, spec)
+	}
+
+	return s.generateAndStoreImage(ctx, spec)
 }
 
-func (s Service) generateAndStoreImage(ctx context.Context, id, from, caption string) (string, int64, error) {
-	imagePath := s.getCacheFilename(id, caption)
+func (s Service) generateAndStoreImage(ctx context.Context, spec renderSpec) (string, int64, error) {
+	imagePath := s.getCacheFilename(spec)
 
 	info, err := os.Stat(imagePath)
 	if err != nil && !os.IsNotExist(err) {
@@ -74,12 +145,12 @@ func (s Service) generateAndStoreImage(ctx context.Context, id, from, caption st
 	}
 
 	if info == nil {
-		imageOutput, err := s.generateImage(ctx, from, caption)
+		imageOutput, err := s.generateImage(ctx, spec)
 		if err != nil {
 			return "", 0, fmt.Errorf("generate imageOutput: %w", err)
 		}
 
-		s.storeInCache(ctx, id, caption, imageOutput)
+		s.storeInCache(ctx, spec, imageOutput)
 
 		info, err = os.Stat(imagePath)
 		if err != nil {
@@ -89,3 +160,54 @@ func (s Service) generateAndStoreImage(ctx context.Context, id, from, caption st
 
 	return imagePath, info.Size(), nil
 }
+
+func (s Service) pruneRendersPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(rendersPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		s.pruneRenders(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneRenders removes the renders older than the TTL, then the oldest ones until the renders fit in their size budget
+func (s Service) pruneRenders(ctx context.Context) {
+	renders, err := s.listRenders()
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "list renders for pruning", slog.Any("error", err))
+		return
+	}
+
+	slices.SortFunc(renders, func(a, b renderFile) int {
+		return a.info.ModTime().Compare(b.info.ModTime())
+	})
+
+	var size int64
+	for _, render := range renders {
+		size += render.info.Size()
+	}
+
+	now := time.Now()
+
+	for _, render := range renders {
+		expired := s.rendersTTL > 0 && now.Sub(render.info.ModTime()) > s.rendersTTL
+		oversized := s.rendersSize > 0 && size > s.rendersSize
+
+		if !expired && !oversized {
+			break
+		}
+
+		if err := removeRender(render.filename); err != nil {
+			slog.LogAttrs(ctx, slog.LevelError, "prune render", slog.String("filename", render.filename), slog.Any("error", err))
+			continue
+		}
+
+		size -= render.info.Size()
+	}
+}
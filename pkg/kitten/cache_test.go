@@ -0,0 +1,265 @@
+package kitten
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestPruneRenders(t *testing.T) {
+	t.Parallel()
+
+	type render struct {
+		name string
+		age  time.Duration
+		size int
+	}
+
+	renders := []render{
+		{"old.jpeg", time.Hour * 48, 100},
+		{"middle.gif", time.Hour * 2, 100},
+		{"recent.png", time.Minute, 100},
+	}
+
+	cases := map[string]struct {
+		ttl  time.Duration
+		size int64
+		want []string
+	}{
+		"no limit": {
+			0,
+			0,
+			[]string{"middle.gif", "old.jpeg", "recent.png"},
+		},
+		"expired": {
+			time.Hour * 24,
+			0,
+			[]string{"middle.gif", "recent.png"},
+		},
+		"oversized": {
+			0,
+			150,
+			[]string{"recent.png"},
+		},
+		"fitting": {
+			0,
+			300,
+			[]string{"middle.gif", "old.jpeg", "recent.png"},
+		},
+		"expired and oversized": {
+			time.Hour * 24,
+			250,
+			[]string{"middle.gif", "recent.png"},
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			instance := Service{rendersFolder: t.TempDir(), rendersTTL: testCase.ttl, rendersSize: testCase.size}
+
+			for _, item := range renders {
+				filename := filepath.Join(instance.rendersFolder, item.name)
+
+				if err := os.WriteFile(filename, make([]byte, item.size), 0o600); err != nil {
+					t.Fatalf("write render: %s", err)
+				}
+
+				if err := os.WriteFile(filename+metadataExtension, []byte("{}"), 0o600); err != nil {
+					t.Fatalf("write metadata: %s", err)
+				}
+
+				modTime := time.Now().Add(-item.age)
+				if err := os.Chtimes(filename, modTime, modTime); err != nil {
+					t.Fatalf("age render: %s", err)
+				}
+			}
+
+			instance.pruneRenders(context.Background())
+
+			entries, err := os.ReadDir(instance.rendersFolder)
+			if err != nil {
+				t.Fatalf("read renders: %s", err)
+			}
+
+			var got, metadatas []string
+
+			for _, entry := range entries {
+				if filepath.Ext(entry.Name()) == metadataExtension {
+					metadatas = append(metadatas, entry.Name())
+				} else {
+					got = append(got, entry.Name())
+				}
+			}
+
+			if !slices.Equal(got, testCase.want) {
+				t.Errorf("pruneRenders() kept %v, want %v", got, testCase.want)
+			}
+
+			if len(metadatas) != len(got) {
+				t.Errorf("pruneRenders() kept metadata %v, want one per render", metadatas)
+			}
+		})
+	}
+}
+
+func TestServeFile(t *testing.T) {
+	t.Parallel()
+
+	instance := Service{}
+
+	folder := t.TempDir()
+	content := []byte("0123456789")
+	modTime := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	filename := filepath.Join(folder, "abc123.jpeg")
+	if err := os.WriteFile(filename, content, 0o600); err != nil {
+		t.Fatalf("write render: %s", err)
+	}
+
+	if err := os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatalf("age render: %s", err)
+	}
+
+	cases := map[string]struct {
+		method           string
+		filename         string
+		headers          map[string]string
+		fresh            bool
+		wantServed       bool
+		wantStatus       int
+		wantBody         string
+		wantLastModified string
+		wantContentRange string
+	}{
+		"get": {
+			method:           http.MethodGet,
+			filename:         filename,
+			wantServed:       true,
+			wantStatus:       http.StatusOK,
+			wantBody:         string(content),
+			wantLastModified: modTime.Format(http.TimeFormat),
+		},
+		"missing": {
+			method:   http.MethodGet,
+			filename: filepath.Join(folder, "missing.jpeg"),
+		},
+		"matching etag": {
+			method:     http.MethodGet,
+			filename:   filename,
+			headers:    map[string]string{"If-None-Match": `"abc123"`},
+			wantServed: true,
+			wantStatus: http.StatusNotModified,
+		},
+		"other etag": {
+			method:           http.MethodGet,
+			filename:         filename,
+			headers:          map[string]string{"If-None-Match": `"other"`},
+			wantServed:       true,
+			wantStatus:       http.StatusOK,
+			wantBody:         string(content),
+			wantLastModified: modTime.Format(http.TimeFormat),
+		},
+		"not modified since": {
+			method:     http.MethodGet,
+			filename:   filename,
+			headers:    map[string]string{"If-Modified-Since": modTime.Format(http.TimeFormat)},
+			wantServed: true,
+			wantStatus: http.StatusNotModified,
+		},
+		"head": {
+			method:           http.MethodHead,
+			filename:         filename,
+			wantServed:       true,
+			wantStatus:       http.StatusOK,
+			wantLastModified: modTime.Format(http.TimeFormat),
+		},
+		"range": {
+			method:           http.MethodGet,
+			filename:         filename,
+			headers:          map[string]string{"Range": "bytes=2-5"},
+			wantServed:       true,
+			wantStatus:       http.StatusPartialContent,
+			wantBody:         "2345",
+			wantLastModified: modTime.Format(http.TimeFormat),
+			wantContentRange: "bytes 2-5/10",
+		},
+		"fresh render": {
+			method:     http.MethodGet,
+			filename:   filename,
+			fresh:      true,
+			wantServed: true,
+			wantStatus: http.StatusOK,
+			wantBody:   string(content),
+		},
+		"fresh render matching etag": {
+			method:     http.MethodGet,
+			filename:   filename,
+			headers:    map[string]string{"If-None-Match": `"abc123"`},
+			fresh:      true,
+			wantServed: true,
+			wantStatus: http.StatusNotModified,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(testCase.method, "/api/abc123", nil)
+			for key, value := range testCase.headers {
+				request.Header.Set(key, value)
+			}
+
+			writer := httptest.NewRecorder()
+
+			served := true
+			if testCase.fresh {
+				instance.serveContent(writer, request, testCase.filename, time.Time{}, bytes.NewReader(content))
+			} else {
+				served = instance.serveFile(writer, request, testCase.filename)
+			}
+
+			if served != testCase.wantServed {
+				t.Fatalf("serveFile() = %t, want %t", served, testCase.wantServed)
+			}
+
+			if !served {
+				return
+			}
+
+			if writer.Code != testCase.wantStatus {
+				t.Errorf("status = %d, want %d", writer.Code, testCase.wantStatus)
+			}
+
+			if got := writer.Body.String(); got != testCase.wantBody {
+				t.Errorf("body = `%s`, want `%s`", got, testCase.wantBody)
+			}
+
+			if got := writer.Header().Get("ETag"); got != `"abc123"` {
+				t.Errorf("ETag = `%s`, want `\"abc123\"`", got)
+			}
+
+			if got := writer.Header().Get("Last-Modified"); got != testCase.wantLastModified {
+				t.Errorf("Last-Modified = `%s`, want `%s`", got, testCase.wantLastModified)
+			}
+
+			if got := writer.Header().Get("Content-Range"); got != testCase.wantContentRange {
+				t.Errorf("Content-Range = `%s`, want `%s`", got, testCase.wantContentRange)
+			}
+
+			if testCase.wantStatus == http.StatusOK {
+				if got := writer.Header().Get("Content-Type"); got != "image/jpeg" {
+					t.Errorf("Content-Type = `%s`, want `image/jpeg`", got)
+				}
+			}
+		})
+	}
+}
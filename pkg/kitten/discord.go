@@ -6,8 +6,6 @@ import (
 	"net/url"
 
 	"codeberg.org/ViBiOh/ChatPotte/discord"
-	"github.com/ViBiOh/kitten/pkg/klipy"
-	"github.com/ViBiOh/kitten/pkg/unsplash"
 	"github.com/ViBiOh/kitten/pkg/version"
 )
 
@@ -28,6 +26,16 @@ func (s Service) DiscordHandler(ctx context.Context, webhook discord.Interaction
 		return discord.NewError(replace, err), false, nil
 	}
 
+	if err = s.limits.checkCaption(caption); err != nil {
+		return discord.NewEphemeral(replace, fmt.Sprintf("Your caption is too long, keep it under %d characters", s.limits.caption)), false, nil
+	}
+
+	if len(id) != 0 || len(search) != 0 {
+		if message, ok := s.allowCommand(ctx, "discord", webhook.Member.User.ID, webhook.GuildID); !ok {
+			return discord.NewEphemeral(replace, message), false, nil
+		}
+	}
+
 	if len(id) != 0 {
 		return s.handleDiscordSend(ctx, kind, id, search, caption, webhook.Member.User.ID)
 	}
@@ -102,58 +110,36 @@ func (s Service) handleDiscordSend(_ context.Context, kind memeKind, id, search,
 		deleteMessage = false
 	}
 
-	switch kind {
-	case gifKind:
-		return interactionResponse, deleteMessage, func(ctx context.Context) discord.InteractionResponse {
-			image, err := s.klipyService.Get(ctx, id)
-			if err != nil {
-				return discord.NewError(true, err)
-			}
-
-			go s.klipyService.SendAnalytics(context.WithoutCancel(ctx), image, search)
-
-			return s.getDiscordGifResponse(ctx, fmt.Sprintf("<@!%s> shares a meme", userID), false, image, caption)
+	return interactionResponse, deleteMessage, func(ctx context.Context) discord.InteractionResponse {
+		provider, query, err := s.providers.resolve(kind, search)
+		if err != nil {
+			return discord.NewError(true, err)
 		}
 
-	default:
-		return interactionResponse, deleteMessage, func(ctx context.Context) discord.InteractionResponse {
-			image, err := s.unsplashService.Get(ctx, id)
-			if err != nil {
-				return discord.NewError(true, err)
-			}
+		media, err := provider.Get(ctx, id)
+		if err != nil {
+			return discord.NewError(true, err)
+		}
 
-			go s.unsplashService.SendDownload(context.WithoutCancel(ctx), image)
+		go provider.Report(context.WithoutCancel(ctx), media, query)
 
-			return s.getDiscordUnsplashResponse(ctx, fmt.Sprintf("<@!%s> shares a meme", userID), false, image, caption)
-		}
+		return s.getDiscordResponse(ctx, kind, provider, fmt.Sprintf("<@!%s> shares a meme", userID), false, media, caption)
 	}
 }
 
 func (s Service) handleDiscordSearch(ctx context.Context, kind memeKind, search, caption string, replace bool, next string) discord.InteractionResponse {
-	var response discord.InteractionResponse
-	var id string
-
-	switch kind {
-	case gifKind:
-		image, nextValue, err := s.klipyService.Search(ctx, search, next)
-		if err != nil {
-			return discord.NewError(replace, err)
-		}
-		response = s.getDiscordGifResponse(ctx, "", true, image, caption)
-		id = image.ID
-		next = nextValue
-
-	default:
-		image, err := s.unsplashService.Search(ctx, search)
-		switch err {
-		case nil:
-			response = s.getDiscordUnsplashResponse(ctx, "", true, image, caption)
-			id = image.ID
-		default:
-			return discord.NewError(replace, err)
-		}
+	result, err := s.search(ctx, kind, search, next)
+	if err != nil {
+		return discord.NewError(replace, err)
 	}
 
+	media, next := result.media, result.next
+	search = result.search
+
+	s.enqueueNext(ctx, result.provider.Name(), result.query, caption, next)
+
+	response := s.getDiscordResponse(ctx, kind, result.provider, "", true, media, caption)
+
 	if replace {
 		response.Type = discord.UpdateMessageCallback
 	}
@@ -161,7 +147,7 @@ func (s Service) handleDiscordSearch(ctx context.Context, kind memeKind, search,
 	sendValues := url.Values{}
 	sendValues.Add("action", sendValue)
 	sendValues.Add("kind", string(kind))
-	sendValues.Add(idParam, id)
+	sendValues.Add(idParam, media.ID)
 	sendValues.Add(searchParam, search)
 	sendValues.Add(captionParam, caption)
 
@@ -196,10 +182,10 @@ func (s Service) handleDiscordSearch(ctx context.Context, kind memeKind, search,
 	return response
 }
 
-func (s Service) getDiscordUnsplashResponse(ctx context.Context, content string, ephemeral bool, image unsplash.Image, caption string) discord.InteractionResponse {
-	imagePath, size, err := s.generateAndStoreImage(ctx, image.ID, image.Raw, caption)
+func (s Service) getDiscordResponse(ctx context.Context, kind memeKind, provider Provider, content string, ephemeral bool, media Media, caption string) discord.InteractionResponse {
+	imagePath, size, err := s.generateAndStore(ctx, newRenderSpec(newMediaSource(kind, provider, media), caption))
 	if err != nil {
-		return discord.NewError(false, fmt.Errorf("generate image: %w", err))
+		return discord.NewError(false, fmt.Errorf("generate %s: %w", kind, err))
 	}
 
 	resp := discord.NewResponse(discord.ChannelMessageWithSource, content)
@@ -208,29 +194,22 @@ func (s Service) getDiscordUnsplashResponse(ctx context.Context, content string,
 		resp = resp.Ephemeral()
 	}
 
-	return resp.AddAttachment("image.jpeg", imagePath, size).AddEmbed(discord.Embed{
-		Title:  "Unsplash image",
-		URL:    image.URL,
-		Image:  discord.NewImage("attachment://image.jpeg"),
-		Author: discord.NewAuthor(image.Author, image.AuthorURL),
-	})
-}
+	filename := "image.jpeg"
+	embed := discord.Embed{
+		Title: fmt.Sprintf("%s image", media.Attribution.Provider),
+		URL:   media.Attribution.URL,
+	}
 
-func (s Service) getDiscordGifResponse(ctx context.Context, content string, ephemeral bool, image klipy.ResponseObject, caption string) discord.InteractionResponse {
-	imagePath, size, err := s.generateAndStoreGif(ctx, image.ID, image.GetImageURL(), caption)
-	if err != nil {
-		return discord.NewError(false, fmt.Errorf("generate gif: %w", err))
+	if kind == gifKind {
+		filename = "meme.gif"
+		embed.Title = fmt.Sprintf("Powered By %s", media.Attribution.Provider)
 	}
 
-	resp := discord.NewResponse(discord.ChannelMessageWithSource, content)
+	embed.Image = discord.NewImage("attachment://" + filename)
 
-	if ephemeral {
-		resp = resp.Ephemeral()
+	if len(media.Attribution.Author) != 0 {
+		embed.Author = discord.NewAuthor(media.Attribution.Author, media.Attribution.AuthorURL)
 	}
 
-	return resp.AddAttachment("meme.gif", imagePath, size).AddEmbed(discord.Embed{
-		Title: "Powered By Klipy",
-		URL:   image.URL,
-		Image: discord.NewImage("attachment://meme.gif"),
-	})
+	return resp.AddAttachment(filename, imagePath, size).AddEmbed(embed)
 }
@@ -0,0 +1,276 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ViBiOh/httputils/v4/pkg/httperror"
+	"github.com/ViBiOh/httputils/v4/pkg/httpjson"
+	"github.com/ViBiOh/kitten/pkg/ratelimit"
+)
+
+const (
+	previewPath = "/preview"
+	nextAction  = "next"
+)
+
+type Editor struct {
+	Kind     string
+	Query    string
+	Caption  string
+	Layout   string
+	Font     string
+	ID       string
+	Provider string
+	Next     string
+	Preview  string
+	Error    string
+	Fonts    []string
+	Layouts  []string
+	Stroke   float64
+}
+
+// PreviewLink is a signed preview of the web editor, with the share link of the meme when asked
+type PreviewLink struct {
+	Preview string `json:"preview"`
+	Share   string `json:"share,omitempty"`
+}
+
+// Editor computes the state of the web meme editor from the request
+func (s Service) Editor(r *http.Request) Editor {
+	urlQuery := r.URL.Query()
+
+	editor := Editor{
+		Kind:    string(imageKind),
+		Query:   strings.TrimSpace(urlQuery.Get("query")),
+		Caption: strings.TrimSpace(urlQuery.Get(captionParam)),
+		Layout:  topLayout,
+		Font:    defaultStyle.Font,
+		Stroke:  defaultStyle.Stroke,
+		ID:      strings.TrimSpace(urlQuery.Get(idParam)),
+		Next:    urlQuery.Get("pos"),
+		Fonts:   listFonts(),
+		Layouts: []string{topLayout, bottomLayout, splitLayout},
+	}
+
+	if parseKind(urlQuery.Get("kind")) == gifKind {
+		editor.Kind = string(gifKind)
+	}
+
+	if layout := urlQuery.Get("layout"); len(layout) != 0 {
+		editor.Layout = layout
+	}
+
+	if font := urlQuery.Get("font"); len(font) != 0 {
+		editor.Font = font
+	}
+
+	if stroke, err := strconv.ParseFloat(urlQuery.Get("stroke"), 64); err == nil {
+		editor.Stroke = stroke
+	}
+
+	if len(editor.Query) == 0 {
+		return editor
+	}
+
+	// the public page searches the providers and signs previews, limited like the other routes as it can't be wrapped by the middleware
+	if ok, retryAfter := s.rateLimiter.Allow(r.Context(), ratelimit.IP, s.rateLimiter.ClientIP(r)); !ok {
+		editor.Error = fmt.Sprintf("Easy there, meme lord! Try again in %d seconds.", ratelimit.RetryAfterSeconds(retryAfter))
+		return editor
+	}
+
+	searchChanged := urlQuery.Get(searchParam) != editor.Kind+":"+editor.Query
+	if searchChanged {
+		editor.Next = ""
+	}
+
+	if len(editor.ID) == 0 || searchChanged || urlQuery.Get("action") == nextAction {
+		if err := s.searchEditor(r.Context(), &editor); err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				slog.LogAttrs(r.Context(), slog.LevelError, "search for editor", slog.String("query", editor.Query), slog.Any("error", err))
+			}
+
+			editor.Error = "Nothing found for this search, try another one."
+			if errors.Is(err, ErrUnavailable) {
+				editor.Error = "No provider is available right now, try again in a few minutes."
+			}
+
+			return editor
+		}
+	}
+
+	if provider, query, err := s.providers.resolve(parseKind(editor.Kind), editor.Query); err == nil {
+		editor.Provider = provider.Name()
+
+		if len(editor.Caption) == 0 {
+			editor.Caption = query
+		}
+	}
+
+	editor.Preview = s.signedPreview(editor)
+
+	return editor
+}
+
+func (s Service) signedPreview(editor Editor) string {
+	values := editor.previewValues()
+	s.signPreview(values)
+
+	return previewPath + "?" + values.Encode()
+}
+
+func (s Service) searchEditor(ctx context.Context, editor *Editor) error {
+	result, err := s.search(ctx, parseKind(editor.Kind), editor.Query, editor.Next)
+	if err != nil {
+		return err
+	}
+
+	editor.Query = result.search
+	editor.ID = result.media.ID
+	editor.Next = result.next
+
+	return nil
+}
+
+func (e Editor) previewValues() url.Values {
+	values := url.Values{}
+	values.Set("kind", e.Kind)
+	values.Set("provider", e.Provider)
+	values.Set(idParam, e.ID)
+	values.Set(captionParam, e.Caption)
+	values.Set("layout", e.Layout)
+	values.Set("font", e.Font)
+	values.Set("stroke", strconv.FormatFloat(e.Stroke, 'g', -1, 64))
+
+	return values
+}
+
+// PreviewLinkHandler signs the preview of the caption edited in the web editor, minting its share link when asked
+func (s Service) PreviewLinkHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxRenderRequestSize)
+		if err := r.ParseForm(); err != nil {
+			httperror.BadRequest(ctx, w, fmt.Errorf("parse form: %w", err))
+			return
+		}
+
+		editor := Editor{
+			Kind:     r.PostForm.Get("kind"),
+			Provider: r.PostForm.Get("provider"),
+			ID:       r.PostForm.Get(idParam),
+			Query:    r.PostForm.Get("query"),
+			Caption:  strings.TrimSpace(r.PostForm.Get(captionParam)),
+			Layout:   r.PostForm.Get("layout"),
+			Font:     r.PostForm.Get("font"),
+		}
+
+		kind := parseKind(editor.Kind)
+		if kind == unkownKind || len(editor.ID) == 0 {
+			httperror.BadRequest(ctx, w, errors.New("kind and id are required"))
+			return
+		}
+
+		if _, err := s.providers.provider(kind, editor.Provider); err != nil {
+			handleRenderError(ctx, w, err)
+			return
+		}
+
+		if err := s.limits.checkCaption(editor.Caption); err != nil {
+			handleRenderError(ctx, w, err)
+			return
+		}
+
+		if stroke, err := strconv.ParseFloat(r.PostForm.Get("stroke"), 64); err == nil {
+			editor.Stroke = stroke
+		}
+
+		output := PreviewLink{Preview: s.signedPreview(editor)}
+
+		if r.PostForm.Get("share") == "true" {
+			output.Share = s.getMemeURL(ctx, kind, editor.ID, editor.Query, editor.Caption)
+		}
+
+		httpjson.Write(ctx, w, http.StatusOK, output)
+	})
+}
+
+// PreviewHandler renders a meme from query params, used for live preview in the web editor, its source and caption signed by the editor
+func (s Service) PreviewHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isReadMethod(r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		if err := s.verifyPreview(query); err != nil {
+			slog.LogAttrs(ctx, slog.LevelWarn, "reject preview request", slog.Any("error", err))
+			httperror.Forbidden(ctx, w)
+			return
+		}
+
+		request, err := parseRenderOptions(query)
+		if err != nil {
+			handleRenderError(ctx, w, err)
+			return
+		}
+
+		if len(request.Captions) == 1 && request.Layout == splitLayout {
+			request.Captions = strings.SplitN(request.Captions[0], "\n", 2)
+		}
+
+		kind := parseKind(query.Get("kind"))
+		if kind == unkownKind {
+			httperror.BadRequest(ctx, w, errors.New("unknown kind"))
+			return
+		}
+
+		provider, err := s.providers.provider(kind, query.Get("provider"))
+		if err != nil {
+			handleRenderError(ctx, w, err)
+			return
+		}
+
+		request.Source.ID = query.Get(idParam)
+		request.Source.Provider = provider.Name()
+
+		spec, attribution, err := s.resolveRenderRequest(ctx, request, false)
+		if err != nil {
+			handleRenderError(ctx, w, err)
+			return
+		}
+
+		s.serveRender(w, r, spec, attribution)
+	})
+}
+
+func listFonts() []string {
+	entries, err := fs.ReadDir(content, "fonts")
+	if err != nil {
+		slog.LogAttrs(context.Background(), slog.LevelError, "list fonts", slog.Any("error", err))
+		return nil
+	}
+
+	fonts := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		fonts = append(fonts, strings.TrimSuffix(entry.Name(), ".ttf"))
+	}
+
+	return fonts
+}
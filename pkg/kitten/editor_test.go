@@ -0,0 +1,120 @@
+package kitten
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestPreviewLinkHandler(t *testing.T) {
+	t.Parallel()
+
+	instance := Service{
+		providers:       NewRegistry([]string{"first"}, nil).WithImage(&stubProvider{name: "first"}),
+		signatureSecret: "secret",
+		website:         "https://kitten.example",
+		limits:          limits{caption: 10},
+	}
+
+	form := func(mutate func(url.Values)) string {
+		values := url.Values{
+			"kind":       {"image"},
+			"provider":   {"first"},
+			idParam:      {"abc"},
+			"query":      {"cat"},
+			captionParam: {"hello"},
+			"layout":     {topLayout},
+		}
+
+		if mutate != nil {
+			mutate(values)
+		}
+
+		return values.Encode()
+	}
+
+	cases := map[string]struct {
+		method     string
+		body       string
+		wantStatus int
+		wantShare  bool
+	}{
+		"preview": {
+			http.MethodPost,
+			form(nil),
+			http.StatusOK,
+			false,
+		},
+		"share": {
+			http.MethodPost,
+			form(func(values url.Values) { values.Set("share", "true") }),
+			http.StatusOK,
+			true,
+		},
+		"get": {
+			http.MethodGet,
+			"",
+			http.StatusMethodNotAllowed,
+			false,
+		},
+		"unknown kind": {
+			http.MethodPost,
+			form(func(values url.Values) { values.Set("kind", "video") }),
+			http.StatusBadRequest,
+			false,
+		},
+		"unknown provider": {
+			http.MethodPost,
+			form(func(values url.Values) { values.Set("provider", "other") }),
+			http.StatusBadRequest,
+			false,
+		},
+		"caption too long": {
+			http.MethodPost,
+			form(func(values url.Values) { values.Set(captionParam, "a caption way too long") }),
+			http.StatusBadRequest,
+			false,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(testCase.method, previewPath, strings.NewReader(testCase.body))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			writer := httptest.NewRecorder()
+			instance.PreviewLinkHandler().ServeHTTP(writer, request)
+
+			if writer.Code != testCase.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", writer.Code, testCase.wantStatus, writer.Body.String())
+			}
+
+			if writer.Code != http.StatusOK {
+				return
+			}
+
+			var link PreviewLink
+			if err := json.Unmarshal(writer.Body.Bytes(), &link); err != nil {
+				t.Fatalf("decode link: %s", err)
+			}
+
+			previewURL, err := url.Parse(link.Preview)
+			if err != nil {
+				t.Fatalf("parse preview: %s", err)
+			}
+
+			if err = instance.verifyPreview(previewURL.Query()); err != nil {
+				t.Errorf("preview `%s` is not signed: %s", link.Preview, err)
+			}
+
+			if got := len(link.Share) != 0; got != testCase.wantShare {
+				t.Errorf("share = `%s`, want one %t", link.Share, testCase.wantShare)
+			}
+		})
+	}
+}
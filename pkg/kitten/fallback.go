@@ -0,0 +1,222 @@
+package kitten
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ViBiOh/httputils/v4/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// recentPerSearch is the number of medias kept for each search
+const recentPerSearch = 5
+
+// found is the media a search ended on, with the provider serving it
+type found struct {
+	provider Provider
+	media    Media
+	query    string
+	// search is the one to give back for sharing or asking the next result, prefixed by the provider when it is a fallback
+	search   string
+	next     string
+	fallback bool
+}
+
+// search asks the providers of the search in order, skipping to the next one when a provider is unavailable, then to the recent results of the same query
+func (s Service) search(ctx context.Context, kind memeKind, search, cursor string) (found, error) {
+	providers, query, err := s.providers.chain(kind, search)
+	if err != nil {
+		return found{}, err
+	}
+
+	var failures []error
+
+	for index, provider := range providers {
+		name := provider.Name()
+
+		if !s.breaker.allow(name) {
+			failures = append(failures, fmt.Errorf("%s: circuit open", name))
+			continue
+		}
+
+		// the cursor comes from the first provider, meaningless for the next ones
+		position := cursor
+		if index > 0 {
+			position = ""
+		}
+
+		media, next, err := provider.Search(ctx, query, position)
+		if err == nil {
+			s.breaker.success(name)
+			s.recent.store(kind, query, name, media)
+
+			return s.served(ctx, kind, found{provider: provider, media: media, query: query, search: search, next: next}, index > 0), nil
+		}
+
+		if !unavailable(ctx, err) {
+			if !errors.Is(err, ErrNotFound) {
+				return found{}, err
+			}
+
+			s.breaker.success(name)
+
+			// a fallback having nothing doesn't mean the search has no result
+			if len(failures) == 0 {
+				return found{}, err
+			}
+
+			continue
+		}
+
+		if s.breaker.failure(name, errors.Is(err, ErrRateLimited)) {
+			slog.LogAttrs(ctx, slog.LevelWarn, "provider circuit open", slog.String("provider", name), slog.String("kind", string(kind)), slog.Any("error", err))
+		} else {
+			slog.LogAttrs(ctx, slog.LevelWarn, "provider unavailable", slog.String("provider", name), slog.String("kind", string(kind)), slog.Any("error", err))
+		}
+
+		failures = append(failures, fmt.Errorf("%s: %w", name, err))
+	}
+
+	if name, media, ok := s.recent.pick(kind, query, providers); ok {
+		if provider, err := s.providers.provider(kind, name); err == nil {
+			return s.served(ctx, kind, found{provider: provider, media: media, query: query}, true), nil
+		}
+	}
+
+	return found{}, fmt.Errorf("%w: %w", ErrUnavailable, errors.Join(failures...))
+}
+
+// served records the provider serving the search, pinning the search to it on fallback
+func (s Service) served(ctx context.Context, kind memeKind, result found, fallback bool) found {
+	result.fallback = fallback
+
+	if fallback {
+		result.search = result.provider.Name() + ":" + result.query
+
+		slog.LogAttrs(ctx, slog.LevelInfo, "search served by fallback", slog.String("provider", result.provider.Name()), slog.String("kind", string(kind)), slog.String("query", result.query))
+	}
+
+	if !model.IsNil(s.providerMetric) {
+		s.providerMetric.Add(context.WithoutCancel(ctx), 1, metric.WithAttributes(
+			attribute.String("provider", result.provider.Name()),
+			attribute.String("kind", string(kind)),
+			attribute.Bool("fallback", fallback),
+		))
+	}
+
+	return result
+}
+
+// unavailable tells if the error comes from the provider being down or rate limited, rather than from the search itself
+func unavailable(ctx context.Context, err error) bool {
+	return ctx.Err() == nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, errInvalidRender)
+}
+
+// recentResults keeps the last medias found for the most recent searches, served when every provider is unavailable
+type recentResults struct {
+	entries map[string]*list.Element
+	order   *list.List
+	size    int
+	mutex   sync.Mutex
+}
+
+type recentResult struct {
+	key    string
+	medias []recentMedia
+}
+
+type recentMedia struct {
+	provider string
+	media    Media
+}
+
+func newRecentResults(size int) *recentResults {
+	if size <= 0 {
+		return nil
+	}
+
+	return &recentResults{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		size:    size,
+	}
+}
+
+func (rr *recentResults) store(kind memeKind, query, provider string, media Media) {
+	if rr == nil {
+		return
+	}
+
+	key := recentKey(kind, query)
+	item := recentMedia{provider: provider, media: media}
+
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	if element, ok := rr.entries[key]; ok {
+		entry := element.Value.(*recentResult)
+
+		entry.medias = slices.DeleteFunc(entry.medias, func(existing recentMedia) bool {
+			return existing.provider == provider && existing.media.ID == media.ID
+		})
+
+		entry.medias = append(entry.medias, item)
+		if len(entry.medias) > recentPerSearch {
+			entry.medias = entry.medias[len(entry.medias)-recentPerSearch:]
+		}
+
+		rr.order.MoveToFront(element)
+
+		return
+	}
+
+	rr.entries[key] = rr.order.PushFront(&recentResult{key: key, medias: []recentMedia{item}})
+
+	for rr.order.Len() > rr.size {
+		oldest := rr.order.Back()
+		rr.order.Remove(oldest)
+		delete(rr.entries, oldest.Value.(*recentResult).key)
+	}
+}
+
+// pick returns one of the recent medias of the query served by one of the given providers
+func (rr *recentResults) pick(kind memeKind, query string, providers []Provider) (string, Media, bool) {
+	if rr == nil {
+		return "", Media{}, false
+	}
+
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	element, ok := rr.entries[recentKey(kind, query)]
+	if !ok {
+		return "", Media{}, false
+	}
+
+	candidates := slices.DeleteFunc(slices.Clone(element.Value.(*recentResult).medias), func(item recentMedia) bool {
+		return !slices.ContainsFunc(providers, func(provider Provider) bool {
+			return provider.Name() == item.provider
+		})
+	})
+
+	if len(candidates) == 0 {
+		return "", Media{}, false
+	}
+
+	rr.order.MoveToFront(element)
+	item := candidates[rand.IntN(len(candidates))]
+
+	return item.provider, item.media, true
+}
+
+func recentKey(kind memeKind, query string) string {
+	return string(kind) + ":" + strings.ToLower(strings.TrimSpace(query))
+}
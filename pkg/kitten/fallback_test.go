@@ -0,0 +1,330 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errProviderDown = errors.New("provider down")
+
+// stubProvider answers every search with the same media or error, recording the cursors it is asked
+type stubProvider struct {
+	err     error
+	name    string
+	cursors []string
+	mutex   sync.Mutex
+}
+
+func (sp *stubProvider) Name() string {
+	return sp.name
+}
+
+func (sp *stubProvider) Search(_ context.Context, query, cursor string) (Media, string, error) {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	sp.cursors = append(sp.cursors, cursor)
+
+	if sp.err != nil {
+		return Media{}, "", sp.err
+	}
+
+	return Media{ID: sp.name + "-" + query}, "next", nil
+}
+
+func (sp *stubProvider) Get(context.Context, string) (Media, error) {
+	return Media{}, ErrNotFound
+}
+
+func (sp *stubProvider) Report(context.Context, Media, string) {}
+
+func (sp *stubProvider) calls() []string {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+
+	return sp.cursors
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	type recent struct {
+		provider string
+		query    string
+	}
+
+	cases := map[string]struct {
+		errs       []error
+		open       []string
+		recents    []recent
+		search     string
+		want       string
+		wantSearch string
+		wantErr    error
+		wantCalls  []int
+	}{
+		"first provider": {
+			[]error{nil, nil},
+			nil,
+			nil,
+			"cat",
+			"first",
+			"cat",
+			nil,
+			[]int{1, 0},
+		},
+		"rate limited": {
+			[]error{ErrRateLimited, nil},
+			nil,
+			nil,
+			"cat",
+			"second",
+			"second:cat",
+			nil,
+			[]int{1, 1},
+		},
+		"down": {
+			[]error{errProviderDown, nil},
+			nil,
+			nil,
+			"cat",
+			"second",
+			"second:cat",
+			nil,
+			[]int{1, 1},
+		},
+		"circuit open": {
+			[]error{nil, nil},
+			[]string{"first"},
+			nil,
+			"cat",
+			"second",
+			"second:cat",
+			nil,
+			[]int{0, 1},
+		},
+		"not found": {
+			[]error{ErrNotFound, nil},
+			nil,
+			nil,
+			"cat",
+			"",
+			"",
+			ErrNotFound,
+			[]int{1, 0},
+		},
+		"invalid search": {
+			[]error{errInvalidRender, nil},
+			nil,
+			nil,
+			"cat",
+			"",
+			"",
+			errInvalidRender,
+			[]int{1, 0},
+		},
+		"not found in fallback": {
+			[]error{ErrRateLimited, ErrNotFound, nil},
+			nil,
+			nil,
+			"cat",
+			"third",
+			"third:cat",
+			nil,
+			[]int{1, 1, 1},
+		},
+		"targeted provider": {
+			[]error{nil, nil},
+			nil,
+			nil,
+			"second:cat",
+			"second",
+			"second:cat",
+			nil,
+			[]int{0, 1},
+		},
+		"targeted provider unavailable": {
+			[]error{nil, ErrRateLimited},
+			nil,
+			nil,
+			"second:cat",
+			"",
+			"",
+			ErrUnavailable,
+			[]int{0, 1},
+		},
+		"recent result": {
+			[]error{ErrRateLimited, errProviderDown},
+			nil,
+			[]recent{{"second", "cat"}},
+			"cat",
+			"second",
+			"second:cat",
+			nil,
+			[]int{1, 1},
+		},
+		"recent result of another query": {
+			[]error{ErrRateLimited, errProviderDown},
+			nil,
+			[]recent{{"second", "dog"}},
+			"cat",
+			"",
+			"",
+			ErrUnavailable,
+			[]int{1, 1},
+		},
+		"recent result of another provider": {
+			[]error{ErrRateLimited, errProviderDown},
+			nil,
+			[]recent{{"unknown", "cat"}},
+			"cat",
+			"",
+			"",
+			ErrUnavailable,
+			[]int{1, 1},
+		},
+		"recent result of a not found": {
+			[]error{ErrNotFound, nil},
+			nil,
+			[]recent{{"first", "cat"}},
+			"cat",
+			"",
+			"",
+			ErrNotFound,
+			[]int{1, 0},
+		},
+	}
+
+	names := []string{"first", "second", "third"}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			providers := make([]*stubProvider, len(testCase.errs))
+			registry := NewRegistry(nil, names[:len(testCase.errs)])
+
+			for index, err := range testCase.errs {
+				providers[index] = &stubProvider{name: names[index], err: err}
+				registry = registry.WithGif(providers[index])
+			}
+
+			instance := Service{
+				providers: registry,
+				breaker:   newBreaker(3, time.Hour),
+				recent:    newRecentResults(10),
+			}
+
+			for _, name := range testCase.open {
+				instance.breaker.failure(name, true)
+			}
+
+			for _, item := range testCase.recents {
+				instance.recent.store(gifKind, item.query, item.provider, Media{ID: item.provider + "-" + item.query})
+			}
+
+			got, err := instance.search(context.Background(), gifKind, testCase.search, "cursor")
+
+			if !errors.Is(err, testCase.wantErr) {
+				t.Errorf("search() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if err == nil {
+				if name := got.provider.Name(); name != testCase.want {
+					t.Errorf("search() = `%s`, want `%s`", name, testCase.want)
+				}
+
+				if got.search != testCase.wantSearch {
+					t.Errorf("search() search = `%s`, want `%s`", got.search, testCase.wantSearch)
+				}
+
+				if wantFallback := testCase.search != testCase.wantSearch; got.fallback != wantFallback {
+					t.Errorf("search() fallback = %t, want %t", got.fallback, wantFallback)
+				}
+			}
+
+			for index, provider := range providers {
+				calls := provider.calls()
+
+				if len(calls) != testCase.wantCalls[index] {
+					t.Errorf("`%s` called %d times, want %d", provider.name, len(calls), testCase.wantCalls[index])
+				}
+
+				// the cursor belongs to the first provider of the chain, the targeted one being alone
+				wantCursor := ""
+				if index == 0 || testCase.search != "cat" {
+					wantCursor = "cursor"
+				}
+
+				for _, cursor := range calls {
+					if cursor != wantCursor {
+						t.Errorf("`%s` asked with cursor `%s`, want `%s`", provider.name, cursor, wantCursor)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRecentResults(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		size   int
+		stored []string
+		query  string
+		want   bool
+	}{
+		"disabled": {
+			0,
+			[]string{"cat"},
+			"cat",
+			false,
+		},
+		"stored": {
+			2,
+			[]string{"cat"},
+			"cat",
+			true,
+		},
+		"normalized": {
+			2,
+			[]string{"Cat "},
+			"cat",
+			true,
+		},
+		"missing": {
+			2,
+			[]string{"cat"},
+			"dog",
+			false,
+		},
+		"evicted": {
+			2,
+			[]string{"cat", "dog", "bird"},
+			"cat",
+			false,
+		},
+	}
+
+	provider := &stubProvider{name: "first"}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			instance := newRecentResults(testCase.size)
+
+			for _, query := range testCase.stored {
+				instance.store(gifKind, query, provider.name, Media{ID: query})
+			}
+
+			if _, _, got := instance.pick(gifKind, testCase.query, []Provider{provider}); got != testCase.want {
+				t.Errorf("pick() = %t, want %t", got, testCase.want)
+			}
+		})
+	}
+}
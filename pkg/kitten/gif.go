@@ -4,25 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"image/draw"
 	"image/gif"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
-	"path/filepath"
 
-	"github.com/ViBiOh/httputils/v4/pkg/concurrent"
-	"github.com/ViBiOh/httputils/v4/pkg/hash"
 	"github.com/ViBiOh/httputils/v4/pkg/httperror"
-	"github.com/ViBiOh/httputils/v4/pkg/request"
 	"github.com/ViBiOh/httputils/v4/pkg/telemetry"
-	"github.com/ViBiOh/kitten/pkg/klipy"
-	"github.com/fogleman/gg"
 )
 
 func (s Service) GifHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
+		if !isReadMethod(r.Method) {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
@@ -33,132 +27,273 @@ func (s Service) GifHandler() http.Handler {
 			return
 		}
 
+		if err = s.verifySignature(query); err != nil {
+			slog.LogAttrs(r.Context(), slog.LevelWarn, "reject gif request", slog.Any("error", err))
+			httperror.Forbidden(r.Context(), w)
+			return
+		}
+
 		id, search, caption, err := parseRequest(query)
 		if err != nil {
 			httperror.BadRequest(r.Context(), w, err)
 			return
 		}
 
-		if s.serveCached(r.Context(), w, id, caption, true) {
+		if len(r.PathValue("content")) != 0 && s.serveSharePage(w, r) {
 			return
 		}
 
-		image, err := s.GetGif(r.Context(), id, search, caption)
-		if err != nil {
-			if errors.Is(err, klipy.ErrNotFound) {
-				httperror.NotFound(r.Context(), w, err)
-			} else {
-				httperror.InternalServerError(r.Context(), w, err)
-			}
+		s.serveGif(w, r, id, search, caption)
+	})
+}
 
-			return
-		}
+func (s Service) serveGif(w http.ResponseWriter, r *http.Request, id, search, caption string) {
+	provider, query, err := s.providers.resolve(gifKind, search)
+	if err != nil {
+		handleRenderError(r.Context(), w, err)
+		return
+	}
 
-		w.Header().Add("Cache-Control", cacheControlDuration)
-		w.Header().Set("Content-Type", "image/gif")
-		w.WriteHeader(http.StatusOK)
+	spec := newRenderSpec(newProviderSource(gifKind, provider.Name(), id), caption)
 
-		if err = gif.EncodeAll(w, image); err != nil {
-			httperror.InternalServerError(r.Context(), w, err)
+	if s.serveCached(w, r, spec) {
+		return
+	}
+
+	ctx := r.Context()
+
+	media, err := provider.Get(ctx, id)
+	if err != nil {
+		handleRenderError(ctx, w, fmt.Errorf("get from %s: %w", provider.Name(), err))
+		return
+	}
+
+	go provider.Report(context.WithoutCancel(ctx), media, query)
+
+	spec = newRenderSpec(newMediaSource(gifKind, provider, media), caption)
+
+	if r.Method == http.MethodHead {
+		if _, _, err = s.generateAndStoreGif(ctx, spec); err != nil {
+			handleRenderError(ctx, w, err)
 			return
 		}
 
-		s.increaseServed(r.Context())
+		s.serveCached(w, r, spec)
+		return
+	}
 
-		go s.storeGifInCache(r.Context(), id, caption, image)
-	})
+	s.streamGif(w, r, spec)
 }
 
-func (s Service) getGifCacheFilename(id, caption string) string {
-	return filepath.Join(s.tmpFolder, hash.String(fmt.Sprintf("%s:%s", id, caption))+".gif")
-}
+// streamGif sends every frame as soon as it is captioned, while writing the same bytes to the cache file
+func (s Service) streamGif(w http.ResponseWriter, r *http.Request, spec renderSpec) {
+	ctx := r.Context()
+	response := &streamWriter{ResponseWriter: w, controller: http.NewResponseController(w), filename: s.getCacheFilename(spec)}
 
-func (s Service) storeGifInCache(ctx context.Context, id, caption string, image *gif.GIF) {
-	if file, err := os.OpenFile(s.getGifCacheFilename(id, caption), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600); err != nil {
-		slog.LogAttrs(ctx, slog.LevelError, "open gif to local cache", slog.Any("error", err))
-	} else if err := gif.EncodeAll(file, image); err != nil {
-		slog.LogAttrs(ctx, slog.LevelError, "write gif to local cache", slog.Any("error", err))
+	if err := s.pipeGif(ctx, spec, response, response.flush); err != nil {
+		if !response.started {
+			handleRenderError(ctx, w, err)
+		} else {
+			slog.LogAttrs(ctx, slog.LevelError, "stream gif", slog.Any("error", err))
+		}
+
+		return
 	}
+
+	s.increaseServed(ctx)
 }
 
-func (s Service) generateGif(ctx context.Context, from, caption string) (*gif.GIF, error) {
-	image, err := getGif(ctx, from)
-	if err != nil {
-		return nil, fmt.Errorf("get gif: %w", err)
+// pipeGif encodes the gif in a render slot while its frames are written outside of it, a slow writer holding the slot only once the pipe is full
+func (s Service) pipeGif(ctx context.Context, spec renderSpec, writer io.Writer, flush func() error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	frames := newFramePipe(framePipeSize)
+	defer frames.close(errStreamClosed)
+
+	go func() {
+		frames.close(s.encodeAndStoreGif(ctx, spec, frames, noFlush))
+	}()
+
+	for {
+		content, err := frames.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if _, err = writer.Write(content); err == nil {
+			err = flush()
+		}
+
+		if err != nil {
+			return fmt.Errorf("send gif: %w", err)
+		}
 	}
+}
 
-	image, err = s.CaptionGif(ctx, image, caption)
-	if err != nil {
-		return nil, fmt.Errorf("caption gif: %w", err)
+func (s Service) encodeAndStoreGif(ctx context.Context, spec renderSpec, writer io.Writer, flush func() error) error {
+	filename := s.getCacheFilename(spec)
+
+	if err := writeCacheFile(filename, func(file io.Writer) error {
+		return s.encodeGif(ctx, spec, io.MultiWriter(file, writer), flush)
+	}); err != nil {
+		return err
 	}
 
-	return image, nil
-}
+	storeMetadata(ctx, filename, spec)
 
-func (s Service) generateAndStoreGif(ctx context.Context, id, from, caption string) (string, int64, error) {
-	imagePath := s.getGifCacheFilename(id, caption)
+	return nil
+}
 
-	info, err := os.Stat(imagePath)
-	if err != nil && !os.IsNotExist(err) {
-		return "", 0, err
+func (s Service) encodeGif(ctx context.Context, spec renderSpec, writer io.Writer, flush func() error) error {
+	payload, err := s.fetchSource(ctx, spec.source)
+	if err != nil {
+		return fmt.Errorf("get gif: %w", err)
 	}
 
-	if info == nil {
-		image, err := s.generateGif(ctx, from, caption)
+	return s.renders.run(ctx, func(ctx context.Context) (err error) {
+		ctx, end := telemetry.StartSpan(ctx, s.tracer, "encodeGif")
+		defer end(&err)
+
+		decoder, err := s.limits.openGif(payload)
 		if err != nil {
-			return "", 0, fmt.Errorf("generate image: %w", err)
+			return fmt.Errorf("open gif: %w", err)
 		}
 
-		s.storeGifInCache(ctx, id, caption, image)
-
-		info, err = os.Stat(imagePath)
+		textImage, err := s.captionLayer(ctx, decoder.config, spec.caption, spec.style, spec.layout)
 		if err != nil {
-			return "", 0, fmt.Errorf("get image info: %w", err)
+			return fmt.Errorf("caption gif: %w", err)
 		}
-	}
 
-	return imagePath, info.Size(), nil
+		var encoder *gifEncoder
+
+		for {
+			if err = ctx.Err(); err != nil {
+				return err
+			}
+
+			var frame gifFrame
+
+			frame, err = decoder.next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			if err != nil {
+				return fmt.Errorf("decode frame: %w", err)
+			}
+
+			// The encoder is created with the first frame, once the loop count preceding it is read
+			if encoder == nil {
+				encoder = newGifEncoder(writer, flush, decoder.header())
+			}
+
+			drawLayer(frame.image, textImage)
+
+			if err = encoder.writeFrame(frame.image, frame.delay, frame.disposal); err != nil {
+				return fmt.Errorf("write frame: %w", err)
+			}
+		}
+
+		if encoder == nil {
+			return errors.New("gif has no frame")
+		}
+
+		return encoder.close()
+	})
 }
 
-func getGif(ctx context.Context, imageURL string) (*gif.GIF, error) {
-	resp, err := request.Get(imageURL).Send(ctx, nil)
-	if err != nil {
-		return nil, fmt.Errorf("fetch URL `%s`: %w", imageURL, err)
+// streamWriter writes the response headers on first write, so errors occurring before can still be answered
+type streamWriter struct {
+	http.ResponseWriter
+	controller *http.ResponseController
+	filename   string
+	started    bool
+}
+
+func (sw *streamWriter) Write(content []byte) (int, error) {
+	if !sw.started {
+		sw.started = true
+
+		setContentHeaders(sw.Header(), sw.filename)
+		sw.WriteHeader(http.StatusOK)
 	}
 
-	output, err := gif.DecodeAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("decode gif: %w", err)
+	return sw.ResponseWriter.Write(content)
+}
+
+func noFlush() error {
+	return nil
+}
+
+func (sw *streamWriter) flush() error {
+	if err := sw.controller.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
 	}
 
-	return output, nil
+	return nil
 }
 
-func (s Service) CaptionGif(ctx context.Context, source *gif.GIF, text string) (*gif.GIF, error) {
-	var err error
+func (s Service) storeGifInCache(ctx context.Context, spec renderSpec, image *gif.GIF) {
+	filename := s.getCacheFilename(spec)
 
-	_, end := telemetry.StartSpan(ctx, s.tracer, "captionGif")
-	defer end(&err)
+	if err := writeCacheFile(filename, func(writer io.Writer) error {
+		return gif.EncodeAll(writer, image)
+	}); err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "write gif to local cache", slog.Any("error", err))
+		return
+	}
 
-	wg := concurrent.NewFailFast(8)
+	storeMetadata(ctx, filename, spec)
+}
 
-	textImage, err := s.caption(gg.NewContext(source.Config.Width, source.Config.Height), text)
+func (s Service) generateGif(ctx context.Context, spec renderSpec) (*gif.GIF, error) {
+	payload, err := s.fetchSource(ctx, spec.source)
 	if err != nil {
-		return source, fmt.Errorf("generate text layer: %w", err)
+		return nil, fmt.Errorf("get gif: %w", err)
 	}
-	textImageBounds := textImage.Bounds()
 
-	for _, frame := range source.Image {
-		maskedFrame := frame
-		wg.Go(func() error {
-			draw.DrawMask(maskedFrame, textImageBounds, textImage, textImageBounds.Min, textImage, textImageBounds.Min, draw.Over)
-			return err
-		})
+	var image *gif.GIF
+
+	err = s.renders.run(ctx, func(ctx context.Context) (err error) {
+		image, err = s.limits.decodeGif(payload)
+		if err != nil {
+			return fmt.Errorf("decode gif: %w", err)
+		}
+
+		image, err = s.captionGif(ctx, image, spec.caption, spec.style, spec.layout)
+		if err != nil {
+			return fmt.Errorf("caption gif: %w", err)
+		}
+
+		return nil
+	})
+
+	return image, err
+}
+
+func (s Service) generateAndStoreGif(ctx context.Context, spec renderSpec) (string, int64, error) {
+	imagePath := s.getCacheFilename(spec)
+
+	info, err := os.Stat(imagePath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", 0, err
 	}
 
-	if err = wg.Wait(); err != nil {
-		return source, err
+	if info == nil {
+		if err = s.encodeAndStoreGif(ctx, spec, io.Discard, noFlush); err != nil {
+			return "", 0, fmt.Errorf("generate image: %w", err)
+		}
+
+		info, err = os.Stat(imagePath)
+		if err != nil {
+			return "", 0, fmt.Errorf("get image info: %w", err)
+		}
 	}
 
-	return source, nil
+	return imagePath, info.Size(), nil
 }
@@ -0,0 +1,725 @@
+package kitten
+
+import (
+	"bufio"
+	"bytes"
+	"compress/lzw"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+	"slices"
+	"sync"
+)
+
+const (
+	gifColorTableFlag    = 0x80
+	gifInterlaceFlag     = 0x40
+	gifTransparentFlag   = 0x01
+	gifGraphicControl    = 0xF9
+	gifApplication       = 0xFF
+	gifMaxSubBlock       = 255
+	gifMaxColorTableSize = 256
+	gifLoopExtension     = "NETSCAPE2.0"
+
+	// framePipeSize is the size of the encoded frames buffered for a slow client before blocking the render
+	framePipeSize = 8 << 20
+)
+
+var errStreamClosed = errors.New("gif stream is closed")
+
+// gifHeader is what the encoder needs to know about the whole gif before writing its first frame
+type gifHeader struct {
+	config     image.Config
+	loopCount  int
+	background byte
+	animated   bool
+}
+
+// gifEncoder writes a gif frame by frame, flushing each one to the underlying writer as soon as it is encoded
+type gifEncoder struct {
+	writer      *bufio.Writer
+	flush       func() error
+	globalTable []byte
+	config      image.Config
+	loopCount   int
+	background  byte
+	animated    bool
+	started     bool
+}
+
+func newGifEncoder(writer io.Writer, flush func() error, header gifHeader) *gifEncoder {
+	return &gifEncoder{
+		writer:     bufio.NewWriter(writer),
+		flush:      flush,
+		config:     header.config,
+		loopCount:  header.loopCount,
+		background: header.background,
+		animated:   header.animated,
+	}
+}
+
+func (ge *gifEncoder) writeHeader() error {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("GIF89a")
+	buffer.Write(binary.LittleEndian.AppendUint16(nil, uint16(ge.config.Width)))
+	buffer.Write(binary.LittleEndian.AppendUint16(nil, uint16(ge.config.Height)))
+
+	if palette, ok := ge.config.ColorModel.(color.Palette); ok && len(palette) != 0 {
+		size := paletteBits(len(palette))
+
+		table, err := encodeColorTable(palette, size)
+		if err != nil {
+			return fmt.Errorf("encode global color table: %w", err)
+		}
+
+		ge.globalTable = table
+
+		buffer.Write([]byte{gifColorTableFlag | byte(size), ge.background, 0x00})
+		buffer.Write(table)
+	} else {
+		buffer.Write([]byte{0x00, 0x00, 0x00})
+	}
+
+	if ge.animated && ge.loopCount >= 0 {
+		buffer.Write([]byte{gifExtension, gifApplication, 0x0B})
+		buffer.WriteString(gifLoopExtension)
+		buffer.Write([]byte{0x03, 0x01})
+		buffer.Write(binary.LittleEndian.AppendUint16(nil, uint16(ge.loopCount)))
+		buffer.WriteByte(0x00)
+	}
+
+	_, err := ge.writer.Write(buffer.Bytes())
+
+	return err
+}
+
+func (ge *gifEncoder) writeFrame(frame *image.Paletted, delay int, disposal byte) error {
+	if !ge.started {
+		ge.started = true
+
+		if err := ge.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	bounds := frame.Bounds()
+	if !bounds.In(image.Rect(0, 0, ge.config.Width, ge.config.Height)) {
+		return errors.New("frame is out of bounds")
+	}
+
+	if len(frame.Palette) == 0 || len(frame.Palette) > gifMaxColorTableSize {
+		return fmt.Errorf("frame has %d colors", len(frame.Palette))
+	}
+
+	transparent := -1
+	for index, entry := range frame.Palette {
+		if entry == nil {
+			return errors.New("frame has a nil color")
+		}
+
+		if _, _, _, alpha := entry.RGBA(); alpha == 0 {
+			transparent = index
+			break
+		}
+	}
+
+	if delay > 0 || disposal != 0 || transparent != -1 {
+		control := []byte{gifExtension, gifGraphicControl, 0x04, disposal << 2, 0, 0, 0, 0x00}
+		binary.LittleEndian.PutUint16(control[4:6], uint16(delay))
+
+		if transparent != -1 {
+			control[3] |= gifTransparentFlag
+			control[6] = byte(transparent)
+		}
+
+		if _, err := ge.writer.Write(control); err != nil {
+			return err
+		}
+	}
+
+	descriptor := []byte{gifDescriptor, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(descriptor[1:3], uint16(bounds.Min.X))
+	binary.LittleEndian.PutUint16(descriptor[3:5], uint16(bounds.Min.Y))
+	binary.LittleEndian.PutUint16(descriptor[5:7], uint16(bounds.Dx()))
+	binary.LittleEndian.PutUint16(descriptor[7:9], uint16(bounds.Dy()))
+
+	if _, err := ge.writer.Write(descriptor); err != nil {
+		return err
+	}
+
+	size := paletteBits(len(frame.Palette))
+
+	table, err := encodeColorTable(frame.Palette, size)
+	if err != nil {
+		return fmt.Errorf("encode local color table: %w", err)
+	}
+
+	if ge.matchGlobalTable(table, transparent) {
+		err = ge.writer.WriteByte(0x00)
+	} else {
+		err = ge.writer.WriteByte(gifColorTableFlag | byte(size))
+		if err == nil {
+			_, err = ge.writer.Write(table)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = ge.writePixels(frame, max(size+1, 2)); err != nil {
+		return fmt.Errorf("write pixels: %w", err)
+	}
+
+	if err = ge.writer.Flush(); err != nil {
+		return err
+	}
+
+	return ge.flush()
+}
+
+// matchGlobalTable reports if the frame can reuse the global color table, ignoring the transparent entry set by the decoder
+func (ge *gifEncoder) matchGlobalTable(table []byte, transparent int) bool {
+	if len(table) > len(ge.globalTable) {
+		return false
+	}
+
+	if transparent < 0 {
+		return bytes.Equal(table, ge.globalTable[:len(table)])
+	}
+
+	offset := 3 * transparent
+
+	return bytes.Equal(table[:offset], ge.globalTable[:offset]) && bytes.Equal(table[offset+3:], ge.globalTable[offset+3:len(table)])
+}
+
+func (ge *gifEncoder) writePixels(frame *image.Paletted, litWidth int) error {
+	if err := ge.writer.WriteByte(byte(litWidth)); err != nil {
+		return err
+	}
+
+	blocks := &subBlockWriter{writer: ge.writer}
+	compressor := lzw.NewWriter(blocks, lzw.LSB, litWidth)
+
+	bounds := frame.Bounds()
+	width := bounds.Dx()
+
+	for offset, y := 0, bounds.Min.Y; y < bounds.Max.Y; offset, y = offset+frame.Stride, y+1 {
+		if _, err := compressor.Write(frame.Pix[offset : offset+width]); err != nil {
+			_ = compressor.Close()
+			return err
+		}
+	}
+
+	if err := compressor.Close(); err != nil {
+		return err
+	}
+
+	return blocks.close()
+}
+
+func (ge *gifEncoder) close() error {
+	if !ge.started {
+		return errors.New("gif has no frame")
+	}
+
+	if err := ge.writer.WriteByte(gifTrailer); err != nil {
+		return err
+	}
+
+	if err := ge.writer.Flush(); err != nil {
+		return err
+	}
+
+	return ge.flush()
+}
+
+// subBlockWriter splits the compressed pixels into the length-prefixed sub-blocks of the gif format
+type subBlockWriter struct {
+	writer *bufio.Writer
+	block  [gifMaxSubBlock + 1]byte
+	length int
+}
+
+func (sbw *subBlockWriter) Write(content []byte) (int, error) {
+	for index, value := range content {
+		sbw.length++
+		sbw.block[sbw.length] = value
+
+		if sbw.length == gifMaxSubBlock {
+			if err := sbw.flush(); err != nil {
+				return index, err
+			}
+		}
+	}
+
+	return len(content), nil
+}
+
+func (sbw *subBlockWriter) flush() error {
+	sbw.block[0] = byte(sbw.length)
+	_, err := sbw.writer.Write(sbw.block[:sbw.length+1])
+	sbw.length = 0
+
+	return err
+}
+
+func (sbw *subBlockWriter) close() error {
+	if sbw.length != 0 {
+		if err := sbw.flush(); err != nil {
+			return err
+		}
+	}
+
+	return sbw.writer.WriteByte(0x00)
+}
+
+// paletteBits returns the n for which the table holds 2^(n+1) entries
+func paletteBits(colors int) int {
+	if colors <= 2 {
+		return 0
+	}
+
+	return bits.Len(uint(colors-1)) - 1
+}
+
+func encodeColorTable(palette color.Palette, size int) ([]byte, error) {
+	table := make([]byte, 3<<(size+1))
+
+	for index, entry := range palette {
+		if entry == nil {
+			return nil, errors.New("nil color")
+		}
+
+		red, green, blue, _ := entry.RGBA()
+		table[3*index] = byte(red >> 8)
+		table[3*index+1] = byte(green >> 8)
+		table[3*index+2] = byte(blue >> 8)
+	}
+
+	return table, nil
+}
+
+// gifFrame is a decoded frame, with the delay and disposal of its graphic control extension
+type gifFrame struct {
+	image    *image.Paletted
+	delay    int
+	disposal byte
+}
+
+// gifDecoder decodes a gif frame by frame, keeping in memory only its payload and the frame being decoded
+type gifDecoder struct {
+	reader      *bytes.Reader
+	globalTable color.Palette
+	config      image.Config
+	frames      int64
+	loopCount   int
+	delay       int
+	transparent int
+	background  byte
+	disposal    byte
+}
+
+func newGifDecoder(payload []byte, frames int64) (*gifDecoder, error) {
+	if len(payload) < gifHeaderSize {
+		return nil, errGifTruncated
+	}
+
+	if version := string(payload[:6]); version != "GIF87a" && version != "GIF89a" {
+		return nil, fmt.Errorf("unknown version %q", version)
+	}
+
+	decoder := &gifDecoder{
+		reader: bytes.NewReader(payload[gifHeaderSize:]),
+		config: image.Config{
+			Width:  int(binary.LittleEndian.Uint16(payload[6:8])),
+			Height: int(binary.LittleEndian.Uint16(payload[8:10])),
+		},
+		frames:      frames,
+		loopCount:   -1,
+		transparent: -1,
+		background:  payload[11],
+	}
+
+	if payload[10]&gifColorTableFlag != 0 {
+		palette, err := decoder.readColorTable(payload[10])
+		if err != nil {
+			return nil, fmt.Errorf("read global color table: %w", err)
+		}
+
+		decoder.globalTable = palette
+		decoder.config.ColorModel = palette
+	}
+
+	return decoder, nil
+}
+
+// header answers what is known of the gif, its loop count being read with the extensions preceding the first frame
+func (gd *gifDecoder) header() gifHeader {
+	return gifHeader{
+		config:     gd.config,
+		loopCount:  gd.loopCount,
+		background: gd.background,
+		animated:   gd.frames > 1,
+	}
+}
+
+// next decodes the next frame, answering io.EOF once the trailer is reached
+func (gd *gifDecoder) next() (gifFrame, error) {
+	for {
+		introducer, err := gd.reader.ReadByte()
+		if err != nil {
+			return gifFrame{}, errGifTruncated
+		}
+
+		switch introducer {
+		case gifExtension:
+			if err = gd.readExtension(); err != nil {
+				return gifFrame{}, err
+			}
+
+		case gifDescriptor:
+			return gd.readFrame()
+
+		case gifTrailer:
+			return gifFrame{}, io.EOF
+
+		default:
+			return gifFrame{}, fmt.Errorf("unknown block 0x%02x", introducer)
+		}
+	}
+}
+
+func (gd *gifDecoder) readExtension() error {
+	label, err := gd.reader.ReadByte()
+	if err != nil {
+		return errGifTruncated
+	}
+
+	switch label {
+	case gifGraphicControl:
+		control, err := gd.readSubBlock()
+		if err != nil {
+			return err
+		}
+
+		if len(control) != 4 {
+			return fmt.Errorf("graphic control extension of %d bytes", len(control))
+		}
+
+		gd.disposal = (control[0] & 0x1C) >> 2
+		gd.delay = int(binary.LittleEndian.Uint16(control[1:3]))
+
+		if control[0]&gifTransparentFlag != 0 {
+			gd.transparent = int(control[3])
+		}
+
+	case gifApplication:
+		identifier, err := gd.readSubBlock()
+		if err != nil || len(identifier) == 0 {
+			return err
+		}
+
+		if string(identifier) == gifLoopExtension {
+			loop, err := gd.readSubBlock()
+			if err != nil || len(loop) == 0 {
+				return err
+			}
+
+			if len(loop) == 3 && loop[0] == 0x01 {
+				gd.loopCount = int(binary.LittleEndian.Uint16(loop[1:3]))
+			}
+		}
+	}
+
+	return (&subBlockReader{reader: gd.reader}).drain()
+}
+
+func (gd *gifDecoder) readFrame() (gifFrame, error) {
+	var descriptor [gifDescriptorSize - 1]byte
+	if _, err := io.ReadFull(gd.reader, descriptor[:]); err != nil {
+		return gifFrame{}, errGifTruncated
+	}
+
+	left := int(binary.LittleEndian.Uint16(descriptor[0:2]))
+	top := int(binary.LittleEndian.Uint16(descriptor[2:4]))
+	width := int(binary.LittleEndian.Uint16(descriptor[4:6]))
+	height := int(binary.LittleEndian.Uint16(descriptor[6:8]))
+	flags := descriptor[8]
+
+	if left+width > gd.config.Width || top+height > gd.config.Height {
+		return gifFrame{}, errors.New("frame is out of bounds")
+	}
+
+	palette := gd.globalTable
+
+	switch {
+	case flags&gifColorTableFlag != 0:
+		var err error
+		if palette, err = gd.readColorTable(flags); err != nil {
+			return gifFrame{}, fmt.Errorf("read local color table: %w", err)
+		}
+
+	case len(palette) == 0:
+		return gifFrame{}, errors.New("frame has no color table")
+
+	case gd.transparent >= 0:
+		palette = slices.Clone(palette)
+	}
+
+	if gd.transparent >= 0 {
+		// An out of range transparent index is tolerated by browsers, the palette is enlarged like image/gif does
+		for len(palette) <= gd.transparent {
+			palette = append(palette, color.RGBA{})
+		}
+
+		palette[gd.transparent] = color.RGBA{}
+	}
+
+	frame := image.NewPaletted(image.Rect(left, top, left+width, top+height), palette)
+
+	if err := gd.readPixels(frame); err != nil {
+		return gifFrame{}, fmt.Errorf("read pixels: %w", err)
+	}
+
+	if flags&gifInterlaceFlag != 0 {
+		uninterlace(frame)
+	}
+
+	output := gifFrame{image: frame, delay: gd.delay, disposal: gd.disposal}
+
+	// The graphic control extension only applies to the frame following it
+	gd.delay = 0
+	gd.transparent = -1
+
+	return output, nil
+}
+
+func (gd *gifDecoder) readPixels(frame *image.Paletted) error {
+	litWidth, err := gd.reader.ReadByte()
+	if err != nil {
+		return errGifTruncated
+	}
+
+	if litWidth < 2 || litWidth > 8 {
+		return fmt.Errorf("invalid code size of %d", litWidth)
+	}
+
+	blocks := &subBlockReader{reader: gd.reader}
+
+	decompressor := lzw.NewReader(blocks, lzw.LSB, int(litWidth))
+	defer func() {
+		_ = decompressor.Close()
+	}()
+
+	if _, err = io.ReadFull(decompressor, frame.Pix); err != nil {
+		return fmt.Errorf("decompress: %w", err)
+	}
+
+	if len(frame.Palette) < gifMaxColorTableSize {
+		for _, pixel := range frame.Pix {
+			if int(pixel) >= len(frame.Palette) {
+				return errors.New("pixel is out of the color table")
+			}
+		}
+	}
+
+	return blocks.drain()
+}
+
+func (gd *gifDecoder) readColorTable(flags byte) (color.Palette, error) {
+	table := make([]byte, colorTableSize(flags))
+	if _, err := io.ReadFull(gd.reader, table); err != nil {
+		return nil, errGifTruncated
+	}
+
+	palette := make(color.Palette, len(table)/3)
+	for index := range palette {
+		palette[index] = color.RGBA{R: table[3*index], G: table[3*index+1], B: table[3*index+2], A: 0xFF}
+	}
+
+	return palette, nil
+}
+
+func (gd *gifDecoder) readSubBlock() ([]byte, error) {
+	size, err := gd.reader.ReadByte()
+	if err != nil {
+		return nil, errGifTruncated
+	}
+
+	block := make([]byte, size)
+	if _, err = io.ReadFull(gd.reader, block); err != nil {
+		return nil, errGifTruncated
+	}
+
+	return block, nil
+}
+
+// gifInterlacing is the order of the rows of an interlaced frame, as steps and starting rows of its passes
+var gifInterlacing = [][2]int{{8, 0}, {8, 4}, {4, 2}, {2, 1}}
+
+func uninterlace(frame *image.Paletted) {
+	width := frame.Bounds().Dx()
+	height := frame.Bounds().Dy()
+
+	pixels := make([]byte, width*height)
+	offset := 0
+
+	for _, pass := range gifInterlacing {
+		for y := pass[1]; y < height; y += pass[0] {
+			copy(pixels[y*width:(y+1)*width], frame.Pix[offset:offset+width])
+			offset += width
+		}
+	}
+
+	frame.Pix = pixels
+}
+
+// subBlockReader reads the content of the length-prefixed sub-blocks of the gif format, up to their terminator
+type subBlockReader struct {
+	reader    *bytes.Reader
+	remaining int
+	done      bool
+}
+
+func (sbr *subBlockReader) fill() error {
+	for sbr.remaining == 0 {
+		if sbr.done {
+			return io.EOF
+		}
+
+		size, err := sbr.reader.ReadByte()
+		if err != nil {
+			return errGifTruncated
+		}
+
+		if size == 0 {
+			sbr.done = true
+		}
+
+		sbr.remaining = int(size)
+	}
+
+	return nil
+}
+
+// ReadByte makes the LZW reader read the sub-blocks directly, instead of buffering past their terminator
+func (sbr *subBlockReader) ReadByte() (byte, error) {
+	if err := sbr.fill(); err != nil {
+		return 0, err
+	}
+
+	value, err := sbr.reader.ReadByte()
+	if err != nil {
+		return 0, errGifTruncated
+	}
+
+	sbr.remaining--
+
+	return value, nil
+}
+
+func (sbr *subBlockReader) Read(content []byte) (int, error) {
+	if len(content) == 0 {
+		return 0, nil
+	}
+
+	if err := sbr.fill(); err != nil {
+		return 0, err
+	}
+
+	read, err := sbr.reader.Read(content[:min(len(content), sbr.remaining)])
+	sbr.remaining -= read
+
+	if err != nil {
+		return read, errGifTruncated
+	}
+
+	return read, nil
+}
+
+// drain skips the remaining sub-blocks and their terminator
+func (sbr *subBlockReader) drain() error {
+	_, err := io.Copy(io.Discard, sbr)
+	return err
+}
+
+// framePipe hands the encoded frames from the render to the client, blocking the render only when more than
+// its size is waiting for a slow client
+type framePipe struct {
+	cond     *sync.Cond
+	err      error
+	contents [][]byte
+	size     int
+	limit    int
+	closed   bool
+}
+
+func newFramePipe(limit int) *framePipe {
+	return &framePipe{cond: sync.NewCond(&sync.Mutex{}), limit: limit}
+}
+
+// Write buffers the content, waiting for the client to read the previous ones when the pipe is full
+func (fp *framePipe) Write(content []byte) (int, error) {
+	fp.cond.L.Lock()
+	defer fp.cond.L.Unlock()
+
+	for fp.size != 0 && fp.size+len(content) > fp.limit && !fp.closed {
+		fp.cond.Wait()
+	}
+
+	if fp.closed {
+		return 0, fp.err
+	}
+
+	fp.contents = append(fp.contents, bytes.Clone(content))
+	fp.size += len(content)
+	fp.cond.Broadcast()
+
+	return len(content), nil
+}
+
+// close ends the pipe once the buffered contents are read, with io.EOF when err is nil. Only the first close counts.
+func (fp *framePipe) close(err error) {
+	fp.cond.L.Lock()
+	defer fp.cond.L.Unlock()
+
+	if fp.closed {
+		return
+	}
+
+	if err == nil {
+		err = io.EOF
+	}
+
+	fp.err = err
+	fp.closed = true
+	fp.cond.Broadcast()
+}
+
+// next waits for the next content, answering the closing error when drained
+func (fp *framePipe) next() ([]byte, error) {
+	fp.cond.L.Lock()
+	defer fp.cond.L.Unlock()
+
+	for len(fp.contents) == 0 && !fp.closed {
+		fp.cond.Wait()
+	}
+
+	if len(fp.contents) == 0 {
+		return nil, fp.err
+	}
+
+	content := fp.contents[0]
+	fp.contents[0] = nil
+	fp.contents = fp.contents[1:]
+	fp.size -= len(content)
+	fp.cond.Broadcast()
+
+	return content, nil
+}
@@ -0,0 +1,516 @@
+package kitten
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+	"slices"
+	"testing"
+	"time"
+)
+
+var (
+	testGlobalPalette = color.Palette{
+		color.RGBA{R: 0xFF, A: 0xFF},
+		color.RGBA{G: 0xFF, A: 0xFF},
+		color.RGBA{B: 0xFF, A: 0xFF},
+		color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	}
+	testLocalPalette = color.Palette{
+		color.RGBA{A: 0xFF},
+		color.RGBA{R: 0xFF, G: 0xFF, A: 0xFF},
+	}
+	testTransparentPalette = color.Palette{
+		color.RGBA{R: 0xFF, A: 0xFF},
+		color.RGBA{},
+		color.RGBA{B: 0xFF, A: 0xFF},
+		color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF},
+	}
+)
+
+func newTestFrame(bounds image.Rectangle, palette color.Palette, seed int) *image.Paletted {
+	frame := image.NewPaletted(bounds, palette)
+	for index := range frame.Pix {
+		frame.Pix[index] = byte((index + seed) % len(palette))
+	}
+
+	return frame
+}
+
+// gifLocalTables reports for each frame of the gif if it carries a local color table
+func gifLocalTables(t *testing.T, payload []byte) []bool {
+	t.Helper()
+
+	var output []bool
+
+	offset := gifHeaderSize + colorTableSize(payload[10])
+
+	for offset < len(payload) {
+		var err error
+
+		switch payload[offset] {
+		case gifExtension:
+			offset, err = skipSubBlocks(payload, offset+2)
+
+		case gifDescriptor:
+			output = append(output, payload[offset+9]&gifColorTableFlag != 0)
+			offset, err = skipSubBlocks(payload, offset+gifDescriptorSize+colorTableSize(payload[offset+9])+1)
+
+		default:
+			return output
+		}
+
+		if err != nil {
+			t.Fatalf("walk gif: %s", err)
+		}
+	}
+
+	return output
+}
+
+func equalPalettes(a, b color.Palette) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		aRed, aGreen, aBlue, aAlpha := a[index].RGBA()
+		bRed, bGreen, bBlue, bAlpha := b[index].RGBA()
+
+		if aRed != bRed || aGreen != bGreen || aBlue != bBlue || aAlpha != bAlpha {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestGifEncoder(t *testing.T) {
+	t.Parallel()
+
+	bounds := image.Rect(0, 0, 8, 6)
+	globalConfig := image.Config{Width: 8, Height: 6, ColorModel: testGlobalPalette}
+
+	cases := map[string]struct {
+		header          gifHeader
+		frames          []gifFrame
+		wantLocalTables []bool
+		wantLoopCount   int
+	}{
+		"global palette": {
+			gifHeader{config: globalConfig, animated: true},
+			[]gifFrame{
+				{newTestFrame(bounds, testGlobalPalette, 0), 10, gif.DisposalNone},
+				{newTestFrame(bounds, testGlobalPalette, 1), 20, gif.DisposalBackground},
+			},
+			[]bool{false, false},
+			0,
+		},
+		"local palette": {
+			gifHeader{config: globalConfig, loopCount: 3, animated: true},
+			[]gifFrame{
+				{newTestFrame(bounds, testGlobalPalette, 0), 10, 0},
+				{newTestFrame(bounds, testLocalPalette, 1), 10, gif.DisposalPrevious},
+			},
+			[]bool{false, true},
+			3,
+		},
+		"no global palette": {
+			gifHeader{config: image.Config{Width: 8, Height: 6}, animated: true},
+			[]gifFrame{
+				{newTestFrame(bounds, testLocalPalette, 0), 10, 0},
+				{newTestFrame(bounds, testGlobalPalette, 1), 10, 0},
+			},
+			[]bool{true, true},
+			0,
+		},
+		"transparency": {
+			gifHeader{config: globalConfig, animated: true},
+			[]gifFrame{
+				{newTestFrame(bounds, testTransparentPalette, 0), 10, gif.DisposalBackground},
+				{newTestFrame(bounds, testGlobalPalette, 1), 10, 0},
+			},
+			[]bool{false, false},
+			0,
+		},
+		"empty delay": {
+			gifHeader{config: globalConfig, animated: true},
+			[]gifFrame{
+				{newTestFrame(bounds, testGlobalPalette, 0), 0, 0},
+				{newTestFrame(bounds, testGlobalPalette, 1), 0, 0},
+			},
+			[]bool{false, false},
+			0,
+		},
+		"single frame": {
+			gifHeader{config: globalConfig},
+			[]gifFrame{
+				{newTestFrame(bounds, testGlobalPalette, 0), 0, 0},
+			},
+			[]bool{false},
+			-1,
+		},
+		"frame offset": {
+			gifHeader{config: globalConfig},
+			[]gifFrame{
+				{newTestFrame(image.Rect(2, 1, 6, 5), testLocalPalette, 0), 0, 0},
+			},
+			[]bool{true},
+			-1,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				buffer  bytes.Buffer
+				flushes int
+			)
+
+			encoder := newGifEncoder(&buffer, func() error {
+				flushes++
+				return nil
+			}, testCase.header)
+
+			for _, frame := range testCase.frames {
+				if err := encoder.writeFrame(frame.image, frame.delay, frame.disposal); err != nil {
+					t.Fatalf("writeFrame(): %s", err)
+				}
+			}
+
+			if err := encoder.close(); err != nil {
+				t.Fatalf("close(): %s", err)
+			}
+
+			if want := len(testCase.frames) + 1; flushes != want {
+				t.Errorf("flushed %d times, want %d", flushes, want)
+			}
+
+			payload := buffer.Bytes()
+
+			output, err := gif.DecodeAll(bytes.NewReader(payload))
+			if err != nil {
+				t.Fatalf("decode gif: %s", err)
+			}
+
+			if output.Config.Width != testCase.header.config.Width || output.Config.Height != testCase.header.config.Height {
+				t.Errorf("size = %dx%d, want %dx%d", output.Config.Width, output.Config.Height, testCase.header.config.Width, testCase.header.config.Height)
+			}
+
+			if output.LoopCount != testCase.wantLoopCount {
+				t.Errorf("LoopCount = %d, want %d", output.LoopCount, testCase.wantLoopCount)
+			}
+
+			wantGlobal, _ := testCase.header.config.ColorModel.(color.Palette)
+			if gotGlobal, _ := output.Config.ColorModel.(color.Palette); !equalPalettes(gotGlobal, wantGlobal) {
+				t.Errorf("global palette = %v, want %v", gotGlobal, wantGlobal)
+			}
+
+			if got := gifLocalTables(t, payload); !slices.Equal(got, testCase.wantLocalTables) {
+				t.Errorf("local color tables = %v, want %v", got, testCase.wantLocalTables)
+			}
+
+			if len(output.Image) != len(testCase.frames) {
+				t.Fatalf("decoded %d frames, want %d", len(output.Image), len(testCase.frames))
+			}
+
+			for index, want := range testCase.frames {
+				got := output.Image[index]
+
+				if got.Bounds() != want.image.Bounds() {
+					t.Errorf("frame %d bounds = %v, want %v", index, got.Bounds(), want.image.Bounds())
+				}
+
+				if !bytes.Equal(got.Pix, want.image.Pix) {
+					t.Errorf("frame %d pixels differ", index)
+				}
+
+				if !equalPalettes(got.Palette[:len(want.image.Palette)], want.image.Palette) {
+					t.Errorf("frame %d palette = %v, want %v", index, got.Palette, want.image.Palette)
+				}
+
+				if output.Delay[index] != want.delay {
+					t.Errorf("frame %d delay = %d, want %d", index, output.Delay[index], want.delay)
+				}
+
+				if output.Disposal[index] != want.disposal {
+					t.Errorf("frame %d disposal = %d, want %d", index, output.Disposal[index], want.disposal)
+				}
+			}
+		})
+	}
+}
+
+// interlacedGif encodes a single frame gif whose pixels are stored interlaced, answering it with the frame once deinterlaced
+func interlacedGif(t *testing.T) ([]byte, *image.Paletted) {
+	t.Helper()
+
+	frame := newTestFrame(image.Rect(0, 0, 3, 11), testGlobalPalette, 0)
+	for y := range 11 {
+		for x := range 3 {
+			frame.Pix[y*3+x] = byte(y % len(testGlobalPalette))
+		}
+	}
+
+	stored := image.NewPaletted(frame.Bounds(), frame.Palette)
+	offset := 0
+
+	for _, pass := range gifInterlacing {
+		for y := pass[1]; y < 11; y += pass[0] {
+			copy(stored.Pix[offset:offset+3], frame.Pix[y*3:(y+1)*3])
+			offset += 3
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := gif.Encode(&buffer, stored, nil); err != nil {
+		t.Fatalf("encode gif: %s", err)
+	}
+
+	payload := buffer.Bytes()
+
+	descriptor := gifHeaderSize + colorTableSize(payload[10])
+	for payload[descriptor] != gifDescriptor {
+		var err error
+		if descriptor, err = skipSubBlocks(payload, descriptor+2); err != nil {
+			t.Fatalf("find descriptor: %s", err)
+		}
+	}
+
+	payload[descriptor+9] |= gifInterlaceFlag
+
+	return payload, frame
+}
+
+func TestGifDecoder(t *testing.T) {
+	t.Parallel()
+
+	var animated bytes.Buffer
+	if err := gif.EncodeAll(&animated, &gif.GIF{
+		Image: []*image.Paletted{
+			newTestFrame(image.Rect(0, 0, 8, 6), testGlobalPalette, 0),
+			newTestFrame(image.Rect(1, 1, 5, 4), testLocalPalette, 1),
+			newTestFrame(image.Rect(0, 0, 8, 6), testTransparentPalette, 2),
+		},
+		Delay:     []int{10, 0, 30},
+		Disposal:  []byte{gif.DisposalBackground, 0, gif.DisposalPrevious},
+		LoopCount: 2,
+		Config:    image.Config{Width: 8, Height: 6, ColorModel: testGlobalPalette},
+	}); err != nil {
+		t.Fatalf("encode gif: %s", err)
+	}
+
+	interlaced, deinterlaced := interlacedGif(t)
+	plain := encodeGif(t, 4, 20, 10)
+
+	cases := map[string]struct {
+		payload    []byte
+		wantFrames int
+		wantErr    error
+	}{
+		"animated": {
+			animated.Bytes(),
+			3,
+			nil,
+		},
+		"plain": {
+			plain,
+			4,
+			nil,
+		},
+		"interlaced": {
+			interlaced,
+			1,
+			nil,
+		},
+		"truncated": {
+			plain[:len(plain)/2],
+			0,
+			errGifTruncated,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			decoder, err := newGifDecoder(testCase.payload, int64(testCase.wantFrames))
+			if err != nil {
+				t.Fatalf("newGifDecoder(): %s", err)
+			}
+
+			var frames []gifFrame
+
+			for {
+				frame, err := decoder.next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+
+				if err != nil {
+					if !errors.Is(err, testCase.wantErr) {
+						t.Fatalf("next() = `%s`, want `%v`", err, testCase.wantErr)
+					}
+
+					return
+				}
+
+				frames = append(frames, frame)
+			}
+
+			if testCase.wantErr != nil {
+				t.Fatalf("next() = EOF, want `%s`", testCase.wantErr)
+			}
+
+			want, err := gif.DecodeAll(bytes.NewReader(testCase.payload))
+			if err != nil {
+				t.Fatalf("decode gif: %s", err)
+			}
+
+			if header := decoder.header(); header.loopCount != want.LoopCount || header.config.Width != want.Config.Width || header.config.Height != want.Config.Height || header.background != want.BackgroundIndex {
+				t.Errorf("header = %+v, want %+v", header, want.Config)
+			}
+
+			if len(frames) != len(want.Image) {
+				t.Fatalf("decoded %d frames, want %d", len(frames), len(want.Image))
+			}
+
+			for index, frame := range frames {
+				if frame.image.Bounds() != want.Image[index].Bounds() || !bytes.Equal(frame.image.Pix, want.Image[index].Pix) {
+					t.Errorf("frame %d pixels differ", index)
+				}
+
+				if !equalPalettes(frame.image.Palette, want.Image[index].Palette) {
+					t.Errorf("frame %d palette = %v, want %v", index, frame.image.Palette, want.Image[index].Palette)
+				}
+
+				if frame.delay != want.Delay[index] || frame.disposal != want.Disposal[index] {
+					t.Errorf("frame %d = (%d, %d), want (%d, %d)", index, frame.delay, frame.disposal, want.Delay[index], want.Disposal[index])
+				}
+			}
+
+			if intention == "interlaced" && !bytes.Equal(frames[0].image.Pix, deinterlaced.Pix) {
+				t.Errorf("interlaced frame is not reordered")
+			}
+		})
+	}
+}
+
+func TestFramePipe(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		writes     []int
+		limit      int
+		blocked    int
+		readerGone bool
+	}{
+		"in order": {
+			[]int{10, 20, 30},
+			100,
+			-1,
+			false,
+		},
+		"oversized content": {
+			[]int{20},
+			10,
+			-1,
+			false,
+		},
+		"full": {
+			[]int{6, 6, 2},
+			10,
+			1,
+			false,
+		},
+		"reader gone": {
+			[]int{6, 6},
+			10,
+			1,
+			true,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			pipe := newFramePipe(testCase.limit)
+			results := make(chan error, len(testCase.writes))
+
+			go func() {
+				for index, size := range testCase.writes {
+					_, err := pipe.Write(bytes.Repeat([]byte{byte(index)}, size))
+					results <- err
+
+					if err != nil {
+						return
+					}
+				}
+
+				pipe.close(nil)
+			}()
+
+			var reads [][]byte
+
+			for index := range testCase.writes {
+				if index == testCase.blocked {
+					select {
+					case err := <-results:
+						t.Fatalf("write %d is not blocked: %v", index, err)
+					case <-time.After(time.Millisecond * 50):
+					}
+
+					if testCase.readerGone {
+						pipe.close(errStreamClosed)
+
+						if err := <-results; !errors.Is(err, errStreamClosed) {
+							t.Errorf("Write() = `%v`, want `%s`", err, errStreamClosed)
+						}
+
+						return
+					}
+
+					content, err := pipe.next()
+					if err != nil {
+						t.Fatalf("next(): %s", err)
+					}
+
+					reads = append(reads, content)
+				}
+
+				if err := <-results; err != nil {
+					t.Fatalf("Write(): %s", err)
+				}
+			}
+
+			for {
+				content, err := pipe.next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+
+				if err != nil {
+					t.Fatalf("next(): %s", err)
+				}
+
+				reads = append(reads, content)
+			}
+
+			if len(reads) != len(testCase.writes) {
+				t.Fatalf("read %d contents, want %d", len(reads), len(testCase.writes))
+			}
+
+			for index, content := range reads {
+				if !bytes.Equal(content, bytes.Repeat([]byte{byte(index)}, testCase.writes[index])) {
+					t.Errorf("content %d is out of order", index)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/ViBiOh/kitten/pkg/giphy"
+)
+
+const giphyName = "giphy"
+
+type giphyProvider struct {
+	service giphy.Service
+}
+
+// NewGiphyProvider serves Giphy gifs, nil if no API key is configured
+func NewGiphyProvider(service giphy.Service) Provider {
+	if !service.Enabled() {
+		return nil
+	}
+
+	return giphyProvider{service: service}
+}
+
+func (gp giphyProvider) Name() string {
+	return giphyName
+}
+
+func (gp giphyProvider) Search(ctx context.Context, query, cursor string) (Media, string, error) {
+	content, next, err := gp.service.Search(ctx, query, cursor)
+	if err != nil {
+		return Media{}, "", giphyError(err)
+	}
+
+	return newGiphyMedia(content), next, nil
+}
+
+func (gp giphyProvider) Get(ctx context.Context, id string) (Media, error) {
+	content, err := gp.service.Get(ctx, id)
+	if err != nil {
+		return Media{}, giphyError(err)
+	}
+
+	return newGiphyMedia(content), nil
+}
+
+// Report sends the pingback of the gif, Giphy doesn't need the query
+func (gp giphyProvider) Report(ctx context.Context, media Media, _ string) {
+	content, err := gp.service.Get(ctx, media.ID)
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "get gif for pingback", slog.String("id", media.ID), slog.Any("error", err))
+		return
+	}
+
+	gp.service.SendAnalytics(ctx, content)
+}
+
+func (gp giphyProvider) Cached(ctx context.Context) ([]string, error) {
+	return gp.service.Cached(ctx)
+}
+
+func (gp giphyProvider) Evict(ctx context.Context, ids ...string) error {
+	return gp.service.Evict(ctx, ids...)
+}
+
+func newGiphyMedia(content giphy.ResponseObject) Media {
+	author, authorURL := content.Author()
+
+	return Media{
+		ID:  content.ID,
+		URL: content.GetImageURL(),
+		Attribution: Attribution{
+			Provider:  "Giphy",
+			URL:       content.URL,
+			Author:    author,
+			AuthorURL: authorURL,
+		},
+	}
+}
+
+func giphyError(err error) error {
+	switch {
+	case errors.Is(err, giphy.ErrNotFound):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.Is(err, giphy.ErrRateLimitExceeded):
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	default:
+		return err
+	}
+}
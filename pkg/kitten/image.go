@@ -4,41 +4,46 @@ import (
 	"context"
 	"fmt"
 	"image"
-	"io"
-
-	"github.com/ViBiOh/httputils/v4/pkg/request"
-	"github.com/go-oss/image/imageutil"
+	"log/slog"
 )
 
-func (s Service) generateImage(ctx context.Context, from, caption string) (image.Image, error) {
-	imageOutput, err := getImage(ctx, from)
+func (s Service) generateImage(ctx context.Context, spec renderSpec) (image.Image, error) {
+	payload, err := s.fetchSource(ctx, spec.source)
 	if err != nil {
 		return nil, fmt.Errorf("get imageOutput: %w", err)
 	}
 
-	imageOutput, err = s.CaptionImage(ctx, imageOutput, caption)
-	if err != nil {
-		return nil, fmt.Errorf("caption imageOutput: %w", err)
-	}
+	var imageOutput image.Image
 
-	return imageOutput, nil
-}
+	err = s.renders.run(ctx, func(ctx context.Context) (err error) {
+		imageOutput, err = s.limits.decodeImage(payload)
+		if err != nil {
+			return fmt.Errorf("decode imageOutput: %w", err)
+		}
 
-func getImage(ctx context.Context, imageURL string) (image.Image, error) {
-	resp, err := request.Get(imageURL).Send(ctx, nil)
-	if err != nil {
-		return nil, fmt.Errorf("fetch URL `%s`: %w", imageURL, err)
-	}
+		imageOutput, err = s.captionImage(ctx, resizeImage(imageOutput, spec.width), spec.caption, spec.style, spec.layout)
+		if err != nil {
+			return fmt.Errorf("caption imageOutput: %w", err)
+		}
 
-	reader, err := imageutil.RemoveExif(io.LimitReader(resp.Body, maxBodySize))
-	if err != nil {
-		return nil, fmt.Errorf("remove exif from image, perhaps it exceeded the %d bytes length: %w", maxBodySize, err)
-	}
+		return nil
+	})
 
-	output, _, err := image.Decode(reader)
+	return imageOutput, err
+}
+
+// fetchSource downloads the source within the limits, before taking a render slot so a slow origin doesn't hold it
+func (s Service) fetchSource(ctx context.Context, from source) ([]byte, error) {
+	body, err := s.sources.open(ctx, from)
 	if err != nil {
-		return nil, fmt.Errorf("decode image: %w", err)
+		return nil, err
 	}
 
-	return output, nil
+	defer func() {
+		if closeErr := body.Close(); closeErr != nil {
+			slog.LogAttrs(ctx, slog.LevelWarn, "close source", slog.Any("error", closeErr))
+		}
+	}()
+
+	return s.limits.read(body)
 }
@@ -4,14 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
-	"errors"
 	"flag"
 	"fmt"
-	"image"
-	"image/jpeg"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -19,13 +18,20 @@ import (
 	"github.com/ViBiOh/flags"
 	"github.com/ViBiOh/httputils/v4/pkg/httperror"
 	"github.com/ViBiOh/httputils/v4/pkg/redis"
-	"github.com/ViBiOh/kitten/pkg/klipy"
-	"github.com/ViBiOh/kitten/pkg/unsplash"
-	"github.com/fogleman/gg"
+	"github.com/ViBiOh/kitten/pkg/fetch"
+	"github.com/ViBiOh/kitten/pkg/ratelimit"
 	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	nextPosHeader  = "X-Next-Pos"
+	providerHeader = "X-Provider"
+
+	// rendersFolder holds the renders inside the tmp folder, apart from files of other processes
+	rendersFolder = "kitten-renders"
+)
+
 var (
 	bufferPool = sync.Pool{
 		New: func() any {
@@ -42,35 +48,139 @@ type Service struct {
 	tracer          trace.Tracer
 	cachedMetric    metric.Int64Counter
 	servedMetric    metric.Int64Counter
-	tmpFolder       string
+	providerMetric  metric.Int64Counter
+	rendersFolder   string
 	website         string
-	unsplashService unsplash.Service
-	klipyService    klipy.Service
+	adminToken      string
+	signatureSecret string
+	sources         sourceCache
+	prerender       *prerenderer
+	breaker         *breaker
+	recent          *recentResults
+	limits          limits
+	renders         throttle
+	providers       Registry
+	rateLimiter     ratelimit.Service
+	signatureTTL    time.Duration
+	rendersTTL      time.Duration
+	rendersSize     int64
+	shortLinkTTL    time.Duration
+	batchSize       int
+	batchWorkers    int
+	prerenderNext   bool
+	signatureGrace  bool
+	urlSources      bool
 }
 
 type Config struct {
-	TmpFolder string
+	TmpFolder        string
+	AdminToken       string
+	SignatureSecret  string
+	SourceCacheSize  int64
+	RendersSize      int64
+	PrerenderWorkers int
+	PrerenderQueue   int
+	BatchSize        int
+	BatchWorkers     int
+	RenderWorkers    int
+	MaxSourceSize    int64
+	MaxPixels        int64
+	MaxFrames        int64
+	MaxFramePixels   int64
+	MaxCaption       int64
+	ProviderFailures int
+	RecentResults    int
+	ImageProviders   []string
+	GifProviders     []string
+	SignatureTTL     time.Duration
+	ShortLinkTTL     time.Duration
+	RendersTTL       time.Duration
+	RenderQueue      time.Duration
+	RenderTimeout    time.Duration
+	ProviderCooldown time.Duration
+	PrerenderNext    bool
+	SignatureGrace   bool
+	URLSources       bool
 }
 
 func Flags(fs *flag.FlagSet, prefix string, overrides ...flags.Override) *Config {
 	var config Config
 
 	flags.New("TmpFolder", "Temp folder for storing cache image").Prefix(prefix).DocPrefix("kitten").StringVar(fs, &config.TmpFolder, "/tmp", overrides)
+	flags.New("AdminToken", "Bearer token for the admin API, disabled if empty").Prefix(prefix).DocPrefix("kitten").StringVar(fs, &config.AdminToken, "", overrides)
+	flags.New("SignatureSecret", "Secret for signing meme URLs, disabled if empty").Prefix(prefix).DocPrefix("kitten").StringVar(fs, &config.SignatureSecret, "", overrides)
+	flags.New("SignatureTTL", "Validity of signed meme URLs, 0 for no expiration").Prefix(prefix).DocPrefix("kitten").DurationVar(fs, &config.SignatureTTL, 0, overrides)
+	flags.New("SignatureGrace", "Accept unsigned meme URLs during migration").Prefix(prefix).DocPrefix("kitten").BoolVar(fs, &config.SignatureGrace, false, overrides)
+	flags.New("ShortLinkTTL", "Validity of short links, extended on hit, 0 to disable").Prefix(prefix).DocPrefix("kitten").DurationVar(fs, &config.ShortLinkTTL, time.Hour*24*30, overrides)
+	flags.New("SourceCacheSize", "Max size in bytes of the source images cache, 0 to disable").Prefix(prefix).DocPrefix("kitten").Int64Var(fs, &config.SourceCacheSize, 100<<20, overrides)
+	flags.New("RendersSize", "Max size in bytes of the renders, the oldest ones being removed first, 0 for no limit").Prefix(prefix).DocPrefix("kitten").Int64Var(fs, &config.RendersSize, 1<<30, overrides)
+	flags.New("RendersTTL", "Max age of a render, uploaded ones being lost with it, 0 for no limit").Prefix(prefix).DocPrefix("kitten").DurationVar(fs, &config.RendersTTL, cacheDuration, overrides)
+	flags.New("PrerenderWorkers", "Number of background workers rendering previews, 0 to disable").Prefix(prefix).DocPrefix("kitten").IntVar(fs, &config.PrerenderWorkers, 2, overrides)
+	flags.New("PrerenderQueue", "Size of the background rendering queue").Prefix(prefix).DocPrefix("kitten").IntVar(fs, &config.PrerenderQueue, 32, overrides)
+	flags.New("PrerenderNext", "Prefetch the next gif result in background").Prefix(prefix).DocPrefix("kitten").BoolVar(fs, &config.PrerenderNext, true, overrides)
+	flags.New("BatchSize", "Max number of memes in a batch render").Prefix(prefix).DocPrefix("kitten").IntVar(fs, &config.BatchSize, 50, overrides)
+	flags.New("BatchWorkers", "Number of concurrent renders in a batch").Prefix(prefix).DocPrefix("kitten").IntVar(fs, &config.BatchWorkers, 4, overrides)
+	flags.New("RenderWorkers", "Max number of concurrent renders, 0 for no limit").Prefix(prefix).DocPrefix("kitten").IntVar(fs, &config.RenderWorkers, 8, overrides)
+	flags.New("RenderQueue", "Max wait for a render slot before answering 503, 0 to shed immediately").Prefix(prefix).DocPrefix("kitten").DurationVar(fs, &config.RenderQueue, time.Second*2, overrides)
+	flags.New("RenderTimeout", "Deadline of a single render, 0 for no deadline").Prefix(prefix).DocPrefix("kitten").DurationVar(fs, &config.RenderTimeout, time.Second*30, overrides)
+	flags.New("MaxSourceSize", "Max size in bytes of a source image or gif, 0 for no limit").Prefix(prefix).DocPrefix("kitten").Int64Var(fs, &config.MaxSourceSize, 4<<20, overrides)
+	flags.New("MaxPixels", "Max pixels of a source image or gif, 0 for no limit").Prefix(prefix).DocPrefix("kitten").Int64Var(fs, &config.MaxPixels, 4096*4096, overrides)
+	flags.New("MaxFrames", "Max frames of a source gif, 0 for no limit").Prefix(prefix).DocPrefix("kitten").Int64Var(fs, &config.MaxFrames, 300, overrides)
+	flags.New("MaxFramePixels", "Max pixels of all frames of a source gif, 0 for no limit").Prefix(prefix).DocPrefix("kitten").Int64Var(fs, &config.MaxFramePixels, 100_000_000, overrides)
+	flags.New("MaxCaption", "Max length of a caption, 0 for no limit").Prefix(prefix).DocPrefix("kitten").Int64Var(fs, &config.MaxCaption, 280, overrides)
+	flags.New("URLSources", "Allow rendering images downloaded from any URL in the render API").Prefix(prefix).DocPrefix("kitten").BoolVar(fs, &config.URLSources, false, overrides)
+	flags.New("ImageProviders", "Enabled image providers, the first one being the default").Prefix(prefix).DocPrefix("kitten").StringSliceVar(fs, &config.ImageProviders, []string{unsplashName, libraryName}, overrides)
+	flags.New("GifProviders", "Enabled gif providers, the first one being the default").Prefix(prefix).DocPrefix("kitten").StringSliceVar(fs, &config.GifProviders, []string{klipyName, giphyName, libraryName}, overrides)
+	flags.New("ProviderFailures", "Consecutive failures of a provider before skipping it, a rate limit skipping it at once, 0 to never skip").Prefix(prefix).DocPrefix("kitten").IntVar(fs, &config.ProviderFailures, 3, overrides)
+	flags.New("ProviderCooldown", "Duration a failing provider is skipped before being tried again").Prefix(prefix).DocPrefix("kitten").DurationVar(fs, &config.ProviderCooldown, time.Minute*5, overrides)
+	flags.New("RecentResults", "Number of searches keeping their recent results, served when every provider fails, 0 to disable").Prefix(prefix).DocPrefix("kitten").IntVar(fs, &config.RecentResults, 1000, overrides)
 
 	return &config
 }
 
-func New(config *Config, unsplashService unsplash.Service, klipyService klipy.Service, redisClient redis.Client, meterProvider metric.MeterProvider, tracerProvider trace.TracerProvider, website string) Service {
+// NewCaptioner creates a service only decoding and captioning images and gifs within the configured limits, without providers nor renders folder
+func NewCaptioner(config *Config) Service {
+	return Service{limits: newLimits(config)}
+}
+
+func newLimits(config *Config) limits {
+	return limits{
+		bytes:       config.MaxSourceSize,
+		pixels:      config.MaxPixels,
+		frames:      config.MaxFrames,
+		framePixels: config.MaxFramePixels,
+		caption:     config.MaxCaption,
+	}
+}
+
+func New(config *Config, providers Registry, rateLimiter ratelimit.Service, fetcher fetch.Service, redisClient redis.Client, meterProvider metric.MeterProvider, tracerProvider trace.TracerProvider, website string) Service {
 	service := Service{
-		unsplashService: unsplashService,
-		klipyService:    klipyService,
+		providers:       providers,
+		rateLimiter:     rateLimiter,
 		redisClient:     redisClient,
 		website:         website,
-		tmpFolder:       config.TmpFolder,
+		rendersFolder:   filepath.Join(config.TmpFolder, rendersFolder),
+		rendersSize:     config.RendersSize,
+		rendersTTL:      config.RendersTTL,
+		adminToken:      config.AdminToken,
+		signatureSecret: config.SignatureSecret,
+		signatureTTL:    config.SignatureTTL,
+		signatureGrace:  config.SignatureGrace,
+		urlSources:      config.URLSources,
+		shortLinkTTL:    config.ShortLinkTTL,
+		prerender:       newPrerenderer(config.PrerenderWorkers, config.PrerenderQueue),
+		prerenderNext:   config.PrerenderNext,
+		breaker:         newBreaker(config.ProviderFailures, config.ProviderCooldown),
+		recent:          newRecentResults(config.RecentResults),
+		batchSize:       config.BatchSize,
+		batchWorkers:    max(config.BatchWorkers, 1),
+		limits:          newLimits(config),
 	}
 
+	var meter metric.Meter
+
 	if meterProvider != nil {
-		meter := meterProvider.Meter("github.com/ViBiOh/kitten/pkg/kitten")
+		meter = meterProvider.Meter("github.com/ViBiOh/kitten/pkg/kitten")
 
 		var err error
 
@@ -83,8 +193,20 @@ func New(config *Config, unsplashService unsplash.Service, klipyService klipy.Se
 		if err != nil {
 			slog.LogAttrs(context.Background(), slog.LevelError, "create served counter", slog.Any("error", err))
 		}
+
+		service.providerMetric, err = meter.Int64Counter("kitten.provider_served")
+		if err != nil {
+			slog.LogAttrs(context.Background(), slog.LevelError, "create provider counter", slog.Any("error", err))
+		}
+	}
+
+	if err := os.MkdirAll(service.rendersFolder, 0o700); err != nil {
+		slog.LogAttrs(context.Background(), slog.LevelError, "create renders folder", slog.Any("error", err))
 	}
 
+	service.sources = newSourceCache(fetcher, filepath.Join(config.TmpFolder, "sources"), config.SourceCacheSize, service.limits, meter)
+	service.renders = newThrottle(config.RenderWorkers, config.RenderQueue, config.RenderTimeout, meter)
+
 	if tracerProvider != nil {
 		service.tracer = tracerProvider.Tracer("kitten")
 	}
@@ -94,7 +216,7 @@ func New(config *Config, unsplashService unsplash.Service, klipyService klipy.Se
 
 func (s Service) SearchHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
+		if !isReadMethod(r.Method) {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
@@ -112,45 +234,61 @@ func (s Service) SearchHandler() http.Handler {
 			return
 		}
 
+		result, err := s.search(ctx, kind, query, urlQuery.Get("pos"))
+		if err != nil {
+			handleRenderError(ctx, w, fmt.Errorf("search %s: %w", kind, err))
+			return
+		}
+
+		w.Header().Set(providerHeader, result.provider.Name())
+
 		switch kind {
 		case imageKind:
-			foundImage, err := s.unsplashService.Search(ctx, query)
-			if err != nil {
-				httperror.InternalServerError(ctx, w, fmt.Errorf("search image: %s", err))
-				return
-			}
-
-			s.serveImage(ctx, w, foundImage, caption)
+			s.serveImage(w, r, newMediaSource(kind, result.provider, result.media), caption)
 
 		case gifKind:
-			httperror.InternalServerError(ctx, w, errors.New("not implemented"))
+			s.enqueueNext(ctx, result.provider.Name(), result.query, caption, result.next)
+
+			w.Header().Set(nextPosHeader, result.next)
+			s.serveGif(w, r, result.media.ID, result.search, caption)
 		}
 	})
 }
 
-func (s Service) serveImage(ctx context.Context, w http.ResponseWriter, image unsplash.Image, caption string) {
-	output, err := s.generateImage(ctx, image.Raw, caption)
+func (s Service) serveImage(w http.ResponseWriter, r *http.Request, from source, caption string) {
+	ctx := r.Context()
+
+	spec := newRenderSpec(from, caption)
+
+	output, err := s.generateImage(ctx, spec)
 	if err != nil {
-		httperror.InternalServerError(ctx, w, err)
+		handleRenderError(ctx, w, err)
+		return
+	}
+
+	if ctx.Err() != nil {
 		return
 	}
 
-	w.Header().Add("Cache-Control", cacheControlDuration)
-	w.Header().Set("Content-Type", "image/jpeg")
-	w.WriteHeader(http.StatusOK)
-	if err := jpeg.Encode(w, output, &jpeg.Options{Quality: 80}); err != nil {
+	buffer := bufferPool.Get().(*bytes.Buffer)
+	defer bufferPool.Put(buffer)
+
+	buffer.Reset()
+	if err := spec.encode(buffer, output); err != nil {
 		httperror.InternalServerError(ctx, w, err)
 		return
 	}
 
+	// The render is not cached yet, it has no modification time and is validated by its ETag only
+	s.serveContent(w, r, s.getCacheFilename(spec), time.Time{}, bytes.NewReader(buffer.Bytes()))
 	s.increaseServed(ctx)
 
-	go s.storeInCache(ctx, image.ID, caption, output)
+	go s.storeInCache(ctx, spec, output)
 }
 
 func (s Service) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
+		if !isReadMethod(r.Method) {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
@@ -163,26 +301,52 @@ func (s Service) Handler() http.Handler {
 			return
 		}
 
-		id, _, caption, err := parseRequest(query)
+		if err = s.verifySignature(query); err != nil {
+			slog.LogAttrs(ctx, slog.LevelWarn, "reject meme request", slog.Any("error", err))
+			httperror.Forbidden(ctx, w)
+			return
+		}
+
+		id, search, caption, err := parseRequest(query)
 		if err != nil {
 			httperror.BadRequest(ctx, w, err)
 			return
 		}
 
-		if s.serveCached(ctx, w, id, caption, false) {
+		if len(r.PathValue("content")) != 0 && s.serveSharePage(w, r) {
 			return
 		}
 
-		s.GetFromUnsplash(ctx, w, id, caption)
+		s.serveMeme(w, r, id, search, caption)
 	})
 }
 
+func (s Service) serveMeme(w http.ResponseWriter, r *http.Request, id, search, caption string) {
+	provider, _, err := s.providers.resolve(imageKind, search)
+	if err != nil {
+		handleRenderError(r.Context(), w, err)
+		return
+	}
+
+	spec := newRenderSpec(newProviderSource(imageKind, provider.Name(), id), caption)
+
+	if s.serveCached(w, r, spec) {
+		return
+	}
+
+	s.GetImage(w, r, provider, id, caption)
+}
+
 func getQuery(r *http.Request) (url.Values, error) {
 	rawContent := r.PathValue("content")
 	if len(rawContent) == 0 {
 		return r.URL.Query(), nil
 	}
 
+	return decodeQuery(rawContent)
+}
+
+func decodeQuery(rawContent string) (url.Values, error) {
 	content, err := base64.URLEncoding.DecodeString(rawContent)
 	if err != nil {
 		return nil, fmt.Errorf("decode content: %w", err)
@@ -211,33 +375,3 @@ func parseRequest(query url.Values) (string, string, string, error) {
 
 	return id, search, caption, nil
 }
-
-func (s Service) caption(imageCtx *gg.Context, text string) (image.Image, error) {
-	fontSize := float64(imageCtx.Width()) * fontSizeCoeff
-	fontFace, resolve := getFontFace(fontSize)
-	defer resolve()
-
-	imageCtx.SetFontFace(fontFace)
-
-	lines := imageCtx.WordWrap(strings.ToUpper(text), float64(imageCtx.Width())*widthPadding)
-	xAnchor := float64(imageCtx.Width() / 2)
-	yAnchor := fontSize / 2
-
-	n := float64(2)
-
-	for _, lineString := range lines {
-		yAnchor += fontSize
-
-		imageCtx.SetRGBA(0, 0, 0, 1)
-		for dy := -n; dy <= n; dy++ {
-			for dx := -n; dx <= n; dx++ {
-				imageCtx.DrawStringAnchored(lineString, xAnchor+dx, yAnchor+dy, 0.5, 0.5)
-			}
-		}
-
-		imageCtx.SetRGBA(1, 1, 1, 1)
-		imageCtx.DrawStringAnchored(lineString, xAnchor, yAnchor, 0.5, 0.5)
-	}
-
-	return imageCtx.Image(), nil
-}
@@ -0,0 +1,49 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"image"
+	"os"
+	"testing"
+)
+
+func TestNewCaptioner(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		caption string
+		wantErr error
+	}{
+		"caption": {
+			"hello",
+			nil,
+		},
+		"caption too long": {
+			"a caption way too long",
+			ErrLimitExceeded,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			tmpFolder := t.TempDir()
+			instance := NewCaptioner(&Config{TmpFolder: tmpFolder, MaxCaption: 10})
+
+			output, err := instance.CaptionImage(context.Background(), image.NewRGBA(image.Rect(0, 0, 40, 30)), testCase.caption)
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("CaptionImage() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if err == nil && output.Bounds().Dx() != 40 {
+				t.Errorf("CaptionImage() width = %d, want 40", output.Bounds().Dx())
+			}
+
+			if entries, err := os.ReadDir(tmpFolder); err != nil || len(entries) != 0 {
+				t.Errorf("NewCaptioner() created %v in the tmp folder: %v", entries, err)
+			}
+		})
+	}
+}
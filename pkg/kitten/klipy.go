@@ -0,0 +1,73 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ViBiOh/kitten/pkg/klipy"
+)
+
+const klipyName = "klipy"
+
+type klipyProvider struct {
+	service klipy.Service
+}
+
+// NewKlipyProvider serves Klipy gifs
+func NewKlipyProvider(service klipy.Service) Provider {
+	return klipyProvider{service: service}
+}
+
+func (kp klipyProvider) Name() string {
+	return klipyName
+}
+
+func (kp klipyProvider) Search(ctx context.Context, query, cursor string) (Media, string, error) {
+	content, next, err := kp.service.Search(ctx, query, cursor)
+	if err != nil {
+		return Media{}, "", klipyError(err)
+	}
+
+	return newKlipyMedia(content), next, nil
+}
+
+func (kp klipyProvider) Get(ctx context.Context, id string) (Media, error) {
+	content, err := kp.service.Get(ctx, id)
+	if err != nil {
+		return Media{}, klipyError(err)
+	}
+
+	return newKlipyMedia(content), nil
+}
+
+func (kp klipyProvider) Report(ctx context.Context, media Media, query string) {
+	kp.service.SendAnalytics(ctx, klipy.ResponseObject{ID: media.ID}, query)
+}
+
+func (kp klipyProvider) Cached(ctx context.Context) ([]string, error) {
+	return kp.service.Cached(ctx)
+}
+
+func (kp klipyProvider) Evict(ctx context.Context, ids ...string) error {
+	return kp.service.Evict(ctx, ids...)
+}
+
+func newKlipyMedia(content klipy.ResponseObject) Media {
+	return Media{
+		ID:          content.ID,
+		URL:         content.GetImageURL(),
+		Attribution: Attribution{Provider: "Klipy", URL: content.URL},
+	}
+}
+
+func klipyError(err error) error {
+	switch {
+	case errors.Is(err, klipy.ErrNotFound):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.Is(err, klipy.ErrRateLimitExceeded):
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,221 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ViBiOh/httputils/v4/pkg/httperror"
+	"github.com/ViBiOh/httputils/v4/pkg/httpjson"
+	"github.com/ViBiOh/httputils/v4/pkg/model"
+)
+
+const (
+	SharePrefix = "/share"
+
+	oembedVersion = "1.0"
+	providerName  = "Kitten"
+)
+
+type Meme struct {
+	Attribution Attribution
+	Kind        string
+	Caption     string
+	Search      string
+	Description string
+	Path        string
+	ImageURL    string
+	PageURL     string
+	Width       int
+	Height      int
+}
+
+type OEmbed struct {
+	Type         string `json:"type"`
+	Version      string `json:"version"`
+	Title        string `json:"title,omitempty"`
+	URL          string `json:"url"`
+	AuthorName   string `json:"author_name,omitempty"`
+	AuthorURL    string `json:"author_url,omitempty"`
+	ProviderName string `json:"provider_name"`
+	ProviderURL  string `json:"provider_url"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+}
+
+// Meme resolves the meme shared at the given path, e.g. `/api/...`, `/gif/...` or `/m/...`
+func (s Service) Meme(ctx context.Context, sharedPath string) (Meme, error) {
+	kind, query, err := s.parseSharedPath(ctx, sharedPath)
+	if err != nil {
+		return Meme{}, err
+	}
+
+	id, search, caption, err := parseRequest(query)
+	if err != nil {
+		return Meme{}, model.WrapInvalid(err)
+	}
+
+	provider, _, err := s.providers.resolve(kind, search)
+	if err != nil {
+		return Meme{}, model.WrapNotFound(err)
+	}
+
+	spec, attribution, err := s.resolveRenderRequest(ctx, RenderRequest{
+		Source:   RenderSource{Provider: provider.Name(), ID: id},
+		Captions: []string{caption},
+	}, false)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Meme{}, model.WrapNotFound(err)
+		}
+
+		return Meme{}, err
+	}
+
+	filename, _, err := s.generateAndStore(ctx, spec)
+	if err != nil {
+		return Meme{}, err
+	}
+
+	metadata, err := s.getRenderMetadata(filename, spec, attribution)
+	if err != nil {
+		return Meme{}, err
+	}
+
+	return Meme{
+		Kind:        string(kind),
+		Caption:     caption,
+		Search:      search,
+		Description: attribution.description(),
+		Path:        sharedPath,
+		ImageURL:    s.website + sharedPath,
+		PageURL:     s.website + SharePrefix + sharedPath,
+		Attribution: attribution,
+		Width:       metadata.Width,
+		Height:      metadata.Height,
+	}, nil
+}
+
+// serveSharePage redirects the clients navigating to a shared meme, e.g. a browser or a link unfurler, to its page carrying the Open Graph tags.
+// Clients embedding the meme keep receiving its image.
+func (s Service) serveSharePage(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Add("Vary", "Accept")
+
+	if r.Method != http.MethodGet || !strings.Contains(r.Header.Get("Accept"), "text/html") {
+		return false
+	}
+
+	http.Redirect(w, r, s.website+SharePrefix+r.URL.EscapedPath(), http.StatusFound)
+
+	return true
+}
+
+func (s Service) parseSharedPath(ctx context.Context, sharedPath string) (memeKind, url.Values, error) {
+	var kind memeKind
+	var rawContent string
+
+	switch {
+	case strings.HasPrefix(sharedPath, "/api/"):
+		kind, rawContent = imageKind, strings.TrimPrefix(sharedPath, "/api/")
+	case strings.HasPrefix(sharedPath, "/gif/"):
+		kind, rawContent = gifKind, strings.TrimPrefix(sharedPath, "/gif/")
+	case strings.HasPrefix(sharedPath, "/m/"):
+		return s.parseSharedShortLink(ctx, strings.TrimPrefix(sharedPath, "/m/"))
+	default:
+		return "", nil, model.WrapNotFound(fmt.Errorf("no meme at `%s`", sharedPath))
+	}
+
+	query, err := decodeQuery(rawContent)
+	if err != nil {
+		return "", nil, model.WrapInvalid(err)
+	}
+
+	if err = s.verifySignature(query); err != nil {
+		return "", nil, model.WrapForbidden(err)
+	}
+
+	return kind, query, nil
+}
+
+func (s Service) parseSharedShortLink(ctx context.Context, shortID string) (memeKind, url.Values, error) {
+	if !s.shortLinkEnabled() {
+		return "", nil, model.WrapNotFound(errShortLinkDisabled)
+	}
+
+	values, err := s.loadShortLink(ctx, shortLinkKey(shortID))
+	if err != nil {
+		return "", nil, err
+	}
+
+	if values == nil {
+		return "", nil, model.WrapNotFound(fmt.Errorf("short link `%s` not found", shortID))
+	}
+
+	if parseKind(values.Get("kind")) == gifKind {
+		return gifKind, values, nil
+	}
+
+	return imageKind, values, nil
+}
+
+func (a Attribution) description() string {
+	switch {
+	case len(a.Author) != 0:
+		return fmt.Sprintf("Photo by %s on %s", a.Author, a.Provider)
+	case len(a.Provider) != 0:
+		return fmt.Sprintf("Gif via %s", a.Provider)
+	default:
+		return "Meme generated by " + providerName
+	}
+}
+
+// OEmbedHandler describes a shared meme following the oEmbed specification
+func (s Service) OEmbedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isReadMethod(r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		if format := query.Get("format"); len(format) != 0 && format != "json" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+
+		rawURL := query.Get("url")
+		if !strings.HasPrefix(rawURL, s.website+"/") {
+			httperror.NotFound(ctx, w, fmt.Errorf("`%s` is not a meme", rawURL))
+			return
+		}
+
+		memeURL, err := url.Parse(strings.TrimPrefix(rawURL, s.website))
+		if err != nil {
+			httperror.BadRequest(ctx, w, fmt.Errorf("parse url: %w", err))
+			return
+		}
+
+		meme, err := s.Meme(ctx, strings.TrimPrefix(memeURL.Path, SharePrefix))
+		if httperror.HandleError(ctx, w, err) {
+			return
+		}
+
+		httpjson.Write(ctx, w, http.StatusOK, OEmbed{
+			Type:         "photo",
+			Version:      oembedVersion,
+			Title:        meme.Caption,
+			URL:          meme.ImageURL,
+			AuthorName:   meme.Attribution.Author,
+			AuthorURL:    meme.Attribution.AuthorURL,
+			ProviderName: providerName,
+			ProviderURL:  s.website,
+			Width:        meme.Width,
+			Height:       meme.Height,
+		})
+	})
+}
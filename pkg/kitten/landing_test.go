@@ -0,0 +1,102 @@
+package kitten
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSharePageRedirect(t *testing.T) {
+	t.Parallel()
+
+	setup := newFakeSetup(t)
+	ctx := context.Background()
+
+	imageURL := setup.service.getMemeURL(ctx, imageKind, "abc", "", "hello")
+	gifURL := setup.service.getMemeURL(ctx, gifKind, "abc", "", "hello")
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	cases := map[string]struct {
+		method       string
+		url          string
+		accept       string
+		wantStatus   int
+		wantLocation string
+	}{
+		"image embedded": {
+			http.MethodGet,
+			imageURL,
+			"image/avif,image/webp,*/*",
+			http.StatusOK,
+			"",
+		},
+		"image navigated": {
+			http.MethodGet,
+			imageURL,
+			"text/html,application/xhtml+xml",
+			http.StatusFound,
+			setup.website + SharePrefix + strings.TrimPrefix(imageURL, setup.website),
+		},
+		"gif navigated": {
+			http.MethodGet,
+			gifURL,
+			"text/html",
+			http.StatusFound,
+			setup.website + SharePrefix + strings.TrimPrefix(gifURL, setup.website),
+		},
+		"gif without accept": {
+			http.MethodGet,
+			gifURL,
+			"",
+			http.StatusOK,
+			"",
+		},
+		"head": {
+			http.MethodHead,
+			imageURL,
+			"text/html",
+			http.StatusOK,
+			"",
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			request, err := http.NewRequest(testCase.method, testCase.url, nil)
+			if err != nil {
+				t.Fatalf("create request: %s", err)
+			}
+
+			if len(testCase.accept) != 0 {
+				request.Header.Set("Accept", testCase.accept)
+			}
+
+			resp, err := client.Do(request)
+			if err != nil {
+				t.Fatalf("request: %s", err)
+			}
+
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != testCase.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, testCase.wantStatus)
+			}
+
+			if got := resp.Header.Get("Location"); got != testCase.wantLocation {
+				t.Errorf("Location = `%s`, want `%s`", got, testCase.wantLocation)
+			}
+
+			if got := resp.Header.Get("Vary"); !strings.Contains(got, "Accept") {
+				t.Errorf("Vary = `%s`, want Accept", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ViBiOh/kitten/pkg/library"
+)
+
+const libraryName = "local"
+
+type libraryProvider struct {
+	service library.Service
+	gif     bool
+}
+
+// NewLibraryImageProvider serves the jpeg, png and webp images of the local library, nil if the library is disabled
+func NewLibraryImageProvider(service library.Service) Provider {
+	if !service.Enabled() {
+		return nil
+	}
+
+	return libraryProvider{service: service}
+}
+
+// NewLibraryGifProvider serves the gifs of the local library, nil if the library is disabled
+func NewLibraryGifProvider(service library.Service) Provider {
+	if !service.Enabled() {
+		return nil
+	}
+
+	return libraryProvider{service: service, gif: true}
+}
+
+func (lp libraryProvider) Name() string {
+	return libraryName
+}
+
+func (lp libraryProvider) Search(_ context.Context, query, cursor string) (Media, string, error) {
+	item, next, err := lp.service.Search(query, lp.gif, cursor)
+	if err != nil {
+		return Media{}, "", libraryError(err)
+	}
+
+	return newLibraryMedia(item), next, nil
+}
+
+func (lp libraryProvider) Get(_ context.Context, id string) (Media, error) {
+	item, err := lp.service.Get(id)
+	if err != nil {
+		return Media{}, libraryError(err)
+	}
+
+	if item.Gif != lp.gif {
+		return Media{}, libraryError(library.ErrNotFound)
+	}
+
+	return newLibraryMedia(item), nil
+}
+
+func (lp libraryProvider) Report(context.Context, Media, string) {}
+
+func (lp libraryProvider) Open(_ context.Context, id string) (io.ReadCloser, error) {
+	return lp.service.Open(id)
+}
+
+func newLibraryMedia(item library.Item) Media {
+	return Media{
+		ID:          item.ID,
+		Attribution: Attribution{Provider: "Library"},
+	}
+}
+
+func libraryError(err error) error {
+	switch {
+	case errors.Is(err, library.ErrNotFound):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.Is(err, library.ErrInvalidCursor):
+		return fmt.Errorf("%w: %w", errInvalidRender, err)
+	default:
+		return err
+	}
+}
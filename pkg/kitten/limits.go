@@ -0,0 +1,247 @@
+package kitten
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"unicode/utf8"
+
+	"github.com/go-oss/image/imageutil"
+)
+
+const (
+	gifHeaderSize     = 13
+	gifDescriptorSize = 10
+
+	gifExtension  = 0x21
+	gifDescriptor = 0x2C
+	gifTrailer    = 0x3B
+)
+
+var (
+	ErrLimitExceeded = errors.New("limit exceeded")
+
+	errGifTruncated = errors.New("gif is truncated")
+)
+
+type LimitError struct {
+	Name  string
+	Value int64
+	Max   int64
+}
+
+func (le LimitError) Error() string {
+	return fmt.Sprintf("%s of %d exceeds the limit of %d", le.Name, le.Value, le.Max)
+}
+
+func (le LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+type limits struct {
+	bytes       int64
+	pixels      int64
+	frames      int64
+	framePixels int64
+	caption     int64
+}
+
+func checkLimit(name string, value, max int64) error {
+	if max > 0 && value > max {
+		return LimitError{Name: name, Value: value, Max: max}
+	}
+
+	return nil
+}
+
+func (l limits) checkCaption(caption string) error {
+	return checkLimit("caption length", int64(utf8.RuneCountInString(caption)), l.caption)
+}
+
+func (l limits) read(reader io.Reader) ([]byte, error) {
+	if l.bytes > 0 {
+		reader = io.LimitReader(reader, l.bytes+1)
+	}
+
+	payload, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read source: %w", err)
+	}
+
+	if err = checkLimit("source size", int64(len(payload)), l.bytes); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+func (l limits) decodeImage(payload []byte) (image.Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("decode image config: %w", err)
+	}
+
+	if err = checkLimit("image pixels", int64(config.Width)*int64(config.Height), l.pixels); err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader = bytes.NewReader(payload)
+
+	// only jpeg carries the exif orientation, the other formats are refused by RemoveExif
+	if format == jpegFormat {
+		reader, err = imageutil.RemoveExif(reader)
+		if err != nil {
+			return nil, fmt.Errorf("remove exif from image: %w", err)
+		}
+	}
+
+	output, _, err := image.Decode(reader)
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+
+	return output, nil
+}
+
+// checkGif checks the gif dimensions and frames are within the limits, answering its number of frames
+func (l limits) checkGif(payload []byte) (int64, error) {
+	config, err := gif.DecodeConfig(bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("decode gif config: %w", err)
+	}
+
+	if err = checkLimit("gif pixels", int64(config.Width)*int64(config.Height), l.pixels); err != nil {
+		return 0, err
+	}
+
+	frames, framePixels, err := scanGif(payload)
+	if err != nil {
+		return 0, fmt.Errorf("scan gif: %w", err)
+	}
+
+	if err = checkLimit("gif frames", frames, l.frames); err != nil {
+		return 0, err
+	}
+
+	if err = checkLimit("gif total frame pixels", framePixels, l.framePixels); err != nil {
+		return 0, err
+	}
+
+	return frames, nil
+}
+
+func (l limits) decodeGif(payload []byte) (*gif.GIF, error) {
+	if _, err := l.checkGif(payload); err != nil {
+		return nil, err
+	}
+
+	output, err := gif.DecodeAll(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("decode gif: %w", err)
+	}
+
+	return output, nil
+}
+
+// openGif checks the gif is within the limits, for decoding it frame by frame
+func (l limits) openGif(payload []byte) (*gifDecoder, error) {
+	frames, err := l.checkGif(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return newGifDecoder(payload, frames)
+}
+
+// scanGif walks the gif blocks without decoding frames, for counting them and their pixels
+func scanGif(payload []byte) (int64, int64, error) {
+	if len(payload) < gifHeaderSize {
+		return 0, 0, errGifTruncated
+	}
+
+	var frames, pixels int64
+
+	offset := gifHeaderSize + colorTableSize(payload[10])
+
+	for offset < len(payload) {
+		var err error
+
+		switch payload[offset] {
+		case gifExtension:
+			offset, err = skipSubBlocks(payload, offset+2)
+
+		case gifDescriptor:
+			if offset+gifDescriptorSize > len(payload) {
+				return 0, 0, errGifTruncated
+			}
+
+			width := binary.LittleEndian.Uint16(payload[offset+5:])
+			height := binary.LittleEndian.Uint16(payload[offset+7:])
+
+			frames++
+			pixels += int64(width) * int64(height)
+
+			// Skip the descriptor, the local color table and the LZW minimum code size
+			offset, err = skipSubBlocks(payload, offset+gifDescriptorSize+colorTableSize(payload[offset+9])+1)
+
+		case gifTrailer:
+			return frames, pixels, nil
+
+		default:
+			return 0, 0, fmt.Errorf("unknown block 0x%02x", payload[offset])
+		}
+
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return frames, pixels, nil
+}
+
+func colorTableSize(flags byte) int {
+	if flags&0x80 == 0 {
+		return 0
+	}
+
+	return 3 << ((flags & 0x07) + 1)
+}
+
+func skipSubBlocks(payload []byte, offset int) (int, error) {
+	for {
+		if offset >= len(payload) {
+			return offset, errGifTruncated
+		}
+
+		size := int(payload[offset])
+		offset += size + 1
+
+		if size == 0 {
+			return offset, nil
+		}
+	}
+}
+
+// DecodeImage decodes an image within the configured resource limits
+func (s Service) DecodeImage(reader io.Reader) (image.Image, error) {
+	payload, err := s.limits.read(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.limits.decodeImage(payload)
+}
+
+// DecodeGif decodes a gif within the configured resource limits
+func (s Service) DecodeGif(reader io.Reader) (*gif.GIF, error) {
+	payload, err := s.limits.read(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.limits.decodeGif(payload)
+}
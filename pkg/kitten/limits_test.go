@@ -0,0 +1,323 @@
+package kitten
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ViBiOh/kitten/pkg/fetch"
+)
+
+func encodeGif(t *testing.T, frames, width, height int) []byte {
+	t.Helper()
+
+	animation := &gif.GIF{}
+	for range frames {
+		animation.Image = append(animation.Image, image.NewPaletted(image.Rect(0, 0, width, height), palette.Plan9))
+		animation.Delay = append(animation.Delay, 10)
+	}
+
+	var buffer bytes.Buffer
+	if err := gif.EncodeAll(&buffer, animation); err != nil {
+		t.Fatalf("encode gif: %s", err)
+	}
+
+	return buffer.Bytes()
+}
+
+func encodePng(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("encode png: %s", err)
+	}
+
+	return buffer.Bytes()
+}
+
+func encodeJpeg(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatalf("encode jpeg: %s", err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestScanGif(t *testing.T) {
+	t.Parallel()
+
+	valid := encodeGif(t, 3, 20, 10)
+
+	cases := map[string]struct {
+		payload    []byte
+		wantFrames int64
+		wantPixels int64
+		wantErr    error
+	}{
+		"valid": {
+			payload:    valid,
+			wantFrames: 3,
+			wantPixels: 600,
+		},
+		"empty": {
+			payload: nil,
+			wantErr: errGifTruncated,
+		},
+		"header only": {
+			payload: valid[:gifHeaderSize-1],
+			wantErr: errGifTruncated,
+		},
+		"truncated frame": {
+			payload: valid[:len(valid)/2],
+			wantErr: errGifTruncated,
+		},
+		"truncated descriptor": {
+			payload: valid[:bytes.IndexByte(valid[gifHeaderSize+colorTableSize(valid[10]):], gifDescriptor)+gifHeaderSize+colorTableSize(valid[10])+3],
+			wantErr: errGifTruncated,
+		},
+		"without trailer": {
+			payload:    valid[:len(valid)-1],
+			wantFrames: 3,
+			wantPixels: 600,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			frames, pixels, err := scanGif(testCase.payload)
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("scanGif() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if frames != testCase.wantFrames || pixels != testCase.wantPixels {
+				t.Errorf("scanGif() = (%d, %d), want (%d, %d)", frames, pixels, testCase.wantFrames, testCase.wantPixels)
+			}
+		})
+	}
+}
+
+func TestDecodeGif(t *testing.T) {
+	t.Parallel()
+
+	payload := encodeGif(t, 4, 20, 10)
+
+	cases := map[string]struct {
+		limits  limits
+		payload []byte
+		wantErr error
+	}{
+		"no limit": {
+			limits:  limits{},
+			payload: payload,
+		},
+		"within limits": {
+			limits:  limits{pixels: 200, frames: 4, framePixels: 800},
+			payload: payload,
+		},
+		"too many pixels": {
+			limits:  limits{pixels: 199},
+			payload: payload,
+			wantErr: ErrLimitExceeded,
+		},
+		"too many frames": {
+			limits:  limits{frames: 3},
+			payload: payload,
+			wantErr: ErrLimitExceeded,
+		},
+		"too many frame pixels": {
+			limits:  limits{framePixels: 799},
+			payload: payload,
+			wantErr: ErrLimitExceeded,
+		},
+		"truncated": {
+			limits:  limits{frames: 10},
+			payload: payload[:len(payload)/2],
+			wantErr: errGifTruncated,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			output, err := testCase.limits.decodeGif(testCase.payload)
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("decodeGif() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if testCase.wantErr == nil && len(output.Image) != 4 {
+				t.Errorf("decodeGif() = %d frames, want 4", len(output.Image))
+			}
+		})
+	}
+}
+
+func TestDecodeImage(t *testing.T) {
+	t.Parallel()
+
+	payload := encodePng(t, 30, 20)
+
+	cases := map[string]struct {
+		limits  limits
+		payload []byte
+		wantErr error
+		invalid bool
+	}{
+		"no limit": {
+			limits:  limits{},
+			payload: payload,
+		},
+		"within limits": {
+			limits:  limits{pixels: 600},
+			payload: payload,
+		},
+		"too many pixels": {
+			limits:  limits{pixels: 599},
+			payload: payload,
+			wantErr: ErrLimitExceeded,
+		},
+		"jpeg": {
+			limits:  limits{pixels: 600},
+			payload: encodeJpeg(t, 30, 20),
+		},
+		"jpeg with too many pixels": {
+			limits:  limits{pixels: 599},
+			payload: encodeJpeg(t, 30, 20),
+			wantErr: ErrLimitExceeded,
+		},
+		"not an image": {
+			limits:  limits{pixels: 600},
+			payload: []byte("not an image"),
+			invalid: true,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			output, err := testCase.limits.decodeImage(testCase.payload)
+
+			switch {
+			case testCase.invalid:
+				if err == nil || errors.Is(err, ErrLimitExceeded) {
+					t.Errorf("decodeImage() = `%v`, want a decode error", err)
+				}
+			case !errors.Is(err, testCase.wantErr):
+				t.Errorf("decodeImage() = `%v`, want `%v`", err, testCase.wantErr)
+			case err == nil && output.Bounds().Dx() != 30:
+				t.Errorf("decodeImage() = %s, want 30 pixels wide", output.Bounds())
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		limits  limits
+		content string
+		wantErr error
+	}{
+		"no limit": {
+			limits:  limits{},
+			content: strings.Repeat("a", 100),
+		},
+		"at limit": {
+			limits:  limits{bytes: 100},
+			content: strings.Repeat("a", 100),
+		},
+		"over limit": {
+			limits:  limits{bytes: 99},
+			content: strings.Repeat("a", 100),
+			wantErr: ErrLimitExceeded,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			payload, err := testCase.limits.read(strings.NewReader(testCase.content))
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("read() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if err == nil && string(payload) != testCase.content {
+				t.Errorf("read() = %d bytes, want %d", len(payload), len(testCase.content))
+			}
+		})
+	}
+}
+
+func TestSourceCacheStore(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		limits  limits
+		content string
+		wantErr error
+	}{
+		"no limit": {
+			limits:  limits{},
+			content: strings.Repeat("a", 100),
+		},
+		"at limit": {
+			limits:  limits{bytes: 100},
+			content: strings.Repeat("a", 100),
+		},
+		"over limit": {
+			limits:  limits{bytes: 99},
+			content: strings.Repeat("a", 100),
+			wantErr: ErrLimitExceeded,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			cache := newSourceCache(fetch.Service{}, t.TempDir(), 1<<20, testCase.limits, nil)
+			filename := filepath.Join(cache.folder, "source.jpeg")
+
+			err := cache.store(filename, strings.NewReader(testCase.content))
+
+			var limitErr LimitError
+			if !errors.Is(err, testCase.wantErr) || (testCase.wantErr != nil && !errors.As(err, &limitErr)) {
+				t.Fatalf("store() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			entries, readErr := os.ReadDir(cache.folder)
+			if readErr != nil {
+				t.Fatalf("read folder: %s", readErr)
+			}
+
+			if testCase.wantErr != nil {
+				if len(entries) != 0 {
+					t.Errorf("store() left %d files, want none", len(entries))
+				}
+
+				return
+			}
+
+			if content, readErr := os.ReadFile(filename); readErr != nil || string(content) != testCase.content {
+				t.Errorf("store() = %d bytes stored (%v), want %d", len(content), readErr, len(testCase.content))
+			}
+		})
+	}
+}
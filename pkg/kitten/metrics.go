@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/ViBiOh/httputils/v4/pkg/model"
+	"go.opentelemetry.io/otel/metric"
 )
 
 func (s Service) increaseServed(ctx context.Context) {
@@ -21,3 +22,11 @@ func (s Service) increaseCached(ctx context.Context) {
 
 	s.cachedMetric.Add(ctx, 1)
 }
+
+func increase(ctx context.Context, counter metric.Int64Counter) {
+	if model.IsNil(counter) {
+		return
+	}
+
+	counter.Add(ctx, 1)
+}
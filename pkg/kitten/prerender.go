@@ -0,0 +1,167 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+type prerenderTask struct {
+	search string
+	next   string
+	spec   renderSpec
+}
+
+// key identifies the task, a pending one not being queued twice
+func (pt prerenderTask) key() string {
+	return pt.spec.key() + ":" + pt.search + ":" + pt.next
+}
+
+type prerenderer struct {
+	tasks   chan prerenderTask
+	done    chan struct{}
+	pending map[string]struct{}
+	workers int
+	mutex   sync.Mutex
+	closed  bool
+}
+
+func newPrerenderer(workers, queueSize int) *prerenderer {
+	if workers <= 0 {
+		return nil
+	}
+
+	return &prerenderer{
+		workers: workers,
+		tasks:   make(chan prerenderTask, queueSize),
+		done:    make(chan struct{}),
+		pending: make(map[string]struct{}),
+	}
+}
+
+func (p *prerenderer) enqueue(ctx context.Context, task prerenderTask) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	key := task.key()
+	if _, ok := p.pending[key]; ok {
+		return
+	}
+
+	select {
+	case p.tasks <- task:
+		p.pending[key] = struct{}{}
+	default:
+		slog.LogAttrs(ctx, slog.LevelDebug, "prerender queue is full", slog.String("id", task.spec.source.id), slog.String("next", task.next))
+	}
+}
+
+// release lets the task be queued again, once rendered
+func (p *prerenderer) release(task prerenderTask) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	delete(p.pending, task.key())
+}
+
+func (p *prerenderer) close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.closed = true
+	close(p.tasks)
+}
+
+// Start runs the background rendering workers and the renders pruning until the context is done, then drains the queue
+func (s Service) Start(ctx context.Context) {
+	if s.rendersTTL > 0 || s.rendersSize > 0 {
+		go s.pruneRendersPeriodically(ctx)
+	}
+
+	if s.prerender == nil {
+		return
+	}
+
+	defer close(s.prerender.done)
+
+	var wg sync.WaitGroup
+	workerCtx := context.WithoutCancel(ctx)
+
+	for range s.prerender.workers {
+		wg.Go(func() {
+			for task := range s.prerender.tasks {
+				s.runPrerender(workerCtx, task)
+				s.prerender.release(task)
+			}
+		})
+	}
+
+	<-ctx.Done()
+
+	s.prerender.close()
+	wg.Wait()
+}
+
+// Done is closed when background workers are stopped
+func (s Service) Done() <-chan struct{} {
+	if s.prerender == nil {
+		done := make(chan struct{})
+		close(done)
+
+		return done
+	}
+
+	return s.prerender.done
+}
+
+func (s Service) enqueueRender(ctx context.Context, spec renderSpec) {
+	s.prerender.enqueue(ctx, prerenderTask{spec: spec})
+}
+
+func (s Service) enqueueNext(ctx context.Context, provider, search, caption, next string) {
+	if !s.prerenderNext || len(next) == 0 {
+		return
+	}
+
+	s.prerender.enqueue(ctx, prerenderTask{spec: newRenderSpec(newProviderSource(gifKind, provider, ""), caption), search: search, next: next})
+}
+
+// runPrerender renders only on a free slot, dropping the task rather than delaying user-facing renders
+func (s Service) runPrerender(ctx context.Context, task prerenderTask) {
+	prerender := s
+	prerender.renders = s.renders.shedding()
+
+	if len(task.spec.source.id) == 0 {
+		provider, err := s.providers.provider(gifKind, task.spec.source.provider)
+		if err != nil {
+			slog.LogAttrs(ctx, slog.LevelWarn, "prefetch next gif", slog.String("search", task.search), slog.Any("error", err))
+			return
+		}
+
+		media, _, err := provider.Search(ctx, task.search, task.next)
+		if err != nil {
+			slog.LogAttrs(ctx, slog.LevelWarn, "prefetch next gif", slog.String("search", task.search), slog.Any("error", err))
+			return
+		}
+
+		task.spec.source = newMediaSource(gifKind, provider, media)
+	}
+
+	if _, _, err := prerender.generateAndStore(ctx, task.spec); err != nil {
+		if errors.Is(err, ErrOverloaded) {
+			slog.LogAttrs(ctx, slog.LevelDebug, "prerender dropped, no render slot", slog.String("id", task.spec.source.id))
+			return
+		}
+
+		slog.LogAttrs(ctx, slog.LevelError, "prerender meme", slog.String("id", task.spec.source.id), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,138 @@
+package kitten
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func nextTask(caption, next string) prerenderTask {
+	return prerenderTask{spec: newRenderSpec(newProviderSource(gifKind, "first", ""), caption), search: "cat", next: next}
+}
+
+func TestPrerendererEnqueue(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		tasks     []prerenderTask
+		queueSize int
+		closed    bool
+		want      int
+	}{
+		"queued": {
+			[]prerenderTask{nextTask("hello", "1"), nextTask("hello", "2")},
+			10,
+			false,
+			2,
+		},
+		"dedupe": {
+			[]prerenderTask{nextTask("hello", "1"), nextTask("hello", "1")},
+			10,
+			false,
+			1,
+		},
+		"distinct captions": {
+			[]prerenderTask{nextTask("hello", "1"), nextTask("world", "1")},
+			10,
+			false,
+			2,
+		},
+		"queue full": {
+			[]prerenderTask{nextTask("hello", "1"), nextTask("hello", "2"), nextTask("hello", "3")},
+			2,
+			false,
+			2,
+		},
+		"closed": {
+			[]prerenderTask{nextTask("hello", "1")},
+			10,
+			true,
+			0,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			instance := newPrerenderer(1, testCase.queueSize)
+			if testCase.closed {
+				instance.close()
+			}
+
+			for _, task := range testCase.tasks {
+				instance.enqueue(context.Background(), task)
+			}
+
+			if got := len(instance.tasks); got != testCase.want {
+				t.Errorf("enqueue() queued %d tasks, want %d", got, testCase.want)
+			}
+
+			if got := len(instance.pending); got != testCase.want {
+				t.Errorf("enqueue() has %d pending tasks, want %d", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestPrerenderDrain(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		nexts []string
+	}{
+		"empty": {
+			nil,
+		},
+		"pending tasks": {
+			[]string{"1", "2", "3"},
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			provider := &stubProvider{name: "first", err: errProviderDown}
+
+			instance := Service{
+				providers:     NewRegistry(nil, []string{"first"}).WithGif(provider),
+				prerender:     newPrerenderer(2, 10),
+				prerenderNext: true,
+			}
+
+			for _, next := range testCase.nexts {
+				instance.enqueueNext(context.Background(), "first", "cat", "hello", next)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			instance.Start(ctx)
+
+			select {
+			case <-instance.Done():
+			case <-time.After(time.Second):
+				t.Fatal("Done() is not closed after Start()")
+			}
+
+			got := provider.calls()
+			slices.Sort(got)
+
+			if !slices.Equal(got, testCase.nexts) {
+				t.Errorf("drained %v, want %v", got, testCase.nexts)
+			}
+
+			if len(instance.prerender.pending) != 0 {
+				t.Errorf("%d tasks are still pending", len(instance.prerender.pending))
+			}
+
+			instance.enqueueNext(context.Background(), "first", "cat", "hello", "4")
+
+			if len(instance.prerender.tasks) != 0 {
+				t.Error("enqueued a task after shutdown")
+			}
+		})
+	}
+}
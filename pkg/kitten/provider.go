@@ -0,0 +1,198 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ViBiOh/httputils/v4/pkg/model"
+)
+
+var (
+	// ErrNotFound is wrapped by providers when nothing matches the search or the id
+	ErrNotFound = errors.New("nothing found")
+	// ErrRateLimited is wrapped by providers when their API quota is exhausted
+	ErrRateLimited = errors.New("provider rate limited")
+	// ErrUnavailable is returned when no provider of the search can answer
+	ErrUnavailable = errors.New("no provider available")
+
+	errUnknownProvider = fmt.Errorf("%w: unknown provider", errInvalidRender)
+)
+
+// Media is an image or a gif found by a provider
+type Media struct {
+	Attribution Attribution
+	ID          string
+	URL         string
+}
+
+// Provider finds the medias to caption
+type Provider interface {
+	// Name identifies the provider in sources, cache keys and configuration
+	Name() string
+	// Search returns a media matching the query and the cursor of the next result, empty if there is none
+	Search(ctx context.Context, query, cursor string) (Media, string, error)
+	Get(ctx context.Context, id string) (Media, error)
+	// Report notifies the provider that the media has been shared for the query
+	Report(ctx context.Context, media Media, query string)
+}
+
+// CacheProvider is implemented by providers keeping medias in a cache managed by the admin API
+type CacheProvider interface {
+	Cached(ctx context.Context) ([]string, error)
+	Evict(ctx context.Context, ids ...string) error
+}
+
+// ContentProvider is implemented by providers reading the content of their medias themselves, instead of giving an URL to download
+type ContentProvider interface {
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+}
+
+// Registry holds the enabled providers of each kind, the first one being used by default
+type Registry struct {
+	enabled   map[memeKind][]string
+	providers map[memeKind][]Provider
+}
+
+// NewRegistry creates a registry enabling the named providers, in order of preference
+func NewRegistry(images, gifs []string) Registry {
+	return Registry{
+		enabled: map[memeKind][]string{
+			imageKind: images,
+			gifKind:   gifs,
+		},
+	}
+}
+
+// WithImage registers an image provider, ignored if not enabled
+func (r Registry) WithImage(provider Provider) Registry {
+	return r.with(imageKind, provider)
+}
+
+// WithGif registers a gif provider, ignored if not enabled
+func (r Registry) WithGif(provider Provider) Registry {
+	return r.with(gifKind, provider)
+}
+
+func (r Registry) with(kind memeKind, provider Provider) Registry {
+	if model.IsNil(provider) {
+		return r
+	}
+
+	enabled := r.enabled[kind]
+	if !slices.Contains(enabled, provider.Name()) {
+		return r
+	}
+
+	providers := maps.Clone(r.providers)
+	if providers == nil {
+		providers = make(map[memeKind][]Provider)
+	}
+
+	registered := slices.DeleteFunc(slices.Clone(providers[kind]), func(item Provider) bool {
+		return item.Name() == provider.Name()
+	})
+
+	registered = append(registered, provider)
+	slices.SortStableFunc(registered, func(a, b Provider) int {
+		return slices.Index(enabled, a.Name()) - slices.Index(enabled, b.Name())
+	})
+
+	providers[kind] = registered
+	r.providers = providers
+
+	return r
+}
+
+// provider returns the named provider of the given kind, the default one if the name is empty
+func (r Registry) provider(kind memeKind, name string) (Provider, error) {
+	providers := r.providers[kind]
+
+	if len(name) == 0 {
+		if len(providers) == 0 {
+			return nil, fmt.Errorf("%w: no %s provider", errUnknownProvider, kind)
+		}
+
+		return providers[0], nil
+	}
+
+	for _, provider := range providers {
+		if provider.Name() == name {
+			return provider, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no %s provider `%s`", errUnknownProvider, kind, name)
+}
+
+// resolve returns the provider targeted by a `name:query` search and the query, the default provider and the whole search otherwise
+func (r Registry) resolve(kind memeKind, search string) (Provider, string, error) {
+	providers, query, err := r.chain(kind, search)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return providers[0], query, nil
+}
+
+// chain returns the providers to ask in order for a search and the query: the one targeted by a `name:query` search, every provider of the kind otherwise
+func (r Registry) chain(kind memeKind, search string) ([]Provider, string, error) {
+	if name, query, ok := strings.Cut(search, ":"); ok {
+		if provider, err := r.provider(kind, strings.TrimSpace(name)); err == nil {
+			return []Provider{provider}, strings.TrimSpace(query), nil
+		}
+	}
+
+	providers := r.providers[kind]
+	if len(providers) == 0 {
+		return nil, "", fmt.Errorf("%w: no %s provider", errUnknownProvider, kind)
+	}
+
+	return providers, search, nil
+}
+
+// get returns the media with the given id from the named provider, looking into every kind served under that name
+func (r Registry) get(ctx context.Context, name, id string) (Provider, memeKind, Media, error) {
+	if len(name) == 0 {
+		return nil, unkownKind, Media{}, fmt.Errorf("%w: provider is required", errInvalidRender)
+	}
+
+	err := fmt.Errorf("%w: `%s`", errUnknownProvider, name)
+
+	for _, kind := range []memeKind{imageKind, gifKind} {
+		provider, providerErr := r.provider(kind, name)
+		if providerErr != nil {
+			continue
+		}
+
+		var media Media
+
+		if media, err = provider.Get(ctx, id); err == nil {
+			return provider, kind, media, nil
+		}
+
+		if !errors.Is(err, ErrNotFound) {
+			break
+		}
+	}
+
+	return nil, unkownKind, Media{}, err
+}
+
+// all returns every registered provider with its kind
+func (r Registry) all() iter.Seq2[memeKind, Provider] {
+	return func(yield func(memeKind, Provider) bool) {
+		for _, kind := range []memeKind{imageKind, gifKind} {
+			for _, provider := range r.providers[kind] {
+				if !yield(kind, provider) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package kitten
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"codeberg.org/ViBiOh/ChatPotte/discord"
+	"codeberg.org/ViBiOh/ChatPotte/slack"
+	"github.com/ViBiOh/kitten/pkg/fakeproviders"
+	"github.com/ViBiOh/kitten/pkg/fetch"
+	"github.com/ViBiOh/kitten/pkg/giphy"
+	"github.com/ViBiOh/kitten/pkg/klipy"
+	"github.com/ViBiOh/kitten/pkg/ratelimit"
+	"github.com/ViBiOh/kitten/pkg/unsplash"
+)
+
+// fakeSetup is a kitten served over HTTP, its providers pointing at the fake providers server
+type fakeSetup struct {
+	service Service
+	fake    *fakeproviders.Server
+	// limited holds the providers answering every call with a 429
+	limited map[string]*atomic.Bool
+	website string
+}
+
+func newFakeSetup(t *testing.T) fakeSetup {
+	t.Helper()
+
+	ctx := context.Background()
+
+	setup := fakeSetup{
+		fake: fakeproviders.New(),
+		limited: map[string]*atomic.Bool{
+			klipyName: {},
+			giphyName: {},
+		},
+	}
+
+	fakeHandler := setup.fake.Handler()
+	providersServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, limited := range setup.limited {
+			if limited.Load() && strings.HasPrefix(r.URL.Path, "/"+name+"/") {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		fakeHandler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(providersServer.Close)
+
+	fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+
+	unsplashConfig := unsplash.Flags(fs, "unsplash")
+	klipyConfig := klipy.Flags(fs, "klipy")
+	giphyConfig := giphy.Flags(fs, "giphy")
+	fetchConfig := fetch.Flags(fs, "fetch")
+
+	if err := fs.Parse([]string{
+		"-unsplashURL", providersServer.URL + "/unsplash",
+		"-unsplashAccessKey", "fake",
+		"-klipyURL", providersServer.URL + "/klipy",
+		"-klipyApiKey", "fake",
+		"-giphyURL", providersServer.URL + "/giphy",
+		"-giphyApiKey", "fake",
+		"-fetchAllowedSchemes", "http",
+		"-fetchAllowPrivate",
+	}); err != nil {
+		t.Fatalf("parse flags: %s", err)
+	}
+
+	config := Config{
+		TmpFolder:        t.TempDir(),
+		RenderWorkers:    2,
+		RenderQueue:      time.Second * 5,
+		RenderTimeout:    time.Second * 30,
+		MaxSourceSize:    4 << 20,
+		MaxPixels:        4096 * 4096,
+		MaxFrames:        300,
+		MaxFramePixels:   100_000_000,
+		MaxCaption:       280,
+		ProviderFailures: 3,
+		ProviderCooldown: time.Minute,
+		RecentResults:    10,
+		BatchWorkers:     1,
+	}
+
+	registry := NewRegistry([]string{unsplashName}, []string{klipyName, giphyName}).
+		WithImage(NewUnsplashProvider(unsplash.New(ctx, unsplashConfig, nil, nil))).
+		WithGif(NewKlipyProvider(klipy.New(ctx, klipyConfig, nil, nil))).
+		WithGif(NewGiphyProvider(giphy.New(ctx, giphyConfig, nil, nil)))
+
+	kittenServer := httptest.NewUnstartedServer(nil)
+	kittenServer.Start()
+	t.Cleanup(kittenServer.Close)
+
+	setup.website = kittenServer.URL
+	setup.service = New(&config, registry, ratelimit.Service{}, fetch.New(fetchConfig, nil, nil), nil, nil, nil, setup.website)
+
+	mux := http.NewServeMux()
+	mux.Handle("/search", setup.service.SearchHandler())
+	mux.Handle("/gif/{content...}", setup.service.GifHandler())
+	mux.Handle("/api/{content...}", setup.service.Handler())
+	kittenServer.Config.Handler = mux
+
+	return setup
+}
+
+func (fs fakeSetup) limit(name string, limited bool) {
+	fs.limited[name].Store(limited)
+}
+
+// get requests the kitten server and answers the status, the provider header and the format of the image received
+func (fs fakeSetup) get(t *testing.T, url string) (int, string, string) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("get `%s`: %s", url, err)
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, resp.Header.Get(providerHeader), ""
+	}
+
+	_, format, err := image.DecodeConfig(resp.Body)
+	if err != nil {
+		t.Errorf("decode `%s`: %s", url, err)
+	}
+
+	return resp.StatusCode, resp.Header.Get(providerHeader), format
+}
+
+// renders counts the stored renders with the given extension
+func (fs fakeSetup) renders(t *testing.T, extension string) int {
+	t.Helper()
+
+	entries, err := os.ReadDir(fs.service.rendersFolder)
+	if err != nil {
+		t.Fatalf("read renders: %s", err)
+	}
+
+	var count int
+
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == extension {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestFakeProvidersHTTP(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		kind         string
+		query        string
+		wantStatus   int
+		wantProvider string
+		wantFormat   string
+	}{
+		"image": {
+			"image",
+			"cat",
+			http.StatusOK,
+			unsplashName,
+			"jpeg",
+		},
+		"gif": {
+			"gif",
+			"cat",
+			http.StatusOK,
+			klipyName,
+			"gif",
+		},
+		"targeted provider": {
+			"gif",
+			"giphy:cat",
+			http.StatusOK,
+			giphyName,
+			"gif",
+		},
+		"image not found": {
+			"image",
+			fakeproviders.NotFoundQuery,
+			http.StatusNotFound,
+			"",
+			"",
+		},
+		"gif not found": {
+			"gif",
+			fakeproviders.NotFoundQuery,
+			http.StatusNotFound,
+			"",
+			"",
+		},
+		"image rate limited": {
+			"image",
+			fakeproviders.RateLimitQuery,
+			http.StatusServiceUnavailable,
+			"",
+			"",
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			// a rate limit opens the circuit of the provider for the other cases
+			setup := newFakeSetup(t)
+
+			status, provider, format := setup.get(t, setup.website+"/search?kind="+testCase.kind+"&caption=hello&query="+testCase.query)
+
+			if status != testCase.wantStatus {
+				t.Errorf("status = %d, want %d", status, testCase.wantStatus)
+			}
+
+			if provider != testCase.wantProvider {
+				t.Errorf("provider = `%s`, want `%s`", provider, testCase.wantProvider)
+			}
+
+			if format != testCase.wantFormat {
+				t.Errorf("format = `%s`, want `%s`", format, testCase.wantFormat)
+			}
+		})
+	}
+}
+
+func TestFakeProvidersErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		kind   memeKind
+		search string
+		want   error
+	}{
+		"image not found": {
+			imageKind,
+			fakeproviders.NotFoundQuery,
+			ErrNotFound,
+		},
+		"gif not found": {
+			gifKind,
+			fakeproviders.NotFoundQuery,
+			ErrNotFound,
+		},
+		"image rate limited": {
+			imageKind,
+			fakeproviders.RateLimitQuery,
+			ErrRateLimited,
+		},
+		"gif rate limited": {
+			gifKind,
+			"klipy:" + fakeproviders.RateLimitQuery,
+			ErrRateLimited,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			// a rate limit opens the circuit of the provider for the other cases
+			setup := newFakeSetup(t)
+
+			if _, err := setup.service.search(context.Background(), testCase.kind, testCase.search, ""); !errors.Is(err, testCase.want) {
+				t.Errorf("search() = `%v`, want `%v`", err, testCase.want)
+			}
+		})
+	}
+}
+
+func TestFakeProvidersFallback(t *testing.T) {
+	t.Parallel()
+
+	setup := newFakeSetup(t)
+	ctx := context.Background()
+
+	setup.limit(klipyName, true)
+
+	result, err := setup.service.search(ctx, gifKind, "cat", "")
+	if err != nil {
+		t.Fatalf("search() with klipy rate limited: %s", err)
+	}
+
+	if name := result.provider.Name(); name != giphyName || !result.fallback || result.search != "giphy:cat" {
+		t.Errorf("search() = `%s` `%s` fallback %t, want giphy fallback", name, result.search, result.fallback)
+	}
+
+	if setup.service.breaker.allow(klipyName) {
+		t.Error("klipy circuit is closed after a rate limit")
+	}
+
+	status, provider, format := setup.get(t, setup.website+"/search?kind=gif&caption=hello&query=cat")
+	if status != http.StatusOK || provider != giphyName || format != "gif" {
+		t.Errorf("/search = %d `%s` `%s`, want gif served by giphy", status, provider, format)
+	}
+
+	setup.limit(giphyName, true)
+
+	result, err = setup.service.search(ctx, gifKind, "cat", "")
+	if err != nil {
+		t.Fatalf("search() with every provider rate limited: %s", err)
+	}
+
+	if name := result.provider.Name(); name != giphyName || !result.fallback {
+		t.Errorf("search() = `%s` fallback %t, want the recent giphy result", name, result.fallback)
+	}
+
+	if _, err = setup.service.search(ctx, gifKind, "dog", ""); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("search() without recent result = `%v`, want `%v`", err, ErrUnavailable)
+	}
+
+	if status, _, _ = setup.get(t, setup.website+"/search?kind=gif&caption=hello&query=dog"); status != http.StatusServiceUnavailable {
+		t.Errorf("/search without recent result = %d, want %d", status, http.StatusServiceUnavailable)
+	}
+}
+
+func TestFakeProvidersSlack(t *testing.T) {
+	t.Parallel()
+
+	setup := newFakeSetup(t)
+
+	cases := map[string]struct {
+		command    string
+		text       string
+		want       string
+		wantFormat string
+	}{
+		"image": {
+			customImageCommand,
+			"hello |cat",
+			setup.website + "/api/",
+			"jpeg",
+		},
+		"gif": {
+			customGifSearch,
+			"hello |cat",
+			setup.website + "/gif/",
+			"gif",
+		},
+		"not found": {
+			customGifSearch,
+			"hello |" + fakeproviders.NotFoundQuery,
+			"No gif found",
+			"",
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			response := setup.service.SlackCommand(context.Background(), slack.SlashPayload{Command: testCase.command, Text: testCase.text, UserID: "U1", TeamID: "T1"})
+
+			payload, err := json.Marshal(response)
+			if err != nil {
+				t.Fatalf("marshal response: %s", err)
+			}
+
+			index := strings.Index(string(payload), testCase.want)
+			if index == -1 {
+				t.Fatalf("SlackCommand() = `%s`, want `%s`", payload, testCase.want)
+			}
+
+			if len(testCase.wantFormat) == 0 {
+				return
+			}
+
+			memeURL, _, _ := strings.Cut(string(payload[index:]), `"`)
+
+			if status, _, format := setup.get(t, memeURL); status != http.StatusOK || format != testCase.wantFormat {
+				t.Errorf("meme url = %d `%s`, want %s", status, format, testCase.wantFormat)
+			}
+		})
+	}
+}
+
+func TestFakeProvidersDiscord(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		kind      memeKind
+		command   string
+		extension string
+	}{
+		"image": {
+			imageKind,
+			customImageCommand,
+			".jpeg",
+		},
+		"gif": {
+			gifKind,
+			customGifSearch,
+			".gif",
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			setup := newFakeSetup(t)
+			ctx := context.Background()
+
+			result, err := setup.service.search(ctx, testCase.kind, "cat", "")
+			if err != nil {
+				t.Fatalf("search: %s", err)
+			}
+
+			_, _, callback := setup.service.DiscordHandler(ctx, discord.InteractionRequest{
+				Type: discord.ApplicationCommandInteraction,
+				Data: discord.InteractionData{
+					Name: testCase.command,
+					Options: []discord.CommandOption{
+						{Name: idParam, Value: result.media.ID},
+						{Name: searchParam, Value: result.search},
+						{Name: captionParam, Value: "hello"},
+					},
+				},
+			})
+
+			if callback == nil {
+				t.Fatal("DiscordHandler() has no callback")
+			}
+
+			callback(ctx)
+
+			if count := setup.renders(t, testCase.extension); count != 1 {
+				t.Errorf("renders = %d, want 1", count)
+			}
+		})
+	}
+}
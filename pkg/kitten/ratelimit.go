@@ -0,0 +1,25 @@
+package kitten
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ViBiOh/kitten/pkg/ratelimit"
+)
+
+func (s Service) allowCommand(ctx context.Context, platform, user, team string) (string, bool) {
+	for _, limit := range []struct {
+		scope ratelimit.Scope
+		id    string
+	}{{ratelimit.User, user}, {ratelimit.Team, team}} {
+		if len(limit.id) == 0 {
+			continue
+		}
+
+		if ok, retryAfter := s.rateLimiter.Allow(ctx, limit.scope, platform+":"+limit.id); !ok {
+			return fmt.Sprintf("Easy there, meme lord! Try again in %d seconds.", ratelimit.RetryAfterSeconds(retryAfter)), false
+		}
+	}
+
+	return "", true
+}
@@ -0,0 +1,246 @@
+package kitten
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/ViBiOh/httputils/v4/pkg/concurrent"
+	"github.com/ViBiOh/httputils/v4/pkg/telemetry"
+	"github.com/fogleman/gg"
+	"github.com/golang/freetype/truetype"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+)
+
+// Embedded files are hashed into the renderer version, any change of the fonts or of the rendering code invalidates cached renders
+//
+//go:embed fonts render.go gif.go gifstream.go
+var content embed.FS
+
+const (
+	fontSizeCoeff float64 = 0.07
+	widthPadding  float64 = 0.8
+	jpegQuality   int     = 80
+
+	topLayout    = "top"
+	bottomLayout = "bottom"
+	splitLayout  = "split"
+)
+
+type Style struct {
+	Font   string  `json:"font"`
+	Stroke float64 `json:"stroke"`
+}
+
+var (
+	defaultStyle = Style{Font: "impact", Stroke: 2}
+
+	fontFacesMutex sync.Mutex
+	fontFacesSizes = map[string]*sync.Pool{}
+)
+
+func loadFsFont(fontName string, points float64) (font.Face, error) {
+	fontBytes, err := content.ReadFile(fontName)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := truetype.Parse(fontBytes)
+	if err != nil {
+		return nil, err
+	}
+	face := truetype.NewFace(f, &truetype.Options{
+		Size: points,
+	})
+	return face, nil
+}
+
+func getFontFace(name string, size float64) (font.Face, func()) {
+	key := fmt.Sprintf("%s:%g", name, size)
+
+	fontFacesMutex.Lock()
+	pool, ok := fontFacesSizes[key]
+	if !ok {
+		pool = &sync.Pool{
+			New: func() any {
+				fontFace, err := loadFsFont(fmt.Sprintf("fonts/%s.ttf", name), size)
+				if err != nil {
+					slog.LogAttrs(context.Background(), slog.LevelError, "load font face", slog.String("font", name), slog.Any("error", err))
+				}
+
+				return fontFace
+			},
+		}
+
+		fontFacesSizes[key] = pool
+	}
+	fontFacesMutex.Unlock()
+
+	fontFace, ok := pool.Get().(font.Face)
+	if !ok {
+		return nil, func() {}
+	}
+
+	return fontFace, func() { pool.Put(fontFace) }
+}
+
+// CaptionImage add caption on an image
+func (s Service) CaptionImage(ctx context.Context, source image.Image, text string) (img image.Image, err error) {
+	return s.captionImage(ctx, source, text, defaultStyle, topLayout)
+}
+
+func (s Service) captionImage(ctx context.Context, source image.Image, text string, style Style, layout string) (img image.Image, err error) {
+	_, end := telemetry.StartSpan(ctx, s.tracer, "captionImage")
+	defer end(&err)
+
+	if err = s.limits.checkCaption(text); err != nil {
+		return nil, err
+	}
+
+	return s.caption(ctx, gg.NewContextForImage(source), text, style, layout)
+}
+
+func resizeImage(source image.Image, width int) image.Image {
+	bounds := source.Bounds()
+	if width <= 0 || width == bounds.Dx() {
+		return source
+	}
+
+	height := bounds.Dy() * width / bounds.Dx()
+	output := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(output, output.Bounds(), source, bounds, xdraw.Src, nil)
+
+	return output
+}
+
+// CaptionGif add caption on every frame of a gif
+func (s Service) CaptionGif(ctx context.Context, source *gif.GIF, text string) (*gif.GIF, error) {
+	return s.captionGif(ctx, source, text, defaultStyle, topLayout)
+}
+
+func (s Service) captionGif(ctx context.Context, source *gif.GIF, text string, style Style, layout string) (*gif.GIF, error) {
+	var err error
+
+	ctx, end := telemetry.StartSpan(ctx, s.tracer, "captionGif")
+	defer end(&err)
+
+	wg := concurrent.NewFailFast(8)
+
+	textImage, err := s.captionLayer(ctx, source.Config, text, style, layout)
+	if err != nil {
+		return source, err
+	}
+
+	for _, frame := range source.Image {
+		if ctx.Err() != nil {
+			break
+		}
+
+		maskedFrame := frame
+		wg.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			drawLayer(maskedFrame, textImage)
+			return nil
+		})
+	}
+
+	if err = wg.Wait(); err != nil {
+		return source, err
+	}
+
+	if err = ctx.Err(); err != nil {
+		return source, err
+	}
+
+	return source, nil
+}
+
+func (s Service) captionLayer(ctx context.Context, config image.Config, text string, style Style, layout string) (image.Image, error) {
+	if err := s.limits.checkCaption(text); err != nil {
+		return nil, err
+	}
+
+	textImage, err := s.caption(ctx, gg.NewContext(config.Width, config.Height), text, style, layout)
+	if err != nil {
+		return nil, fmt.Errorf("generate text layer: %w", err)
+	}
+
+	return textImage, nil
+}
+
+func drawLayer(frame draw.Image, layer image.Image) {
+	bounds := layer.Bounds()
+	draw.DrawMask(frame, bounds, layer, bounds.Min, layer, bounds.Min, draw.Over)
+}
+
+func (s Service) caption(ctx context.Context, imageCtx *gg.Context, text string, style Style, layout string) (image.Image, error) {
+	fontSize := float64(imageCtx.Width()) * fontSizeCoeff
+	fontFace, resolve := getFontFace(style.Font, fontSize)
+	defer resolve()
+
+	if fontFace == nil {
+		return nil, fmt.Errorf("unknown font `%s`", style.Font)
+	}
+
+	imageCtx.SetFontFace(fontFace)
+
+	var top, bottom string
+
+	switch layout {
+	case bottomLayout:
+		bottom = text
+	case splitLayout:
+		top, bottom, _ = strings.Cut(text, "\n")
+	default:
+		top = text
+	}
+
+	maxWidth := float64(imageCtx.Width()) * widthPadding
+
+	if err := drawLines(ctx, imageCtx, imageCtx.WordWrap(strings.ToUpper(top), maxWidth), fontSize/2, fontSize, style.Stroke); err != nil {
+		return nil, err
+	}
+
+	if len(bottom) != 0 {
+		lines := imageCtx.WordWrap(strings.ToUpper(bottom), maxWidth)
+		if err := drawLines(ctx, imageCtx, lines, float64(imageCtx.Height())-fontSize/2-fontSize*float64(len(lines)+1), fontSize, style.Stroke); err != nil {
+			return nil, err
+		}
+	}
+
+	return imageCtx.Image(), nil
+}
+
+func drawLines(ctx context.Context, imageCtx *gg.Context, lines []string, yAnchor, fontSize, n float64) error {
+	xAnchor := float64(imageCtx.Width() / 2)
+
+	for _, lineString := range lines {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		yAnchor += fontSize
+
+		imageCtx.SetRGBA(0, 0, 0, 1)
+		for dy := -n; dy <= n; dy++ {
+			for dx := -n; dx <= n; dx++ {
+				imageCtx.DrawStringAnchored(lineString, xAnchor+dx, yAnchor+dy, 0.5, 0.5)
+			}
+		}
+
+		imageCtx.SetRGBA(1, 1, 1, 1)
+		imageCtx.DrawStringAnchored(lineString, xAnchor, yAnchor, 0.5, 0.5)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/ViBiOh/httputils/v4/pkg/hash"
+	"github.com/ViBiOh/httputils/v4/pkg/httperror"
+	"github.com/ViBiOh/httputils/v4/pkg/model"
+	"github.com/ViBiOh/kitten/pkg/version"
+)
+
+const shortIDLength = 12
+
+var errShortLinkDisabled = errors.New("short links are disabled")
+
+func (s Service) shortLinkEnabled() bool {
+	return s.shortLinkTTL > 0 && !model.IsNil(s.redisClient) && s.redisClient.Enabled()
+}
+
+func (s Service) getShortURL(ctx context.Context, kind memeKind, id, search, caption string) (string, error) {
+	if !s.shortLinkEnabled() {
+		return "", errShortLinkDisabled
+	}
+
+	values := url.Values{}
+	values.Set("kind", string(kind))
+	values.Set(idParam, id)
+	values.Set(searchParam, search)
+	values.Set(captionParam, caption)
+
+	content := values.Encode()
+	shortID := hash.String(content)[:shortIDLength]
+
+	if err := s.redisClient.Store(ctx, shortLinkKey(shortID), content, s.shortLinkTTL); err != nil {
+		return "", fmt.Errorf("store short link: %w", err)
+	}
+
+	return fmt.Sprintf("%s/m/%s", s.website, shortID), nil
+}
+
+// ShortLinkHandler serves memes from their short identifier
+func (s Service) ShortLinkHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isReadMethod(r.Method) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+
+		if !s.shortLinkEnabled() {
+			httperror.NotFound(ctx, w, errShortLinkDisabled)
+			return
+		}
+
+		key := shortLinkKey(r.PathValue("id"))
+
+		values, err := s.loadShortLink(ctx, key)
+		if err != nil {
+			httperror.InternalServerError(ctx, w, err)
+			return
+		}
+
+		if values == nil {
+			http.Redirect(w, r, s.website+"/", http.StatusFound)
+			return
+		}
+
+		if s.serveSharePage(w, r) {
+			return
+		}
+
+		go func(ctx context.Context) {
+			if err := s.redisClient.Expire(ctx, s.shortLinkTTL, key); err != nil {
+				slog.LogAttrs(ctx, slog.LevelWarn, "extend short link", slog.Any("error", err))
+			}
+		}(context.WithoutCancel(ctx))
+
+		switch parseKind(values.Get("kind")) {
+		case gifKind:
+			s.serveGif(w, r, values.Get(idParam), values.Get(searchParam), values.Get(captionParam))
+		default:
+			s.serveMeme(w, r, values.Get(idParam), values.Get(searchParam), values.Get(captionParam))
+		}
+	})
+}
+
+func (s Service) loadShortLink(ctx context.Context, key string) (url.Values, error) {
+	content, err := s.redisClient.Load(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("load short link: %w", err)
+	}
+
+	if len(content) == 0 {
+		return nil, nil
+	}
+
+	values, err := url.ParseQuery(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse short link: %w", err)
+	}
+
+	return values, nil
+}
+
+func shortLinkKey(shortID string) string {
+	return version.Redis("short:" + shortID)
+}
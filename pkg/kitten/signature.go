@@ -0,0 +1,149 @@
+package kitten
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	signatureParam = "signature"
+	expiresParam   = "expires"
+
+	// previewTTL is the validity of the previews signed for the web editor
+	previewTTL = time.Hour
+)
+
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpiredSignature = errors.New("expired signature")
+)
+
+func (s Service) getContent(id, search, caption string) string {
+	content := getSignedPayload(id, search, caption, "")
+
+	if len(s.signatureSecret) != 0 {
+		if s.signatureTTL > 0 {
+			expires := strconv.FormatInt(time.Now().Add(s.signatureTTL).Unix(), 10)
+			content = getSignedPayload(id, search, caption, expires)
+		}
+
+		content += fmt.Sprintf("&%s=%s", signatureParam, s.sign(content))
+	}
+
+	return base64.URLEncoding.EncodeToString([]byte(content))
+}
+
+func (s Service) verifySignature(query url.Values) error {
+	if len(s.signatureSecret) == 0 {
+		return nil
+	}
+
+	if len(query.Get(signatureParam)) == 0 && s.signatureGrace {
+		return nil
+	}
+
+	return s.verify(query, getSignedPayload(query.Get(idParam), query.Get(searchParam), query.Get(captionParam), query.Get(expiresParam)))
+}
+
+// verifyPreview checks the preview renders the source and caption signed by the editor, the style being free to change
+func (s Service) verifyPreview(query url.Values) error {
+	if len(s.signatureSecret) == 0 {
+		return nil
+	}
+
+	if len(query.Get(expiresParam)) == 0 {
+		return ErrMissingSignature
+	}
+
+	return s.verify(query, getPreviewPayload(query.Get("kind"), query.Get("provider"), query.Get(idParam), query.Get(captionParam), query.Get(expiresParam)))
+}
+
+func (s Service) verify(query url.Values, payload string) error {
+	signature := query.Get(signatureParam)
+	if len(signature) == 0 {
+		return ErrMissingSignature
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(s.sign(payload))) {
+		return ErrInvalidSignature
+	}
+
+	if expires := query.Get(expiresParam); len(expires) != 0 {
+		timestamp, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse expiration: %w", err)
+		}
+
+		if time.Now().Unix() > timestamp {
+			return ErrExpiredSignature
+		}
+	}
+
+	return nil
+}
+
+// Signed checks if the request carries a valid signature, allowing shared URLs without authentication
+func (s Service) Signed(r *http.Request) bool {
+	if len(s.signatureSecret) == 0 {
+		return false
+	}
+
+	query, err := getQuery(r)
+	if err != nil || len(query.Get(signatureParam)) == 0 {
+		return false
+	}
+
+	return s.verifySignature(query) == nil
+}
+
+// PreviewSigned checks if the preview request carries a valid signature from the web editor
+func (s Service) PreviewSigned(r *http.Request) bool {
+	if len(s.signatureSecret) == 0 {
+		return false
+	}
+
+	query := r.URL.Query()
+
+	return len(query.Get(signatureParam)) != 0 && s.verifyPreview(query) == nil
+}
+
+// signPreview adds the signature of the preview source and caption to the values, always expiring so it can't be used as a permanent link
+func (s Service) signPreview(values url.Values) {
+	if len(s.signatureSecret) == 0 {
+		return
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(previewTTL).Unix(), 10)
+	values.Set(expiresParam, expires)
+
+	values.Set(signatureParam, s.sign(getPreviewPayload(values.Get("kind"), values.Get("provider"), values.Get(idParam), values.Get(captionParam), expires)))
+}
+
+func (s Service) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(s.signatureSecret))
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func getSignedPayload(id, search, caption, expires string) string {
+	payload := fmt.Sprintf("%s=%s&%s=%s&%s=%s", idParam, url.QueryEscape(id), searchParam, url.QueryEscape(search), captionParam, url.QueryEscape(caption))
+
+	if len(expires) != 0 {
+		payload += fmt.Sprintf("&%s=%s", expiresParam, expires)
+	}
+
+	return payload
+}
+
+func getPreviewPayload(kind, provider, id, caption, expires string) string {
+	return fmt.Sprintf("kind=%s&provider=%s&%s=%s&%s=%s&%s=%s", url.QueryEscape(kind), url.QueryEscape(provider), idParam, url.QueryEscape(id), captionParam, url.QueryEscape(caption), expiresParam, expires)
+}
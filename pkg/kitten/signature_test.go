@@ -0,0 +1,264 @@
+package kitten
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	t.Parallel()
+
+	signer := Service{signatureSecret: "secret"}
+
+	signed := func(service Service, id, search, caption string) url.Values {
+		t.Helper()
+
+		query, err := decodeQuery(service.getContent(id, search, caption))
+		if err != nil {
+			t.Fatalf("decode content: %s", err)
+		}
+
+		return query
+	}
+
+	withExpires := func(expires time.Time) url.Values {
+		payload := getSignedPayload("abc", "cat", "hello", strconv.FormatInt(expires.Unix(), 10))
+
+		query, err := url.ParseQuery(payload + "&" + signatureParam + "=" + signer.sign(payload))
+		if err != nil {
+			t.Fatalf("parse payload: %s", err)
+		}
+
+		return query
+	}
+
+	tampered := func(query url.Values, key, value string) url.Values {
+		output := url.Values{}
+		for name, values := range query {
+			output[name] = append([]string(nil), values...)
+		}
+
+		output.Set(key, value)
+
+		return output
+	}
+
+	cases := map[string]struct {
+		service Service
+		query   url.Values
+		want    error
+	}{
+		"no secret": {
+			Service{},
+			url.Values{idParam: {"abc"}, captionParam: {"hello"}},
+			nil,
+		},
+		"valid": {
+			signer,
+			signed(signer, "abc", "cat", "hello"),
+			nil,
+		},
+		"valid with ttl": {
+			Service{signatureSecret: "secret", signatureTTL: time.Hour},
+			signed(Service{signatureSecret: "secret", signatureTTL: time.Hour}, "abc", "cat", "hello & bye"),
+			nil,
+		},
+		"not expired": {
+			signer,
+			withExpires(time.Now().Add(time.Minute)),
+			nil,
+		},
+		"expired": {
+			signer,
+			withExpires(time.Now().Add(-time.Minute)),
+			ErrExpiredSignature,
+		},
+		"tampered caption": {
+			signer,
+			tampered(signed(signer, "abc", "cat", "hello"), captionParam, "bye"),
+			ErrInvalidSignature,
+		},
+		"tampered id": {
+			signer,
+			tampered(signed(signer, "abc", "cat", "hello"), idParam, "def"),
+			ErrInvalidSignature,
+		},
+		"extended expiration": {
+			signer,
+			tampered(withExpires(time.Now().Add(-time.Minute)), expiresParam, strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)),
+			ErrInvalidSignature,
+		},
+		"other secret": {
+			Service{signatureSecret: "other"},
+			signed(signer, "abc", "cat", "hello"),
+			ErrInvalidSignature,
+		},
+		"missing": {
+			signer,
+			url.Values{idParam: {"abc"}, captionParam: {"hello"}},
+			ErrMissingSignature,
+		},
+		"missing with grace": {
+			Service{signatureSecret: "secret", signatureGrace: true},
+			url.Values{idParam: {"abc"}, captionParam: {"hello"}},
+			nil,
+		},
+		"invalid with grace": {
+			Service{signatureSecret: "secret", signatureGrace: true},
+			tampered(signed(signer, "abc", "cat", "hello"), captionParam, "bye"),
+			ErrInvalidSignature,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			if got := testCase.service.verifySignature(testCase.query); !errors.Is(got, testCase.want) {
+				t.Errorf("verifySignature() = `%v`, want `%v`", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		secret  string
+		payload string
+		other   string
+		same    bool
+	}{
+		"deterministic": {
+			"secret",
+			"id=abc&caption=hello",
+			"secret",
+			true,
+		},
+		"secret dependent": {
+			"secret",
+			"id=abc&caption=hello",
+			"other",
+			false,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			first := Service{signatureSecret: testCase.secret}.sign(testCase.payload)
+			second := Service{signatureSecret: testCase.other}.sign(testCase.payload)
+
+			if (first == second) != testCase.same {
+				t.Errorf("sign() = `%s` and `%s`, same want %t", first, second, testCase.same)
+			}
+
+			if _, err := url.ParseQuery(signatureParam + "=" + first); err != nil {
+				t.Errorf("sign() = `%s`, not usable in a query: %s", first, err)
+			}
+		})
+	}
+}
+
+func TestVerifyPreview(t *testing.T) {
+	t.Parallel()
+
+	signer := Service{signatureSecret: "secret"}
+
+	preview := func(mutate func(url.Values)) url.Values {
+		values := Editor{Kind: "image", Provider: "unsplash", ID: "abc", Caption: "hello", Layout: topLayout, Font: "impact", Stroke: 4}.previewValues()
+		signer.signPreview(values)
+
+		if mutate != nil {
+			mutate(values)
+		}
+
+		return values
+	}
+
+	cases := map[string]struct {
+		service Service
+		query   url.Values
+		want    error
+	}{
+		"no secret": {
+			Service{},
+			url.Values{idParam: {"abc"}},
+			nil,
+		},
+		"valid": {
+			signer,
+			preview(nil),
+			nil,
+		},
+		"caption changed": {
+			signer,
+			preview(func(values url.Values) { values.Set(captionParam, "bye") }),
+			ErrInvalidSignature,
+		},
+		"style changed": {
+			signer,
+			preview(func(values url.Values) { values.Set("layout", splitLayout) }),
+			nil,
+		},
+		"id changed": {
+			signer,
+			preview(func(values url.Values) { values.Set(idParam, "def") }),
+			ErrInvalidSignature,
+		},
+		"provider changed": {
+			signer,
+			preview(func(values url.Values) { values.Set("provider", "local") }),
+			ErrInvalidSignature,
+		},
+		"extended expiration": {
+			signer,
+			preview(func(values url.Values) {
+				values.Set(expiresParam, strconv.FormatInt(time.Now().Add(time.Hour*24).Unix(), 10))
+			}),
+			ErrInvalidSignature,
+		},
+		"expired": {
+			signer,
+			func() url.Values {
+				values := url.Values{"kind": {"image"}, "provider": {"unsplash"}, idParam: {"abc"}, captionParam: {"hello"}}
+				expires := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+				values.Set(expiresParam, expires)
+				values.Set(signatureParam, signer.sign(getPreviewPayload("image", "unsplash", "abc", "hello", expires)))
+
+				return values
+			}(),
+			ErrExpiredSignature,
+		},
+		"without expiration": {
+			signer,
+			preview(func(values url.Values) { values.Del(expiresParam) }),
+			ErrMissingSignature,
+		},
+		"missing": {
+			signer,
+			preview(func(values url.Values) { values.Del(signatureParam) }),
+			ErrMissingSignature,
+		},
+		"missing with grace": {
+			Service{signatureSecret: "secret", signatureGrace: true},
+			preview(func(values url.Values) { values.Del(signatureParam) }),
+			ErrMissingSignature,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			if got := testCase.service.verifyPreview(testCase.query); !errors.Is(got, testCase.want) {
+				t.Errorf("verifyPreview() = `%v`, want `%v`", got, testCase.want)
+			}
+		})
+	}
+}
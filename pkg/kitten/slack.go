@@ -2,15 +2,13 @@ package kitten
 
 import (
 	"context"
-	"encoding/base64"
+	"errors"
 	"fmt"
-	"net/url"
+	"log/slog"
 	"regexp"
 	"strings"
 
 	"codeberg.org/ViBiOh/ChatPotte/slack"
-	"github.com/ViBiOh/kitten/pkg/klipy"
-	"github.com/ViBiOh/kitten/pkg/unsplash"
 )
 
 type memeKind string
@@ -54,6 +52,14 @@ func (s Service) SlackCommand(ctx context.Context, payload slack.SlashPayload) s
 		return slack.NewEphemeralMessage("You must provide a caption")
 	}
 
+	if err := s.limits.checkCaption(payload.Text); err != nil {
+		return slack.NewEphemeralMessage(fmt.Sprintf("Your caption is too long, keep it under %d characters", s.limits.caption))
+	}
+
+	if message, ok := s.allowCommand(ctx, "slack", payload.UserID, payload.TeamID); !ok {
+		return slack.NewEphemeralMessage(message)
+	}
+
 	var kind memeKind
 	switch payload.Command {
 	case customGifSearch:
@@ -73,7 +79,7 @@ func (s Service) getKittenBlock(ctx context.Context, kind memeKind, user, search
 		initialSearch := search
 
 		var err error
-		search, err = sanitizeValue(matches[1])
+		search, err = s.sanitizeSearch(kind, matches[1])
 		if err != nil {
 			return slack.NewError(fmt.Errorf("sanitize value `%s`: %w", matches[1], err))
 		}
@@ -90,47 +96,33 @@ func (s Service) getKittenBlock(ctx context.Context, kind memeKind, user, search
 		return slack.NewEphemeralMessage("You must provide a query for image in the form `my caption value |searched_query`")
 	}
 
-	var id string
-
-	switch kind {
-	case gifKind:
-		image, nextValue, err := s.klipyService.Search(ctx, search, next)
-
-		switch err {
-		case nil:
-			id = image.ID
-			next = nextValue
-		case klipy.ErrNotFound:
-			return slack.NewEphemeralMessage("No gif found")
-		default:
-			return slack.NewEphemeralMessage(fmt.Sprintf("Oh! It's broken 😱. Reason is: %s", err))
-		}
-
+	result, err := s.search(ctx, kind, search, next)
+
+	switch {
+	case err == nil:
+		s.enqueueRender(ctx, newRenderSpec(newMediaSource(kind, result.provider, result.media), caption))
+		s.enqueueNext(ctx, result.provider.Name(), result.query, caption, result.next)
+	case errors.Is(err, errUnknownProvider):
+		return slack.NewEphemeralMessage(fmt.Sprintf("Sorry, we don't serve %s memes for now.", kind))
+	case errors.Is(err, ErrNotFound):
+		return slack.NewEphemeralMessage(fmt.Sprintf("No %s found", kind))
+	case errors.Is(err, ErrUnavailable):
+		return slack.NewEphemeralMessage(fmt.Sprintf("No %s provider is available right now, try again in a few minutes.", kind))
 	default:
-		image, err := s.unsplashService.Search(ctx, search)
-		if err != nil {
-			return slack.NewEphemeralMessage(fmt.Sprintf("Oh! It's broken 😱. Reason is: %s", err))
-		}
-		id = image.ID
+		return slack.NewEphemeralMessage(fmt.Sprintf("Oh! It's broken 😱. Reason is: %s", err))
 	}
 
-	return s.getSlackInteractResponse(kind, user, id, search, caption, next, yolo)
+	return s.getSlackInteractResponse(ctx, kind, user, result.media, result.search, caption, result.next, yolo)
 }
 
-func (s Service) getSlackInteractResponse(kind memeKind, user, id, search, caption, next string, yolo bool) slack.Response {
-	var accessory slack.Image
-	switch kind {
-	case gifKind:
-		accessory = s.getGifContent(id, search, caption)
-	default:
-		accessory = s.getMemeContent(id, search, caption)
-	}
+func (s Service) getSlackInteractResponse(ctx context.Context, kind memeKind, user string, media Media, search, caption, next string, yolo bool) slack.Response {
+	accessory := s.getSlackContent(ctx, kind, media.ID, search, caption)
 
 	if yolo {
 		return slack.Response{
 			ResponseType: "in_channel",
 			Blocks: []slack.Block{
-				getSlackHeadline(user),
+				getSlackHeadline(user, media.Attribution),
 				accessory,
 			},
 		}
@@ -145,7 +137,7 @@ func (s Service) getSlackInteractResponse(kind memeKind, user, id, search, capti
 				search,
 				cancelButton,
 				slack.NewButtonElement("Another?", nextValue, fmt.Sprintf("%s:%s:%s", kind, caption, next), ""),
-				slack.NewButtonElement("Send", sendValue, fmt.Sprintf("%s:%s:%s: ", kind, id, caption), "primary"),
+				slack.NewButtonElement("Send", sendValue, fmt.Sprintf("%s:%s:%s: ", kind, media.ID, caption), "primary"),
 			),
 		},
 	}
@@ -162,27 +154,27 @@ func (s Service) SlackInteract(ctx context.Context, payload slack.InteractivePay
 		return slack.NewEphemeralMessage("Ok, not now.")
 	}
 
+	if message, ok := s.allowCommand(ctx, "slack", payload.User.ID, payload.Team.ID); !ok {
+		return slack.NewEphemeralMessage(message)
+	}
+
 	if action.ActionID == sendValue {
 		kind, id, caption, _ := parseValue(action.Value)
+		if kind != imageKind && kind != gifKind {
+			return slack.NewEphemeralMessage("Sorry, we don't that kind of meme.")
+		}
 
-		switch kind {
-		case imageKind:
-			image, err := s.unsplashService.Get(ctx, id)
-			if err != nil {
-				return slack.NewError(err)
-			}
-
-			return s.getSlackImageResponse(image, action.BlockID, caption, payload.User.ID)
-		case gifKind:
-			image, err := s.klipyService.Get(ctx, id)
-			if err != nil {
-				return slack.NewError(err)
-			}
+		provider, _, err := s.providers.resolve(kind, action.BlockID)
+		if err != nil {
+			return slack.NewError(err)
+		}
 
-			return s.getSlackGifReponse(image, action.BlockID, caption, payload.User.ID)
-		default:
-			return slack.NewEphemeralMessage("Sorry, we don't that kind of meme.")
+		media, err := provider.Get(ctx, id)
+		if err != nil {
+			return slack.NewError(err)
 		}
+
+		return s.getSlackSendResponse(ctx, kind, media, action.BlockID, caption, payload.User.ID)
 	}
 
 	if action.ActionID == nextValue {
@@ -193,45 +185,69 @@ func (s Service) SlackInteract(ctx context.Context, payload slack.InteractivePay
 	return slack.NewEphemeralMessage("We don't understand the action to perform.")
 }
 
-func (s Service) getSlackImageResponse(image unsplash.Image, search, caption, user string) slack.Response {
+func (s Service) getSlackSendResponse(ctx context.Context, kind memeKind, media Media, search, caption, user string) slack.Response {
 	return slack.Response{
 		ResponseType:   "in_channel",
 		DeleteOriginal: true,
 		Blocks: []slack.Block{
-			slack.NewContext().AddElement(slack.NewText(fmt.Sprintf("Triggered By <@%s>", user))).AddElement(slack.NewText(fmt.Sprintf("Image By <%s|%s>", image.AuthorURL, image.Author))).AddElement(slack.NewText(fmt.Sprintf("Powered By <%s|Unsplash>", image.URL))),
-			s.getMemeContent(image.ID, search, caption),
+			getSlackHeadline(user, media.Attribution),
+			s.getSlackContent(ctx, kind, media.ID, search, caption),
 		},
 	}
 }
 
-func (s Service) getSlackGifReponse(image klipy.ResponseObject, search, caption, user string) slack.Response {
-	return slack.Response{
-		ResponseType:   "in_channel",
-		DeleteOriginal: true,
-		Blocks: []slack.Block{
-			getSlackHeadline(user),
-			s.getGifContent(image.ID, search, caption),
-		},
+// sanitizeSearch cleans the query of a search, keeping its `provider:` prefix if any
+func (s Service) sanitizeSearch(kind memeKind, search string) (string, error) {
+	if name, query, ok := strings.Cut(search, ":"); ok {
+		if provider, err := s.providers.provider(kind, strings.ToLower(strings.TrimSpace(name))); err == nil {
+			query, err = sanitizeValue(strings.TrimSpace(query))
+			if err != nil {
+				return "", err
+			}
+
+			return provider.Name() + ":" + query, nil
+		}
 	}
+
+	return sanitizeValue(search)
 }
 
-func getSlackHeadline(user string) slack.Context {
+func getSlackHeadline(user string, attribution Attribution) slack.Context {
 	slackCtx := slack.NewContext().AddElement(slack.NewText(fmt.Sprintf("Triggered By <@%s>", user)))
-	slackCtx = slackCtx.AddElement(slack.NewText("Powered By *Klipy*"))
 
-	return slackCtx
-}
+	if len(attribution.AuthorURL) != 0 {
+		slackCtx = slackCtx.AddElement(slack.NewText(fmt.Sprintf("Image By <%s|%s>", attribution.AuthorURL, attribution.Author)))
+	} else if len(attribution.Author) != 0 {
+		slackCtx = slackCtx.AddElement(slack.NewText(fmt.Sprintf("Image By %s", attribution.Author)))
+	}
 
-func (s Service) getMemeContent(id, search, caption string) slack.Image {
-	return slack.NewImage(fmt.Sprintf("%s/api/%s", s.website, getContent(id, search, caption)), fmt.Sprintf("image with caption `%s` on it", caption), search)
+	if len(attribution.URL) != 0 {
+		return slackCtx.AddElement(slack.NewText(fmt.Sprintf("Powered By <%s|%s>", attribution.URL, attribution.Provider)))
+	}
+
+	return slackCtx.AddElement(slack.NewText(fmt.Sprintf("Powered By *%s*", attribution.Provider)))
 }
 
-func (s Service) getGifContent(id, search, caption string) slack.Image {
-	return slack.NewImage(fmt.Sprintf("%s/gif/%s", s.website, getContent(id, search, caption)), fmt.Sprintf("gif with caption `%s` on it", caption), search)
+func (s Service) getSlackContent(ctx context.Context, kind memeKind, id, search, caption string) slack.Image {
+	return slack.NewImage(s.getMemeURL(ctx, kind, id, search, caption), fmt.Sprintf("%s with caption `%s` on it", kind, caption), search)
 }
 
-func getContent(id, search, caption string) string {
-	return base64.URLEncoding.EncodeToString(fmt.Appendf(nil, "id=%s&search=%s&caption=%s", url.QueryEscape(id), url.QueryEscape(search), url.QueryEscape(caption)))
+func (s Service) getMemeURL(ctx context.Context, kind memeKind, id, search, caption string) string {
+	shortURL, err := s.getShortURL(ctx, kind, id, search, caption)
+	if err == nil {
+		return shortURL
+	}
+
+	if !errors.Is(err, errShortLinkDisabled) {
+		slog.LogAttrs(ctx, slog.LevelError, "create short link", slog.Any("error", err))
+	}
+
+	path := "api"
+	if kind == gifKind {
+		path = "gif"
+	}
+
+	return fmt.Sprintf("%s/%s/%s", s.website, path, s.getContent(id, search, caption))
 }
 
 func parseValue(value string) (memeKind, string, string, string) {
@@ -0,0 +1,291 @@
+package kitten
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ViBiOh/httputils/v4/pkg/hash"
+	"github.com/ViBiOh/kitten/pkg/fetch"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	jpegFormat = "jpeg"
+	pngFormat  = "png"
+	gifFormat  = "gif"
+
+	tmpSuffix = ".tmp"
+)
+
+type source struct {
+	open     func(context.Context) (io.ReadCloser, error)
+	provider string
+	id       string
+	url      string
+	format   string
+}
+
+func newMediaSource(kind memeKind, provider Provider, media Media) source {
+	output := source{provider: provider.Name(), id: media.ID, url: media.URL, format: kindFormat(kind)}
+
+	if contentProvider, ok := provider.(ContentProvider); ok {
+		output.open = func(ctx context.Context) (io.ReadCloser, error) {
+			return contentProvider.Open(ctx, media.ID)
+		}
+	}
+
+	return output
+}
+
+// newProviderSource identifies a media not fetched yet, enough to look for its render in cache
+func newProviderSource(kind memeKind, provider, id string) source {
+	return source{provider: provider, id: id, format: kindFormat(kind)}
+}
+
+func newURLSource(url, format string) source {
+	return source{url: url, format: format}
+}
+
+func kindFormat(kind memeKind) string {
+	if kind == gifKind {
+		return gifFormat
+	}
+
+	return jpegFormat
+}
+
+func (s source) cacheable() bool {
+	return len(s.provider) != 0 && len(s.id) != 0
+}
+
+func (s source) key() string {
+	if s.cacheable() {
+		return s.provider + ":" + s.id
+	}
+
+	return s.url
+}
+
+type sourceCache struct {
+	mutex       *sync.Mutex
+	hitMetric   metric.Int64Counter
+	missMetric  metric.Int64Counter
+	evictMetric metric.Int64Counter
+	fetcher     fetch.Service
+	folder      string
+	limits      limits
+	maxSize     int64
+}
+
+func newSourceCache(fetcher fetch.Service, folder string, maxSize int64, limits limits, meter metric.Meter) sourceCache {
+	cache := sourceCache{
+		mutex:   &sync.Mutex{},
+		fetcher: fetcher,
+		folder:  folder,
+		limits:  limits,
+		maxSize: maxSize,
+	}
+
+	if meter != nil {
+		var err error
+
+		cache.hitMetric, err = meter.Int64Counter("kitten.source_cache_hit")
+		if err != nil {
+			slog.LogAttrs(context.Background(), slog.LevelError, "create source hit counter", slog.Any("error", err))
+		}
+
+		cache.missMetric, err = meter.Int64Counter("kitten.source_cache_miss")
+		if err != nil {
+			slog.LogAttrs(context.Background(), slog.LevelError, "create source miss counter", slog.Any("error", err))
+		}
+
+		cache.evictMetric, err = meter.Int64Counter("kitten.source_cache_evicted")
+		if err != nil {
+			slog.LogAttrs(context.Background(), slog.LevelError, "create source evicted counter", slog.Any("error", err))
+		}
+	}
+
+	return cache
+}
+
+func (sc sourceCache) enabled() bool {
+	return sc.maxSize > 0 && len(sc.folder) != 0
+}
+
+func (sc sourceCache) filename(src source) string {
+	return filepath.Join(sc.folder, hash.String(src.key()+":"+src.format)+"."+src.format)
+}
+
+func (sc sourceCache) open(ctx context.Context, src source) (io.ReadCloser, error) {
+	if src.open != nil {
+		return src.open(ctx)
+	}
+
+	if !sc.enabled() || !src.cacheable() {
+		return sc.fetcher.Get(ctx, src.url)
+	}
+
+	filename := sc.filename(src)
+
+	file, err := os.Open(filename)
+	if err == nil {
+		now := time.Now()
+		if err = os.Chtimes(filename, now, now); err != nil {
+			slog.LogAttrs(ctx, slog.LevelWarn, "touch source in cache", slog.Any("error", err))
+		}
+
+		increase(ctx, sc.hitMetric)
+
+		return file, nil
+	}
+
+	if !os.IsNotExist(err) {
+		slog.LogAttrs(ctx, slog.LevelError, "open source from cache", slog.Any("error", err))
+	}
+
+	increase(ctx, sc.missMetric)
+
+	reader, err := sc.fetcher.Get(ctx, src.url)
+	if err != nil {
+		return nil, err
+	}
+
+	err = sc.store(filename, reader)
+
+	if closeErr := reader.Close(); closeErr != nil {
+		slog.LogAttrs(ctx, slog.LevelWarn, "close source body", slog.Any("error", closeErr))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("store source: %w", err)
+	}
+
+	// opened before evicting, for a source larger than the whole cache to be read before its removal
+	file, err = os.Open(filename)
+
+	go sc.evict(context.WithoutCancel(ctx))
+
+	return file, err
+}
+
+func (sc sourceCache) store(filename string, reader io.Reader) error {
+	if err := os.MkdirAll(sc.folder, 0o700); err != nil {
+		return fmt.Errorf("create folder: %w", err)
+	}
+
+	if sc.limits.bytes > 0 {
+		reader = io.LimitReader(reader, sc.limits.bytes+1)
+	}
+
+	// the temporary file is removed when the source exceeds the limit, never reaching the cache
+	return writeCacheFile(filename, func(writer io.Writer) error {
+		written, err := io.Copy(writer, reader)
+		if err != nil {
+			return err
+		}
+
+		return checkLimit("source size", written, sc.limits.bytes)
+	})
+}
+
+func (sc sourceCache) evict(ctx context.Context) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	infos, size, err := sc.list()
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "list source cache", slog.Any("error", err))
+		return
+	}
+
+	if size <= sc.maxSize {
+		return
+	}
+
+	slices.SortFunc(infos, func(a, b os.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	})
+
+	for _, info := range infos {
+		if size <= sc.maxSize {
+			return
+		}
+
+		if err := os.Remove(filepath.Join(sc.folder, info.Name())); err != nil && !os.IsNotExist(err) {
+			slog.LogAttrs(ctx, slog.LevelError, "evict source from cache", slog.Any("error", err))
+			continue
+		}
+
+		size -= info.Size()
+		increase(ctx, sc.evictMetric)
+	}
+}
+
+func (sc sourceCache) purge() (int, error) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	infos, _, err := sc.list()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+
+	for _, info := range infos {
+		if err := os.Remove(filepath.Join(sc.folder, info.Name())); err != nil && !os.IsNotExist(err) {
+			return count, fmt.Errorf("remove source: %w", err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func (sc sourceCache) usage() (CacheUsage, error) {
+	infos, size, err := sc.list()
+	if err != nil {
+		return CacheUsage{}, err
+	}
+
+	return CacheUsage{Count: len(infos), Size: size, Limit: sc.maxSize}, nil
+}
+
+func (sc sourceCache) list() ([]os.FileInfo, int64, error) {
+	entries, err := os.ReadDir(sc.folder)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+
+		return nil, 0, fmt.Errorf("list sources: %w", err)
+	}
+
+	var size int64
+	infos := make([]os.FileInfo, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), tmpSuffix) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		size += info.Size()
+		infos = append(infos, info)
+	}
+
+	return infos, size, nil
+}
@@ -0,0 +1,270 @@
+package kitten
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ViBiOh/kitten/pkg/fetch"
+)
+
+// newTestSourceCache caches the sources served by a local server, counting how many times they are fetched
+func newTestSourceCache(t *testing.T, maxSize, maxBytes int64) (sourceCache, string, *atomic.Int32) {
+	t.Helper()
+
+	var fetches atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+
+		_, _ = w.Write(sourceContent(r.URL.Path[1:]))
+	}))
+	t.Cleanup(server.Close)
+
+	fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	fetchConfig := fetch.Flags(fs, "fetch")
+
+	if err := fs.Parse([]string{"-fetchAllowedSchemes", "http", "-fetchAllowPrivate"}); err != nil {
+		t.Fatalf("parse flags: %s", err)
+	}
+
+	return newSourceCache(fetch.New(fetchConfig, nil, nil), t.TempDir(), maxSize, limits{bytes: maxBytes}, nil), server.URL, &fetches
+}
+
+// sourceContent is a 100 bytes payload, sniffed as a jpeg
+func sourceContent(id string) []byte {
+	return append([]byte("\xff\xd8\xff"), bytes.Repeat([]byte(id), 97)...)
+}
+
+func readSource(t *testing.T, cache sourceCache, src source) ([]byte, error) {
+	t.Helper()
+
+	reader, err := cache.open(context.Background(), src)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if closeErr := reader.Close(); closeErr != nil {
+			t.Errorf("close source: %s", closeErr)
+		}
+	}()
+
+	return io.ReadAll(reader)
+}
+
+func TestSourceCacheOpen(t *testing.T) {
+	t.Parallel()
+
+	provided := func(context.Context) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader([]byte("provided"))), nil
+	}
+
+	cases := map[string]struct {
+		maxSize     int64
+		maxBytes    int64
+		sources     func(string) []source
+		wantFetches int32
+		wantFiles   int
+		wantErr     error
+	}{
+		"miss then hit": {
+			maxSize:  1000,
+			maxBytes: 1000,
+			sources: func(url string) []source {
+				return []source{
+					{provider: "unsplash", id: "a", url: url + "/a", format: jpegFormat},
+					{provider: "unsplash", id: "a", url: url + "/a", format: jpegFormat},
+				}
+			},
+			wantFetches: 1,
+			wantFiles:   1,
+		},
+		"other format": {
+			maxSize:  1000,
+			maxBytes: 1000,
+			sources: func(url string) []source {
+				return []source{
+					{provider: "unsplash", id: "a", url: url + "/a", format: jpegFormat},
+					{provider: "unsplash", id: "a", url: url + "/a", format: gifFormat},
+				}
+			},
+			wantFetches: 2,
+			wantFiles:   2,
+		},
+		"disabled": {
+			maxBytes: 1000,
+			sources: func(url string) []source {
+				return []source{
+					{provider: "unsplash", id: "a", url: url + "/a", format: jpegFormat},
+					{provider: "unsplash", id: "a", url: url + "/a", format: jpegFormat},
+				}
+			},
+			wantFetches: 2,
+		},
+		"url source": {
+			maxSize:  1000,
+			maxBytes: 1000,
+			sources: func(url string) []source {
+				return []source{newURLSource(url+"/a", jpegFormat), newURLSource(url+"/a", jpegFormat)}
+			},
+			wantFetches: 2,
+		},
+		"provider content": {
+			maxSize:  1000,
+			maxBytes: 1000,
+			sources: func(url string) []source {
+				return []source{{provider: libraryName, id: "a", format: jpegFormat, open: provided}}
+			},
+		},
+		"over the size limit": {
+			maxSize:  1000,
+			maxBytes: 50,
+			sources: func(url string) []source {
+				return []source{{provider: "unsplash", id: "a", url: url + "/a", format: jpegFormat}}
+			},
+			wantFetches: 1,
+			wantErr:     ErrLimitExceeded,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			cache, url, fetches := newTestSourceCache(t, testCase.maxSize, testCase.maxBytes)
+
+			for _, src := range testCase.sources(url) {
+				content, err := readSource(t, cache, src)
+				if !errors.Is(err, testCase.wantErr) {
+					t.Fatalf("open() = `%v`, want `%v`", err, testCase.wantErr)
+				}
+
+				if err != nil {
+					continue
+				}
+
+				if src.open == nil && !bytes.Equal(content, sourceContent("a")) {
+					t.Errorf("open() = `%s`, want the served content", content)
+				}
+			}
+
+			if got := fetches.Load(); got != testCase.wantFetches {
+				t.Errorf("fetched %d times, want %d", got, testCase.wantFetches)
+			}
+
+			entries, err := os.ReadDir(cache.folder)
+			if err != nil && !os.IsNotExist(err) {
+				t.Fatalf("read cache: %s", err)
+			}
+
+			if len(entries) != testCase.wantFiles {
+				t.Errorf("cached %d files, want %d", len(entries), testCase.wantFiles)
+			}
+		})
+	}
+}
+
+func TestSourceCacheEvict(t *testing.T) {
+	t.Parallel()
+
+	type step struct {
+		id      string
+		wantHit bool
+	}
+
+	cases := map[string]struct {
+		maxSize int64
+		steps   []step
+		want    []string
+	}{
+		"within budget": {
+			300,
+			[]step{{"a", false}, {"b", false}, {"c", false}},
+			[]string{"a", "b", "c"},
+		},
+		"oldest evicted": {
+			250,
+			[]step{{"a", false}, {"b", false}, {"c", false}},
+			[]string{"b", "c"},
+		},
+		"hit refreshes": {
+			250,
+			[]step{{"a", false}, {"b", false}, {"a", true}, {"c", false}},
+			[]string{"a", "c"},
+		},
+		"evicted source fetched again": {
+			150,
+			[]step{{"a", false}, {"b", false}, {"a", false}},
+			[]string{"a"},
+		},
+		"larger than the budget": {
+			50,
+			[]step{{"a", false}, {"b", false}},
+			nil,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			cache, url, fetches := newTestSourceCache(t, testCase.maxSize, 1000)
+
+			sources := make(map[string]source)
+			for _, id := range []string{"a", "b", "c"} {
+				sources[id] = source{provider: "unsplash", id: id, url: url + "/" + id, format: jpegFormat}
+			}
+
+			for _, item := range testCase.steps {
+				before := fetches.Load()
+
+				if _, err := readSource(t, cache, sources[item.id]); err != nil {
+					t.Fatalf("open(`%s`) = `%s`", item.id, err)
+				}
+
+				if hit := fetches.Load() == before; hit != item.wantHit {
+					t.Errorf("open(`%s`) hit = %t, want %t", item.id, hit, item.wantHit)
+				}
+
+				// the eviction runs in background, it is run again for the outcome not to depend on scheduling
+				cache.evict(context.Background())
+
+				// every step happens a minute after the previous one, whatever the precision of modification times
+				infos, _, err := cache.list()
+				if err != nil {
+					t.Fatalf("list() = `%s`", err)
+				}
+
+				for _, info := range infos {
+					modTime := info.ModTime().Add(-time.Minute)
+					if err = os.Chtimes(filepath.Join(cache.folder, info.Name()), modTime, modTime); err != nil && !os.IsNotExist(err) {
+						t.Fatalf("age source: %s", err)
+					}
+				}
+			}
+
+			var got []string
+
+			for _, id := range []string{"a", "b", "c"} {
+				if _, err := os.Stat(cache.filename(sources[id])); err == nil {
+					got = append(got, id)
+				}
+			}
+
+			if !slices.Equal(got, testCase.want) {
+				t.Errorf("cache kept %v, want %v", got, testCase.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package kitten
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"log/slog"
+	"strings"
+
+	"github.com/ViBiOh/httputils/v4/pkg/hash"
+	"github.com/ViBiOh/kitten/pkg/version"
+)
+
+// rendererVersion identifies the renderer output, derived from the embedded fonts and rendering code
+var rendererVersion = computeRendererVersion()
+
+type renderSpec struct {
+	source  source
+	caption string
+	version string
+	format  string
+	layout  string
+	style   Style
+	quality int
+	width   int
+}
+
+func newRenderSpec(from source, caption string) renderSpec {
+	return renderSpec{
+		source:  from,
+		caption: caption,
+		style:   defaultStyle,
+		format:  from.format,
+		layout:  topLayout,
+		quality: jpegQuality,
+		version: rendererVersion,
+	}
+}
+
+func (rs renderSpec) key() string {
+	return hash.String(fmt.Sprintf("%s:%s:%s:%s:%s:%s:%g:%d:%d:%s", version.CacheVersion, rs.version, rs.source.key(), rs.format, rs.layout, rs.style.Font, rs.style.Stroke, rs.quality, rs.width, rs.caption))
+}
+
+func (rs renderSpec) encode(writer io.Writer, output image.Image) error {
+	if rs.format == pngFormat {
+		return png.Encode(writer, output)
+	}
+
+	return jpeg.Encode(writer, output, &jpeg.Options{Quality: rs.quality})
+}
+
+func (rs renderSpec) kind() memeKind {
+	if rs.format == gifFormat {
+		return gifKind
+	}
+
+	return imageKind
+}
+
+func computeRendererVersion() string {
+	var builder strings.Builder
+
+	if err := fs.WalkDir(content, ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		fileContent, err := content.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		builder.WriteString(path)
+		builder.Write(fileContent)
+
+		return nil
+	}); err != nil {
+		slog.LogAttrs(context.Background(), slog.LevelError, "compute renderer version", slog.Any("error", err))
+	}
+
+	return hash.String(builder.String())[:8]
+}
@@ -0,0 +1,106 @@
+package kitten
+
+import (
+	"testing"
+)
+
+func TestSpecKey(t *testing.T) {
+	t.Parallel()
+
+	base := func() renderSpec {
+		return newRenderSpec(newProviderSource(imageKind, "unsplash", "abc"), "hello")
+	}
+
+	cases := map[string]struct {
+		mutate   func(*renderSpec)
+		wantSame bool
+	}{
+		"equal": {
+			func(*renderSpec) {},
+			true,
+		},
+		"source provider": {
+			func(spec *renderSpec) { spec.source.provider = "giphy" },
+			false,
+		},
+		"source id": {
+			func(spec *renderSpec) { spec.source.id = "def" },
+			false,
+		},
+		"source url": {
+			func(spec *renderSpec) { spec.source = newURLSource("https://example.com/cat.jpeg", jpegFormat) },
+			false,
+		},
+		"caption": {
+			func(spec *renderSpec) { spec.caption = "world" },
+			false,
+		},
+		"version": {
+			func(spec *renderSpec) { spec.version = "other" },
+			false,
+		},
+		"format": {
+			func(spec *renderSpec) { spec.format = pngFormat },
+			false,
+		},
+		"layout": {
+			func(spec *renderSpec) { spec.layout = bottomLayout },
+			false,
+		},
+		"font": {
+			func(spec *renderSpec) { spec.style.Font = "other" },
+			false,
+		},
+		"stroke": {
+			func(spec *renderSpec) { spec.style.Stroke++ },
+			false,
+		},
+		"quality": {
+			func(spec *renderSpec) { spec.quality-- },
+			false,
+		},
+		"width": {
+			func(spec *renderSpec) { spec.width = 320 },
+			false,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			spec := base()
+			testCase.mutate(&spec)
+
+			if got := spec.key() == base().key(); got != testCase.wantSame {
+				t.Errorf("key() equal = %t, want %t", got, testCase.wantSame)
+			}
+		})
+	}
+}
+
+func TestComputeRendererVersion(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		filename string
+	}{
+		"render":     {"render.go"},
+		"gif":        {"gif.go"},
+		"gif stream": {"gifstream.go"},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := content.ReadFile(testCase.filename); err != nil {
+				t.Errorf("`%s` is not hashed into the renderer version: %s", testCase.filename, err)
+			}
+
+			if got := computeRendererVersion(); got != rendererVersion {
+				t.Errorf("computeRendererVersion() = `%s`, want the stable `%s`", got, rendererVersion)
+			}
+		})
+	}
+}
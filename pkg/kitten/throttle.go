@@ -0,0 +1,142 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ViBiOh/httputils/v4/pkg/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrOverloaded is returned when no render slot frees up in time
+var ErrOverloaded = errors.New("too many renders in progress")
+
+type throttle struct {
+	slots        chan struct{}
+	queueMetric  metric.Float64Histogram
+	renderMetric metric.Float64Histogram
+	queueTimeout time.Duration
+	timeout      time.Duration
+}
+
+func newThrottle(concurrency int, queueTimeout, timeout time.Duration, meter metric.Meter) throttle {
+	output := throttle{
+		queueTimeout: queueTimeout,
+		timeout:      timeout,
+	}
+
+	if concurrency > 0 {
+		output.slots = make(chan struct{}, concurrency)
+	}
+
+	if meter != nil {
+		var err error
+
+		output.queueMetric, err = meter.Float64Histogram("kitten.render_queue_time", metric.WithUnit("s"), metric.WithDescription("Time spent waiting for a render slot"))
+		if err != nil {
+			slog.LogAttrs(context.Background(), slog.LevelError, "create render queue histogram", slog.Any("error", err))
+		}
+
+		output.renderMetric, err = meter.Float64Histogram("kitten.render_time", metric.WithUnit("s"), metric.WithDescription("Time spent rendering a meme"))
+		if err != nil {
+			slog.LogAttrs(context.Background(), slog.LevelError, "create render time histogram", slog.Any("error", err))
+		}
+	}
+
+	return output
+}
+
+// run executes the render once a slot is acquired, within the render deadline
+func (t throttle) run(ctx context.Context, render func(context.Context) error) error {
+	release, err := t.acquire(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+
+	err = render(ctx)
+
+	record(ctx, t.renderMetric, time.Since(start), outcome(err))
+
+	return err
+}
+
+// shedding returns a throttle sharing the same slots but never waiting for one, for work that can be dropped
+func (t throttle) shedding() throttle {
+	t.queueTimeout = 0
+
+	return t
+}
+
+func (t throttle) acquire(ctx context.Context) (func(), error) {
+	if t.slots == nil {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	release := func() { <-t.slots }
+
+	select {
+	case t.slots <- struct{}{}:
+		record(ctx, t.queueMetric, time.Since(start), "acquired")
+		return release, nil
+	default:
+	}
+
+	if t.queueTimeout <= 0 {
+		record(ctx, t.queueMetric, time.Since(start), "shed")
+		return nil, ErrOverloaded
+	}
+
+	timer := time.NewTimer(t.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case t.slots <- struct{}{}:
+		record(ctx, t.queueMetric, time.Since(start), "acquired")
+		return release, nil
+
+	case <-timer.C:
+		record(ctx, t.queueMetric, time.Since(start), "shed")
+		return nil, ErrOverloaded
+
+	case <-ctx.Done():
+		record(ctx, t.queueMetric, time.Since(start), "canceled")
+		return nil, fmt.Errorf("wait for render slot: %w", ctx.Err())
+	}
+}
+
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+func record(ctx context.Context, histogram metric.Float64Histogram, duration time.Duration, outcome string) {
+	if model.IsNil(histogram) {
+		return
+	}
+
+	histogram.Record(context.WithoutCancel(ctx), duration.Seconds(), metric.WithAttributes(attribute.String("outcome", outcome)))
+}
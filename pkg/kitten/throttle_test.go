@@ -0,0 +1,282 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestThrottleRun(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		concurrency  int
+		held         int
+		queueTimeout time.Duration
+		timeout      time.Duration
+		freeAfter    time.Duration
+		shedding     bool
+		canceled     bool
+		wantErr      error
+		wantRendered bool
+	}{
+		"unlimited": {
+			wantRendered: true,
+		},
+		"free slot": {
+			concurrency:  2,
+			held:         1,
+			wantRendered: true,
+		},
+		"queue timeout": {
+			concurrency:  1,
+			held:         1,
+			queueTimeout: time.Millisecond * 20,
+			wantErr:      ErrOverloaded,
+		},
+		"no queue": {
+			concurrency: 1,
+			held:        1,
+			wantErr:     ErrOverloaded,
+		},
+		"shedding": {
+			concurrency:  1,
+			held:         1,
+			queueTimeout: time.Minute,
+			shedding:     true,
+			wantErr:      ErrOverloaded,
+		},
+		"slot freed while queued": {
+			concurrency:  1,
+			held:         1,
+			queueTimeout: time.Minute,
+			freeAfter:    time.Millisecond * 20,
+			wantRendered: true,
+		},
+		"canceled while queued": {
+			concurrency:  1,
+			held:         1,
+			queueTimeout: time.Minute,
+			canceled:     true,
+			wantErr:      context.Canceled,
+		},
+		"render deadline": {
+			concurrency:  1,
+			timeout:      time.Millisecond * 20,
+			wantErr:      context.DeadlineExceeded,
+			wantRendered: true,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			instance := newThrottle(testCase.concurrency, testCase.queueTimeout, testCase.timeout, nil)
+
+			for range testCase.held {
+				release, err := instance.acquire(context.Background())
+				if err != nil {
+					t.Fatalf("acquire() = `%s`", err)
+				}
+
+				if testCase.freeAfter > 0 {
+					time.AfterFunc(testCase.freeAfter, release)
+				} else {
+					t.Cleanup(release)
+				}
+			}
+
+			if testCase.shedding {
+				instance = instance.shedding()
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if testCase.canceled {
+				time.AfterFunc(time.Millisecond*20, cancel)
+			}
+
+			var rendered bool
+
+			err := instance.run(ctx, func(ctx context.Context) error {
+				rendered = true
+
+				if testCase.timeout > 0 {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+
+				return nil
+			})
+
+			if !errors.Is(err, testCase.wantErr) {
+				t.Errorf("run() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if rendered != testCase.wantRendered {
+				t.Errorf("rendered = %t, want %t", rendered, testCase.wantRendered)
+			}
+
+			if got := len(instance.slots); got != testCase.held && testCase.freeAfter == 0 {
+				t.Errorf("%d slots held after run, want %d", got, testCase.held)
+			}
+		})
+	}
+}
+
+func TestRenderErrorStatus(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		err            error
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		"overloaded": {
+			ErrOverloaded,
+			http.StatusServiceUnavailable,
+			"1",
+		},
+		"wrapped overloaded": {
+			errors.Join(errors.New("render"), ErrOverloaded),
+			http.StatusServiceUnavailable,
+			"1",
+		},
+		"render deadline": {
+			context.DeadlineExceeded,
+			http.StatusServiceUnavailable,
+			"1",
+		},
+		"invalid": {
+			errInvalidRender,
+			http.StatusBadRequest,
+			"",
+		},
+		"not found": {
+			ErrNotFound,
+			http.StatusNotFound,
+			"",
+		},
+		"other": {
+			errProviderDown,
+			http.StatusInternalServerError,
+			"",
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			if got := RenderErrorStatus(testCase.err); got != testCase.wantStatus {
+				t.Errorf("RenderErrorStatus() = %d, want %d", got, testCase.wantStatus)
+			}
+
+			writer := httptest.NewRecorder()
+			handleRenderError(context.Background(), writer, testCase.err)
+
+			if writer.Code != testCase.wantStatus {
+				t.Errorf("handleRenderError() = %d, want %d", writer.Code, testCase.wantStatus)
+			}
+
+			if got := writer.Header().Get("Retry-After"); got != testCase.wantRetryAfter {
+				t.Errorf("Retry-After = `%s`, want `%s`", got, testCase.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestFetchOutsideSlot(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		contentType string
+		format      string
+		generate    func(Service, context.Context, renderSpec) error
+	}{
+		"image": {
+			"image/jpeg",
+			jpegFormat,
+			func(instance Service, ctx context.Context, spec renderSpec) error {
+				_, err := instance.generateImage(ctx, spec)
+				return err
+			},
+		},
+		"gif": {
+			"image/gif",
+			gifFormat,
+			func(instance Service, ctx context.Context, spec renderSpec) error {
+				_, err := instance.generateGif(ctx, spec)
+				return err
+			},
+		},
+		"streamed gif": {
+			"image/gif",
+			gifFormat,
+			func(instance Service, ctx context.Context, spec renderSpec) error {
+				return instance.encodeGif(ctx, spec, io.Discard, noFlush)
+			},
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			payload := encodeJpeg(t, 32, 32)
+			if testCase.format == gifFormat {
+				payload = encodeGif(t, 2, 32, 32)
+			}
+
+			fetched := make(chan struct{}, 1)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", testCase.contentType)
+				_, _ = w.Write(payload)
+
+				fetched <- struct{}{}
+			}))
+			t.Cleanup(server.Close)
+
+			instance := newFakeSetup(t).service
+
+			var releases []func()
+
+			for range cap(instance.renders.slots) {
+				release, err := instance.renders.acquire(context.Background())
+				if err != nil {
+					t.Fatalf("acquire() = `%s`", err)
+				}
+
+				releases = append(releases, release)
+			}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- testCase.generate(instance, context.Background(), newRenderSpec(newURLSource(server.URL+"/source", testCase.format), "hello"))
+			}()
+
+			select {
+			case <-fetched:
+			case err := <-done:
+				t.Fatalf("generate() = `%v` before fetching the source", err)
+			case <-time.After(time.Second * 2):
+				t.Fatal("source not fetched while every render slot is busy")
+			}
+
+			for _, release := range releases {
+				release()
+			}
+
+			if err := <-done; err != nil {
+				t.Errorf("generate() = `%s`", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package kitten
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/ViBiOh/kitten/pkg/unsplash"
+)
+
+const unsplashName = "unsplash"
+
+type unsplashProvider struct {
+	service unsplash.Service
+}
+
+// NewUnsplashProvider serves Unsplash photos as images
+func NewUnsplashProvider(service unsplash.Service) Provider {
+	return unsplashProvider{service: service}
+}
+
+func (up unsplashProvider) Name() string {
+	return unsplashName
+}
+
+// Search picks a random photo, Unsplash has no cursor
+func (up unsplashProvider) Search(ctx context.Context, query, _ string) (Media, string, error) {
+	image, err := up.service.Search(ctx, query)
+	if err != nil {
+		return Media{}, "", unsplashError(err)
+	}
+
+	return newUnsplashMedia(image), "", nil
+}
+
+func (up unsplashProvider) Get(ctx context.Context, id string) (Media, error) {
+	image, err := up.service.Get(ctx, id)
+	if err != nil {
+		return Media{}, unsplashError(err)
+	}
+
+	return newUnsplashMedia(image), nil
+}
+
+func (up unsplashProvider) Report(ctx context.Context, media Media, _ string) {
+	image, err := up.service.Get(ctx, media.ID)
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "get image for download report", slog.String("id", media.ID), slog.Any("error", err))
+		return
+	}
+
+	up.service.SendDownload(ctx, image)
+}
+
+func (up unsplashProvider) Cached(ctx context.Context) ([]string, error) {
+	return up.service.Cached(ctx)
+}
+
+func (up unsplashProvider) Evict(ctx context.Context, ids ...string) error {
+	return up.service.Evict(ctx, ids...)
+}
+
+func newUnsplashMedia(image unsplash.Image) Media {
+	return Media{
+		ID:  image.ID,
+		URL: image.Raw,
+		Attribution: Attribution{
+			Provider:  "Unsplash",
+			URL:       image.URL,
+			Author:    image.Author,
+			AuthorURL: image.AuthorURL,
+		},
+	}
+}
+
+func unsplashError(err error) error {
+	switch {
+	case errors.Is(err, unsplash.ErrNotFound):
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case errors.Is(err, unsplash.ErrRateLimitExceeded):
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,260 @@
+package kitten
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ViBiOh/httputils/v4/pkg/hash"
+	"github.com/ViBiOh/httputils/v4/pkg/httperror"
+	"github.com/ViBiOh/httputils/v4/pkg/httpjson"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	uploadProvider = "upload"
+	uploadField    = "file"
+
+	maxUploadMemory = 32 << 20
+	// maxUploadSize bounds the request body whatever the configured limits, even disabled ones
+	maxUploadSize int64 = 64 << 20
+)
+
+// UploadHandler captions an uploaded image or gif and answers the shareable URL of the render
+func (s Service) UploadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx := r.Context()
+
+		payload, options, err := s.readUpload(w, r)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) || errors.Is(err, ErrLimitExceeded) {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			httperror.BadRequest(ctx, w, err)
+			return
+		}
+
+		from, err := newUploadSource(payload)
+		if err != nil {
+			handleRenderError(ctx, w, err)
+			return
+		}
+
+		request, err := parseRenderOptions(options)
+		if err != nil {
+			handleRenderError(ctx, w, err)
+			return
+		}
+
+		spec, err := newRenderSpecFromRequest(from, request)
+		if err != nil {
+			handleRenderError(ctx, w, err)
+			return
+		}
+
+		filename, size, err := s.renderUpload(ctx, spec, payload)
+		if err != nil {
+			handleRenderError(ctx, w, err)
+			return
+		}
+
+		metadata, err := s.getRenderMetadata(filename, spec, Attribution{})
+		if err != nil {
+			httperror.InternalServerError(ctx, w, err)
+			return
+		}
+
+		metadata.Size = size
+
+		httpjson.Write(ctx, w, http.StatusCreated, metadata)
+	})
+}
+
+func (s Service) readUpload(w http.ResponseWriter, r *http.Request) ([]byte, url.Values, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, uploadBodyLimit(s.limits.bytes))
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		payload, err := s.limits.read(r.Body)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return payload, r.URL.Query(), nil
+	}
+
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		return nil, nil, fmt.Errorf("parse multipart form: %w", err)
+	}
+
+	file, _, err := r.FormFile(uploadField)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get `%s` field: %w", uploadField, err)
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			slog.LogAttrs(r.Context(), slog.LevelWarn, "close uploaded file", slog.Any("error", closeErr))
+		}
+	}()
+
+	payload, err := s.limits.read(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return payload, r.Form, nil
+}
+
+// uploadBodyLimit leaves room for the multipart envelope around a source of the configured size, never above maxUploadSize
+func uploadBodyLimit(sourceSize int64) int64 {
+	if sourceSize <= 0 || sourceSize > maxUploadSize-maxRenderRequestSize {
+		return maxUploadSize
+	}
+
+	return sourceSize + maxRenderRequestSize
+}
+
+func newUploadSource(payload []byte) (source, error) {
+	_, name, err := image.DecodeConfig(bytes.NewReader(payload))
+	if err != nil {
+		return source{}, fmt.Errorf("%w: unsupported upload: %s", errInvalidRender, err)
+	}
+
+	var format string
+
+	switch name {
+	case gifFormat:
+		format = gifFormat
+	case pngFormat:
+		format = pngFormat
+	case jpegFormat, "webp":
+		format = jpegFormat
+	default:
+		return source{}, fmt.Errorf("%w: unsupported upload format `%s`", errInvalidRender, name)
+	}
+
+	return source{provider: uploadProvider, id: hash.String(string(payload)), format: format}, nil
+}
+
+func parseRenderOptions(values url.Values) (RenderRequest, error) {
+	request := RenderRequest{
+		Captions: values[captionParam],
+		Layout:   values.Get("layout"),
+		Format:   values.Get("format"),
+	}
+
+	if font, stroke := values.Get("font"), values.Get("stroke"); len(font) != 0 || len(stroke) != 0 {
+		style := defaultStyle
+
+		if len(font) != 0 {
+			style.Font = font
+		}
+
+		if len(stroke) != 0 {
+			value, err := strconv.ParseFloat(stroke, 64)
+			if err != nil {
+				return request, fmt.Errorf("%w: parse stroke: %s", errInvalidRender, err)
+			}
+
+			style.Stroke = value
+		}
+
+		request.Style = &style
+	}
+
+	var err error
+
+	if width := values.Get("width"); len(width) != 0 {
+		if request.Width, err = strconv.Atoi(width); err != nil {
+			return request, fmt.Errorf("%w: parse width: %s", errInvalidRender, err)
+		}
+	}
+
+	if quality := values.Get("quality"); len(quality) != 0 {
+		if request.Quality, err = strconv.Atoi(quality); err != nil {
+			return request, fmt.Errorf("%w: parse quality: %s", errInvalidRender, err)
+		}
+	}
+
+	return request, nil
+}
+
+func (s Service) renderUpload(ctx context.Context, spec renderSpec, payload []byte) (string, int64, error) {
+	imagePath := s.getCacheFilename(spec)
+
+	info, err := os.Stat(imagePath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", 0, err
+	}
+
+	if info == nil {
+		if spec.kind() == gifKind {
+			err = s.renderUploadedGif(ctx, spec, payload)
+		} else {
+			err = s.renderUploadedImage(ctx, spec, payload)
+		}
+
+		if err != nil {
+			return "", 0, err
+		}
+
+		info, err = os.Stat(imagePath)
+		if err != nil {
+			return "", 0, fmt.Errorf("get image info: %w", err)
+		}
+	}
+
+	return imagePath, info.Size(), nil
+}
+
+func (s Service) renderUploadedImage(ctx context.Context, spec renderSpec, payload []byte) error {
+	return s.renders.run(ctx, func(ctx context.Context) error {
+		uploaded, err := s.limits.decodeImage(payload)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errInvalidRender, err)
+		}
+
+		output, err := s.captionImage(ctx, resizeImage(uploaded, spec.width), spec.caption, spec.style, spec.layout)
+		if err != nil {
+			return fmt.Errorf("caption upload: %w", err)
+		}
+
+		s.storeInCache(ctx, spec, output)
+
+		return nil
+	})
+}
+
+func (s Service) renderUploadedGif(ctx context.Context, spec renderSpec, payload []byte) error {
+	return s.renders.run(ctx, func(ctx context.Context) error {
+		uploaded, err := s.limits.decodeGif(payload)
+		if err != nil {
+			return fmt.Errorf("%w: %w", errInvalidRender, err)
+		}
+
+		output, err := s.captionGif(ctx, uploaded, spec.caption, spec.style, spec.layout)
+		if err != nil {
+			return fmt.Errorf("caption upload: %w", err)
+		}
+
+		s.storeGifInCache(ctx, spec, output)
+
+		return nil
+	})
+}
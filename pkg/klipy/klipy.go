@@ -6,7 +6,9 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/ViBiOh/flags"
@@ -15,17 +17,17 @@ import (
 	"github.com/ViBiOh/httputils/v4/pkg/httpjson"
 	"github.com/ViBiOh/httputils/v4/pkg/redis"
 	"github.com/ViBiOh/httputils/v4/pkg/request"
+	"github.com/ViBiOh/kitten/pkg/rediscache"
 	"github.com/ViBiOh/kitten/pkg/version"
 	"go.opentelemetry.io/otel/trace"
 )
 
-const (
-	root        = "https://api.klipy.com/v2"
-	maxFileSize = 4 << 20
-)
+const maxFileSize = 4 << 20
 
 var (
-	ErrNotFound   = errors.New("no gif found")
+	ErrNotFound          = errors.New("no gif found")
+	ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
 	cacheDuration = time.Hour * 24 * 7
 )
 
@@ -55,32 +57,35 @@ type response struct {
 
 type Service struct {
 	cache  *cache.Cache[string, ResponseObject]
+	index  rediscache.Index[ResponseObject]
 	apiKey string
 	req    request.Request
 }
 
 type Config struct {
 	apiKey string
+	url    string
 }
 
 func Flags(fs *flag.FlagSet, prefix string, overrides ...flags.Override) *Config {
 	var config Config
 
 	flags.New("ApiKey", "API Key").Prefix(prefix).DocPrefix("klipy").StringVar(fs, &config.apiKey, "", overrides)
+	flags.New("URL", "API base URL").Prefix(prefix).DocPrefix("klipy").StringVar(fs, &config.url, "https://api.klipy.com/v2", overrides)
 
 	return &config
 }
 
 func New(ctx context.Context, config *Config, redisClient redis.Client, tracerProvider trace.TracerProvider) Service {
 	service := Service{
-		req:    request.Get(root).WithClient(request.CreateClient(time.Second*30, request.NoRedirection)),
+		req:    request.Get(strings.TrimSuffix(config.url, "/")).WithClient(request.CreateClient(time.Second*30, request.NoRedirection)),
 		apiKey: url.QueryEscape(config.apiKey),
 	}
 
 	service.cache = cache.New(redisClient, cacheID, func(ctx context.Context, id string) (ResponseObject, error) {
 		resp, err := service.req.Path("/posts?key=%s&ids=%s", service.apiKey, url.QueryEscape(id)).Send(ctx, nil)
 		if err != nil {
-			return ResponseObject{}, httperror.FromResponse(resp, fmt.Errorf("get gif: %w", err))
+			return ResponseObject{}, handleError(resp, fmt.Errorf("get gif: %w", err))
 		}
 
 		result, err := httpjson.Read[response](resp)
@@ -98,13 +103,15 @@ func New(ctx context.Context, config *Config, redisClient redis.Client, tracerPr
 		WithExtendOnHit(ctx, cacheDuration/4, 50).
 		WithClientSideCaching(ctx, "kitten_klipy", 50)
 
+	service.index = rediscache.New(redisClient, service.cache, cacheID)
+
 	return service
 }
 
 func (s Service) Search(ctx context.Context, query, pos string) (ResponseObject, string, error) {
 	resp, err := s.req.Path(fmt.Sprintf("/search?key=%s&q=%s&limit=1&pos=%s&media_filter=mediumgif,tinygif", s.apiKey, url.QueryEscape(query), url.QueryEscape(pos))).Send(ctx, nil)
 	if err != nil {
-		return ResponseObject{}, "", httperror.FromResponse(resp, fmt.Errorf("search gif: %w", err))
+		return ResponseObject{}, "", handleError(resp, fmt.Errorf("search gif: %w", err))
 	}
 
 	search, err := httpjson.Read[response](resp)
@@ -119,7 +126,7 @@ func (s Service) Search(ctx context.Context, query, pos string) (ResponseObject,
 	gif := search.Results[0]
 
 	go func(ctx context.Context) {
-		if err = s.cache.Store(ctx, gif.ID, gif); err != nil {
+		if err := s.cache.Store(ctx, gif.ID, gif); err != nil {
 			slog.LogAttrs(ctx, slog.LevelError, "save gif in cache", slog.Any("error", err))
 		}
 	}(context.WithoutCancel(ctx))
@@ -143,6 +150,29 @@ func (s Service) SendAnalytics(ctx context.Context, content ResponseObject, quer
 	}
 }
 
+// Cached lists the ids stored in cache
+func (s Service) Cached(ctx context.Context) ([]string, error) {
+	return s.index.Cached(ctx)
+}
+
+// Evict removes the given ids from cache
+func (s Service) Evict(ctx context.Context, ids ...string) error {
+	return s.index.Evict(ctx, ids...)
+}
+
+func handleError(resp *http.Response, err error) error {
+	if httpError, ok := errors.AsType[request.Error](err); ok {
+		switch httpError.StatusCode {
+		case http.StatusNotFound:
+			return ErrNotFound
+		case http.StatusTooManyRequests:
+			return ErrRateLimitExceeded
+		}
+	}
+
+	return httperror.FromResponse(resp, err)
+}
+
 func cacheID(id string) string {
 	return version.Redis("klipy:" + id)
 }
@@ -0,0 +1,308 @@
+package library
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ViBiOh/flags"
+)
+
+var (
+	ErrNotFound      = errors.New("no media found in library")
+	ErrInvalidCursor = errors.New("invalid library cursor")
+)
+
+// Item is an image or a gif of the library
+type Item struct {
+	ID   string
+	Path string
+	Name string
+	Tags []string
+	Gif  bool
+}
+
+type Service struct {
+	fsys  fs.FS
+	ids   map[string]int
+	items []Item
+}
+
+type Config struct {
+	directory string
+	tags      string
+}
+
+func Flags(fs *flag.FlagSet, prefix string, overrides ...flags.Override) *Config {
+	var config Config
+
+	flags.New("Directory", "Directory of jpeg, png and webp images and gifs served as the local library, disabled if empty").Prefix(prefix).DocPrefix("library").StringVar(fs, &config.directory, "", overrides)
+	flags.New("Tags", "Tags file of the library, relative to its directory, one path:tag1,tag2 per line").Prefix(prefix).DocPrefix("library").StringVar(fs, &config.tags, "tags.txt", overrides)
+
+	return &config
+}
+
+func New(config *Config) (Service, error) {
+	if len(config.directory) == 0 {
+		return Service{}, nil
+	}
+
+	return NewFromFS(os.DirFS(config.directory), config.tags)
+}
+
+// NewFromFS indexes the images and gifs of the given filesystem, e.g. an embedded one
+func NewFromFS(fsys fs.FS, tagsFile string) (Service, error) {
+	tags, err := loadTags(fsys, tagsFile)
+	if err != nil {
+		return Service{}, fmt.Errorf("load tags: %w", err)
+	}
+
+	service := Service{
+		fsys: fsys,
+		ids:  make(map[string]int),
+	}
+
+	err = fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if name != "." && strings.HasPrefix(entry.Name(), ".") {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		var gif bool
+
+		switch strings.ToLower(path.Ext(name)) {
+		case ".jpg", ".jpeg", ".png", ".webp":
+		case ".gif":
+			gif = true
+		default:
+			return nil
+		}
+
+		id, err := contentID(fsys, name)
+		if err != nil {
+			return fmt.Errorf("hash `%s`: %w", name, err)
+		}
+
+		if _, ok := service.ids[id]; ok {
+			slog.LogAttrs(context.Background(), slog.LevelWarn, "duplicate media in library", slog.String("path", name))
+			return nil
+		}
+
+		service.ids[id] = len(service.items)
+		service.items = append(service.items, Item{
+			ID:   id,
+			Path: name,
+			Name: strings.TrimSuffix(path.Base(name), path.Ext(name)),
+			Tags: tags[name],
+			Gif:  gif,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return Service{}, fmt.Errorf("index: %w", err)
+	}
+
+	slog.LogAttrs(context.Background(), slog.LevelInfo, "library indexed", slog.Int("count", len(service.items)))
+
+	return service, nil
+}
+
+func loadTags(fsys fs.FS, filename string) (map[string][]string, error) {
+	tags := make(map[string][]string)
+	if len(filename) == 0 {
+		return tags, nil
+	}
+
+	file, err := fsys.Open(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return tags, nil
+		}
+
+		return nil, fmt.Errorf("open: %w", err)
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			slog.LogAttrs(context.Background(), slog.LevelWarn, "close tags file", slog.Any("error", closeErr))
+		}
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, values, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid line `%s`", line)
+		}
+
+		name = path.Clean(strings.TrimSpace(name))
+
+		for tag := range strings.SplitSeq(values, ",") {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); len(tag) != 0 {
+				tags[name] = append(tags[name], tag)
+			}
+		}
+	}
+
+	return tags, scanner.Err()
+}
+
+// contentID identifies a media by its content, so it stays the same when moved and changes when edited
+func contentID(fsys fs.FS, name string) (string, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			slog.LogAttrs(context.Background(), slog.LevelWarn, "close library media", slog.Any("error", closeErr))
+		}
+	}()
+
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)[:8]), nil
+}
+
+func (s Service) Enabled() bool {
+	return s.fsys != nil
+}
+
+// Search returns the match of the query at the cursor position and the cursor of the next one, a random item for an empty query
+func (s Service) Search(query string, gif bool, cursor string) (Item, string, error) {
+	words := strings.FieldsFunc(strings.ToLower(query), isSeparator)
+	if len(words) == 0 {
+		return s.Random(gif)
+	}
+
+	var offset int
+	if len(cursor) != 0 {
+		var err error
+
+		if offset, err = strconv.Atoi(cursor); err != nil || offset < 0 {
+			return Item{}, "", fmt.Errorf("%w: `%s`", ErrInvalidCursor, cursor)
+		}
+	}
+
+	type match struct {
+		item  Item
+		score int
+	}
+
+	var matches []match
+
+	for _, item := range s.items {
+		if item.Gif != gif {
+			continue
+		}
+
+		if score := item.score(words); score > 0 {
+			matches = append(matches, match{item: item, score: score})
+		}
+	}
+
+	if offset >= len(matches) {
+		return Item{}, "", ErrNotFound
+	}
+
+	slices.SortFunc(matches, func(a, b match) int {
+		return cmp.Or(b.score-a.score, strings.Compare(a.item.Path, b.item.Path))
+	})
+
+	var next string
+	if offset+1 < len(matches) {
+		next = strconv.Itoa(offset + 1)
+	}
+
+	return matches[offset].item, next, nil
+}
+
+// Random picks an item, there is no next one
+func (s Service) Random(gif bool) (Item, string, error) {
+	var candidates []Item
+
+	for _, item := range s.items {
+		if item.Gif == gif {
+			candidates = append(candidates, item)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return Item{}, "", ErrNotFound
+	}
+
+	return candidates[rand.IntN(len(candidates))], "", nil
+}
+
+func (s Service) Get(id string) (Item, error) {
+	index, ok := s.ids[id]
+	if !ok {
+		return Item{}, ErrNotFound
+	}
+
+	return s.items[index], nil
+}
+
+// Open reads the content of the item with the given id
+func (s Service) Open(id string) (io.ReadCloser, error) {
+	item, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.fsys.Open(item.Path)
+}
+
+// score counts how well the item matches every word, a tag being worth more than a part of the path, 0 if any word is missing
+func (i Item) score(words []string) int {
+	var score int
+
+	for _, word := range words {
+		switch {
+		case slices.Contains(i.Tags, word):
+			score += 2
+		case strings.Contains(strings.ToLower(i.Path), word):
+			score++
+		default:
+			return 0
+		}
+	}
+
+	return score
+}
+
+func isSeparator(char rune) bool {
+	return !unicode.IsLetter(char) && !unicode.IsNumber(char)
+}
@@ -0,0 +1,262 @@
+package library
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestService(t *testing.T) Service {
+	t.Helper()
+
+	service, err := NewFromFS(fstest.MapFS{
+		"tags.txt":           {Data: []byte("# tags\nteam/deploy.gif: deploy, Friday,fire\ncats/grumpy.jpg:cat,angry\ncats/happy.jpeg:cat\n")},
+		"team/deploy.gif":    {Data: []byte("deploy gif")},
+		"team/friday.gif":    {Data: []byte("friday gif")},
+		"team/zz-copy.gif":   {Data: []byte("friday gif")},
+		"cats/grumpy.jpg":    {Data: []byte("grumpy jpg")},
+		"cats/happy.jpeg":    {Data: []byte("happy jpg")},
+		"cats/sleeping.JPG":  {Data: []byte("sleeping jpg")},
+		"dogs/lazy.png":      {Data: []byte("lazy png")},
+		"dogs/jumping.webp":  {Data: []byte("jumping webp")},
+		"cats/readme.txt":    {Data: []byte("not a media")},
+		".hidden/secret.jpg": {Data: []byte("secret jpg")},
+	}, "tags.txt")
+	if err != nil {
+		t.Fatalf("NewFromFS() = `%s`", err)
+	}
+
+	return service
+}
+
+func TestNewFromFS(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+
+	if len(service.items) != 7 {
+		t.Fatalf("NewFromFS() = %d items, want 7", len(service.items))
+	}
+
+	for _, item := range service.items {
+		if item.Path == ".hidden/secret.jpg" || item.Path == "cats/readme.txt" {
+			t.Errorf("NewFromFS() indexed `%s`", item.Path)
+		}
+	}
+
+	if _, err := NewFromFS(fstest.MapFS{"tags.txt": {Data: []byte("no separator")}}, "tags.txt"); err == nil {
+		t.Error("NewFromFS() = nil, want an error for an invalid tags file")
+	}
+
+	if _, err := NewFromFS(fstest.MapFS{"cat.jpg": {Data: []byte("cat")}}, "tags.txt"); err != nil {
+		t.Errorf("NewFromFS() = `%s`, want no error without tags file", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+
+	cases := map[string]struct {
+		query    string
+		gif      bool
+		cursor   string
+		wantPath string
+		wantNext string
+		wantErr  error
+	}{
+		"tag": {
+			query:    "deploy",
+			gif:      true,
+			wantPath: "team/deploy.gif",
+		},
+		"tag is case insensitive": {
+			query:    "FRIDAY",
+			gif:      true,
+			wantPath: "team/deploy.gif",
+			wantNext: "1",
+		},
+		"tag before path": {
+			query:    "friday",
+			gif:      true,
+			wantPath: "team/deploy.gif",
+			wantNext: "1",
+		},
+		"next by path": {
+			query:    "friday",
+			gif:      true,
+			cursor:   "1",
+			wantPath: "team/friday.gif",
+		},
+		"every word": {
+			query:    "cat angry",
+			wantPath: "cats/grumpy.jpg",
+		},
+		"missing word": {
+			query:   "cat dog",
+			wantErr: ErrNotFound,
+		},
+		"path part": {
+			query:    "sleep",
+			wantPath: "cats/sleeping.JPG",
+		},
+		"png": {
+			query:    "lazy",
+			wantPath: "dogs/lazy.png",
+		},
+		"webp": {
+			query:    "jumping",
+			wantPath: "dogs/jumping.webp",
+		},
+		"separators": {
+			query:    "  cat,   angry!",
+			wantPath: "cats/grumpy.jpg",
+		},
+		"tags outscore paths": {
+			query:    "cat",
+			wantPath: "cats/grumpy.jpg",
+			wantNext: "1",
+		},
+		"second match": {
+			query:    "cat",
+			cursor:   "1",
+			wantPath: "cats/happy.jpeg",
+			wantNext: "2",
+		},
+		"last match": {
+			query:    "cat",
+			cursor:   "2",
+			wantPath: "cats/sleeping.JPG",
+		},
+		"past last match": {
+			query:   "cat",
+			cursor:  "3",
+			wantErr: ErrNotFound,
+		},
+		"kind filtered": {
+			query:   "deploy",
+			wantErr: ErrNotFound,
+		},
+		"invalid cursor": {
+			query:   "cat",
+			cursor:  "next",
+			wantErr: ErrInvalidCursor,
+		},
+		"negative cursor": {
+			query:   "cat",
+			cursor:  "-1",
+			wantErr: ErrInvalidCursor,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			item, next, err := service.Search(testCase.query, testCase.gif, testCase.cursor)
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("Search() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if item.Path != testCase.wantPath || next != testCase.wantNext {
+				t.Errorf("Search() = (`%s`, `%s`), want (`%s`, `%s`)", item.Path, next, testCase.wantPath, testCase.wantNext)
+			}
+		})
+	}
+}
+
+func TestRandom(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+
+	cases := map[string]struct {
+		service Service
+		gif     bool
+		wantErr error
+	}{
+		"image": {
+			service: service,
+		},
+		"gif": {
+			service: service,
+			gif:     true,
+		},
+		"empty": {
+			service: Service{},
+			wantErr: ErrNotFound,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			item, next, err := testCase.service.Search(" ", testCase.gif, "")
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("Search() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if err == nil && (item.Gif != testCase.gif || len(next) != 0) {
+				t.Errorf("Search() = (%+v, `%s`), want a gif %t without next", item, next, testCase.gif)
+			}
+		})
+	}
+}
+
+func TestGetAndOpen(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+
+	item, _, err := service.Search("grumpy", false, "")
+	if err != nil {
+		t.Fatalf("Search() = `%s`", err)
+	}
+
+	cases := map[string]struct {
+		id       string
+		wantPath string
+		wantErr  error
+	}{
+		"known": {
+			id:       item.ID,
+			wantPath: "cats/grumpy.jpg",
+		},
+		"unknown": {
+			id:      "unknown",
+			wantErr: ErrNotFound,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := service.Get(testCase.id)
+			if !errors.Is(err, testCase.wantErr) || got.Path != testCase.wantPath {
+				t.Fatalf("Get() = (`%s`, `%v`), want (`%s`, `%v`)", got.Path, err, testCase.wantPath, testCase.wantErr)
+			}
+
+			reader, err := service.Open(testCase.id)
+			if !errors.Is(err, testCase.wantErr) {
+				t.Fatalf("Open() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			content, err := io.ReadAll(reader)
+			if closeErr := reader.Close(); closeErr != nil {
+				t.Errorf("close: %s", closeErr)
+			}
+
+			if err != nil || string(content) != "grumpy jpg" {
+				t.Errorf("Open() = (`%s`, `%v`), want `grumpy jpg`", content, err)
+			}
+		})
+	}
+}
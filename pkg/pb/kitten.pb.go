@@ -0,0 +1,849 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: kitten.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Kind int32
+
+const (
+	Kind_KIND_UNSPECIFIED Kind = 0
+	Kind_KIND_IMAGE       Kind = 1
+	Kind_KIND_GIF         Kind = 2
+)
+
+// Enum value maps for Kind.
+var (
+	Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "KIND_IMAGE",
+		2: "KIND_GIF",
+	}
+	Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"KIND_IMAGE":       1,
+		"KIND_GIF":         2,
+	}
+)
+
+func (x Kind) Enum() *Kind {
+	p := new(Kind)
+	*p = x
+	return p
+}
+
+func (x Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_kitten_proto_enumTypes[0].Descriptor()
+}
+
+func (Kind) Type() protoreflect.EnumType {
+	return &file_kitten_proto_enumTypes[0]
+}
+
+func (x Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Kind.Descriptor instead.
+func (Kind) EnumDescriptor() ([]byte, []int) {
+	return file_kitten_proto_rawDescGZIP(), []int{0}
+}
+
+type Source struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Provider is unsplash or klipy, empty when rendering from an URL
+	Provider      string `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Id            string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Url           string `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Source) Reset() {
+	*x = Source{}
+	mi := &file_kitten_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Source) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Source) ProtoMessage() {}
+
+func (x *Source) ProtoReflect() protoreflect.Message {
+	mi := &file_kitten_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Source.ProtoReflect.Descriptor instead.
+func (*Source) Descriptor() ([]byte, []int) {
+	return file_kitten_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Source) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *Source) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Source) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type Style struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Font          string                 `protobuf:"bytes,1,opt,name=font,proto3" json:"font,omitempty"`
+	Stroke        float64                `protobuf:"fixed64,2,opt,name=stroke,proto3" json:"stroke,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Style) Reset() {
+	*x = Style{}
+	mi := &file_kitten_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Style) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Style) ProtoMessage() {}
+
+func (x *Style) ProtoReflect() protoreflect.Message {
+	mi := &file_kitten_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Style.ProtoReflect.Descriptor instead.
+func (*Style) Descriptor() ([]byte, []int) {
+	return file_kitten_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Style) GetFont() string {
+	if x != nil {
+		return x.Font
+	}
+	return ""
+}
+
+func (x *Style) GetStroke() float64 {
+	if x != nil {
+		return x.Stroke
+	}
+	return 0
+}
+
+type RenderRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Source   *Source                `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Captions []string               `protobuf:"bytes,2,rep,name=captions,proto3" json:"captions,omitempty"`
+	// Layout is top, bottom or split
+	Layout string `protobuf:"bytes,3,opt,name=layout,proto3" json:"layout,omitempty"`
+	// Format is jpeg, png or gif
+	Format        string `protobuf:"bytes,4,opt,name=format,proto3" json:"format,omitempty"`
+	Style         *Style `protobuf:"bytes,5,opt,name=style,proto3" json:"style,omitempty"`
+	Width         int32  `protobuf:"varint,6,opt,name=width,proto3" json:"width,omitempty"`
+	Quality       int32  `protobuf:"varint,7,opt,name=quality,proto3" json:"quality,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenderRequest) Reset() {
+	*x = RenderRequest{}
+	mi := &file_kitten_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenderRequest) ProtoMessage() {}
+
+func (x *RenderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kitten_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenderRequest.ProtoReflect.Descriptor instead.
+func (*RenderRequest) Descriptor() ([]byte, []int) {
+	return file_kitten_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RenderRequest) GetSource() *Source {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *RenderRequest) GetCaptions() []string {
+	if x != nil {
+		return x.Captions
+	}
+	return nil
+}
+
+func (x *RenderRequest) GetLayout() string {
+	if x != nil {
+		return x.Layout
+	}
+	return ""
+}
+
+func (x *RenderRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *RenderRequest) GetStyle() *Style {
+	if x != nil {
+		return x.Style
+	}
+	return nil
+}
+
+func (x *RenderRequest) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *RenderRequest) GetQuality() int32 {
+	if x != nil {
+		return x.Quality
+	}
+	return 0
+}
+
+type Attribution struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provider      string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	Author        string                 `protobuf:"bytes,3,opt,name=author,proto3" json:"author,omitempty"`
+	AuthorUrl     string                 `protobuf:"bytes,4,opt,name=author_url,json=authorUrl,proto3" json:"author_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Attribution) Reset() {
+	*x = Attribution{}
+	mi := &file_kitten_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Attribution) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Attribution) ProtoMessage() {}
+
+func (x *Attribution) ProtoReflect() protoreflect.Message {
+	mi := &file_kitten_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Attribution.ProtoReflect.Descriptor instead.
+func (*Attribution) Descriptor() ([]byte, []int) {
+	return file_kitten_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Attribution) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *Attribution) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Attribution) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *Attribution) GetAuthorUrl() string {
+	if x != nil {
+		return x.AuthorUrl
+	}
+	return ""
+}
+
+type RenderMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Attribution   *Attribution           `protobuf:"bytes,1,opt,name=attribution,proto3" json:"attribution,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	CacheKey      string                 `protobuf:"bytes,3,opt,name=cache_key,json=cacheKey,proto3" json:"cache_key,omitempty"`
+	Format        string                 `protobuf:"bytes,4,opt,name=format,proto3" json:"format,omitempty"`
+	ContentType   string                 `protobuf:"bytes,5,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	Width         int32                  `protobuf:"varint,6,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32                  `protobuf:"varint,7,opt,name=height,proto3" json:"height,omitempty"`
+	Size          int64                  `protobuf:"varint,8,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenderMetadata) Reset() {
+	*x = RenderMetadata{}
+	mi := &file_kitten_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenderMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenderMetadata) ProtoMessage() {}
+
+func (x *RenderMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_kitten_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenderMetadata.ProtoReflect.Descriptor instead.
+func (*RenderMetadata) Descriptor() ([]byte, []int) {
+	return file_kitten_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RenderMetadata) GetAttribution() *Attribution {
+	if x != nil {
+		return x.Attribution
+	}
+	return nil
+}
+
+func (x *RenderMetadata) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *RenderMetadata) GetCacheKey() string {
+	if x != nil {
+		return x.CacheKey
+	}
+	return ""
+}
+
+func (x *RenderMetadata) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *RenderMetadata) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *RenderMetadata) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *RenderMetadata) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *RenderMetadata) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type RenderResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metadata      *RenderMetadata        `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Content       []byte                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenderResponse) Reset() {
+	*x = RenderResponse{}
+	mi := &file_kitten_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenderResponse) ProtoMessage() {}
+
+func (x *RenderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_kitten_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenderResponse.ProtoReflect.Descriptor instead.
+func (*RenderResponse) Descriptor() ([]byte, []int) {
+	return file_kitten_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RenderResponse) GetMetadata() *RenderMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *RenderResponse) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type RenderChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*RenderChunk_Content
+	//	*RenderChunk_Metadata
+	Payload       isRenderChunk_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenderChunk) Reset() {
+	*x = RenderChunk{}
+	mi := &file_kitten_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenderChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenderChunk) ProtoMessage() {}
+
+func (x *RenderChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_kitten_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenderChunk.ProtoReflect.Descriptor instead.
+func (*RenderChunk) Descriptor() ([]byte, []int) {
+	return file_kitten_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RenderChunk) GetPayload() isRenderChunk_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *RenderChunk) GetContent() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*RenderChunk_Content); ok {
+			return x.Content
+		}
+	}
+	return nil
+}
+
+func (x *RenderChunk) GetMetadata() *RenderMetadata {
+	if x != nil {
+		if x, ok := x.Payload.(*RenderChunk_Metadata); ok {
+			return x.Metadata
+		}
+	}
+	return nil
+}
+
+type isRenderChunk_Payload interface {
+	isRenderChunk_Payload()
+}
+
+type RenderChunk_Content struct {
+	Content []byte `protobuf:"bytes,1,opt,name=content,proto3,oneof"`
+}
+
+type RenderChunk_Metadata struct {
+	Metadata *RenderMetadata `protobuf:"bytes,2,opt,name=metadata,proto3,oneof"`
+}
+
+func (*RenderChunk_Content) isRenderChunk_Payload() {}
+
+func (*RenderChunk_Metadata) isRenderChunk_Payload() {}
+
+type SearchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Kind  Kind                   `protobuf:"varint,1,opt,name=kind,proto3,enum=kitten.v1.Kind" json:"kind,omitempty"`
+	Query string                 `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	// Pos is the pagination cursor of gif results
+	Pos           string `protobuf:"bytes,3,opt,name=pos,proto3" json:"pos,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_kitten_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_kitten_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_kitten_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SearchRequest) GetKind() Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return Kind_KIND_UNSPECIFIED
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetPos() string {
+	if x != nil {
+		return x.Pos
+	}
+	return ""
+}
+
+type SearchResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Kind        Kind                   `protobuf:"varint,1,opt,name=kind,proto3,enum=kitten.v1.Kind" json:"kind,omitempty"`
+	Source      *Source                `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	Attribution *Attribution           `protobuf:"bytes,3,opt,name=attribution,proto3" json:"attribution,omitempty"`
+	// Next is the pagination cursor of the next gif result
+	Next          string `protobuf:"bytes,4,opt,name=next,proto3" json:"next,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_kitten_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_kitten_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_kitten_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SearchResponse) GetKind() Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return Kind_KIND_UNSPECIFIED
+}
+
+func (x *SearchResponse) GetSource() *Source {
+	if x != nil {
+		return x.Source
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetAttribution() *Attribution {
+	if x != nil {
+		return x.Attribution
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetNext() string {
+	if x != nil {
+		return x.Next
+	}
+	return ""
+}
+
+var File_kitten_proto protoreflect.FileDescriptor
+
+const file_kitten_proto_rawDesc = "" +
+	"\n" +
+	"\fkitten.proto\x12\tkitten.v1\"F\n" +
+	"\x06Source\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\"3\n" +
+	"\x05Style\x12\x12\n" +
+	"\x04font\x18\x01 \x01(\tR\x04font\x12\x16\n" +
+	"\x06stroke\x18\x02 \x01(\x01R\x06stroke\"\xde\x01\n" +
+	"\rRenderRequest\x12)\n" +
+	"\x06source\x18\x01 \x01(\v2\x11.kitten.v1.SourceR\x06source\x12\x1a\n" +
+	"\bcaptions\x18\x02 \x03(\tR\bcaptions\x12\x16\n" +
+	"\x06layout\x18\x03 \x01(\tR\x06layout\x12\x16\n" +
+	"\x06format\x18\x04 \x01(\tR\x06format\x12&\n" +
+	"\x05style\x18\x05 \x01(\v2\x10.kitten.v1.StyleR\x05style\x12\x14\n" +
+	"\x05width\x18\x06 \x01(\x05R\x05width\x12\x18\n" +
+	"\aquality\x18\a \x01(\x05R\aquality\"r\n" +
+	"\vAttribution\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x16\n" +
+	"\x06author\x18\x03 \x01(\tR\x06author\x12\x1d\n" +
+	"\n" +
+	"author_url\x18\x04 \x01(\tR\tauthorUrl\"\xf6\x01\n" +
+	"\x0eRenderMetadata\x128\n" +
+	"\vattribution\x18\x01 \x01(\v2\x16.kitten.v1.AttributionR\vattribution\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12\x1b\n" +
+	"\tcache_key\x18\x03 \x01(\tR\bcacheKey\x12\x16\n" +
+	"\x06format\x18\x04 \x01(\tR\x06format\x12!\n" +
+	"\fcontent_type\x18\x05 \x01(\tR\vcontentType\x12\x14\n" +
+	"\x05width\x18\x06 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\a \x01(\x05R\x06height\x12\x12\n" +
+	"\x04size\x18\b \x01(\x03R\x04size\"a\n" +
+	"\x0eRenderResponse\x125\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x19.kitten.v1.RenderMetadataR\bmetadata\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\fR\acontent\"m\n" +
+	"\vRenderChunk\x12\x1a\n" +
+	"\acontent\x18\x01 \x01(\fH\x00R\acontent\x127\n" +
+	"\bmetadata\x18\x02 \x01(\v2\x19.kitten.v1.RenderMetadataH\x00R\bmetadataB\t\n" +
+	"\apayload\"\\\n" +
+	"\rSearchRequest\x12#\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x0f.kitten.v1.KindR\x04kind\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12\x10\n" +
+	"\x03pos\x18\x03 \x01(\tR\x03pos\"\xae\x01\n" +
+	"\x0eSearchResponse\x12#\n" +
+	"\x04kind\x18\x01 \x01(\x0e2\x0f.kitten.v1.KindR\x04kind\x12)\n" +
+	"\x06source\x18\x02 \x01(\v2\x11.kitten.v1.SourceR\x06source\x128\n" +
+	"\vattribution\x18\x03 \x01(\v2\x16.kitten.v1.AttributionR\vattribution\x12\x12\n" +
+	"\x04next\x18\x04 \x01(\tR\x04next*:\n" +
+	"\x04Kind\x12\x14\n" +
+	"\x10KIND_UNSPECIFIED\x10\x00\x12\x0e\n" +
+	"\n" +
+	"KIND_IMAGE\x10\x01\x12\f\n" +
+	"\bKIND_GIF\x10\x022\xd2\x01\n" +
+	"\x0eKittenRenderer\x12=\n" +
+	"\x06Render\x12\x18.kitten.v1.RenderRequest\x1a\x19.kitten.v1.RenderResponse\x12B\n" +
+	"\fRenderStream\x12\x18.kitten.v1.RenderRequest\x1a\x16.kitten.v1.RenderChunk0\x01\x12=\n" +
+	"\x06Search\x12\x18.kitten.v1.SearchRequest\x1a\x19.kitten.v1.SearchResponseB!Z\x1fgithub.com/ViBiOh/kitten/pkg/pbb\x06proto3"
+
+var (
+	file_kitten_proto_rawDescOnce sync.Once
+	file_kitten_proto_rawDescData []byte
+)
+
+func file_kitten_proto_rawDescGZIP() []byte {
+	file_kitten_proto_rawDescOnce.Do(func() {
+		file_kitten_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_kitten_proto_rawDesc), len(file_kitten_proto_rawDesc)))
+	})
+	return file_kitten_proto_rawDescData
+}
+
+var file_kitten_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_kitten_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_kitten_proto_goTypes = []any{
+	(Kind)(0),              // 0: kitten.v1.Kind
+	(*Source)(nil),         // 1: kitten.v1.Source
+	(*Style)(nil),          // 2: kitten.v1.Style
+	(*RenderRequest)(nil),  // 3: kitten.v1.RenderRequest
+	(*Attribution)(nil),    // 4: kitten.v1.Attribution
+	(*RenderMetadata)(nil), // 5: kitten.v1.RenderMetadata
+	(*RenderResponse)(nil), // 6: kitten.v1.RenderResponse
+	(*RenderChunk)(nil),    // 7: kitten.v1.RenderChunk
+	(*SearchRequest)(nil),  // 8: kitten.v1.SearchRequest
+	(*SearchResponse)(nil), // 9: kitten.v1.SearchResponse
+}
+var file_kitten_proto_depIdxs = []int32{
+	1,  // 0: kitten.v1.RenderRequest.source:type_name -> kitten.v1.Source
+	2,  // 1: kitten.v1.RenderRequest.style:type_name -> kitten.v1.Style
+	4,  // 2: kitten.v1.RenderMetadata.attribution:type_name -> kitten.v1.Attribution
+	5,  // 3: kitten.v1.RenderResponse.metadata:type_name -> kitten.v1.RenderMetadata
+	5,  // 4: kitten.v1.RenderChunk.metadata:type_name -> kitten.v1.RenderMetadata
+	0,  // 5: kitten.v1.SearchRequest.kind:type_name -> kitten.v1.Kind
+	0,  // 6: kitten.v1.SearchResponse.kind:type_name -> kitten.v1.Kind
+	1,  // 7: kitten.v1.SearchResponse.source:type_name -> kitten.v1.Source
+	4,  // 8: kitten.v1.SearchResponse.attribution:type_name -> kitten.v1.Attribution
+	3,  // 9: kitten.v1.KittenRenderer.Render:input_type -> kitten.v1.RenderRequest
+	3,  // 10: kitten.v1.KittenRenderer.RenderStream:input_type -> kitten.v1.RenderRequest
+	8,  // 11: kitten.v1.KittenRenderer.Search:input_type -> kitten.v1.SearchRequest
+	6,  // 12: kitten.v1.KittenRenderer.Render:output_type -> kitten.v1.RenderResponse
+	7,  // 13: kitten.v1.KittenRenderer.RenderStream:output_type -> kitten.v1.RenderChunk
+	9,  // 14: kitten.v1.KittenRenderer.Search:output_type -> kitten.v1.SearchResponse
+	12, // [12:15] is the sub-list for method output_type
+	9,  // [9:12] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_kitten_proto_init() }
+func file_kitten_proto_init() {
+	if File_kitten_proto != nil {
+		return
+	}
+	file_kitten_proto_msgTypes[6].OneofWrappers = []any{
+		(*RenderChunk_Content)(nil),
+		(*RenderChunk_Metadata)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_kitten_proto_rawDesc), len(file_kitten_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_kitten_proto_goTypes,
+		DependencyIndexes: file_kitten_proto_depIdxs,
+		EnumInfos:         file_kitten_proto_enumTypes,
+		MessageInfos:      file_kitten_proto_msgTypes,
+	}.Build()
+	File_kitten_proto = out.File
+	file_kitten_proto_goTypes = nil
+	file_kitten_proto_depIdxs = nil
+}
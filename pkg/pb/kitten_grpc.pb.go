@@ -0,0 +1,211 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: kitten.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	KittenRenderer_Render_FullMethodName       = "/kitten.v1.KittenRenderer/Render"
+	KittenRenderer_RenderStream_FullMethodName = "/kitten.v1.KittenRenderer/RenderStream"
+	KittenRenderer_Search_FullMethodName       = "/kitten.v1.KittenRenderer/Search"
+)
+
+// KittenRendererClient is the client API for KittenRenderer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// KittenRenderer generates memes for backend services
+type KittenRendererClient interface {
+	// Render generates a meme and answers it whole
+	Render(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (*RenderResponse, error)
+	// RenderStream generates a meme and answers it in chunks, frame by frame for gifs, ending with its metadata
+	RenderStream(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RenderChunk], error)
+	// Search finds a source image or gif for a query
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+}
+
+type kittenRendererClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewKittenRendererClient(cc grpc.ClientConnInterface) KittenRendererClient {
+	return &kittenRendererClient{cc}
+}
+
+func (c *kittenRendererClient) Render(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (*RenderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenderResponse)
+	err := c.cc.Invoke(ctx, KittenRenderer_Render_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kittenRendererClient) RenderStream(ctx context.Context, in *RenderRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RenderChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &KittenRenderer_ServiceDesc.Streams[0], KittenRenderer_RenderStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RenderRequest, RenderChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type KittenRenderer_RenderStreamClient = grpc.ServerStreamingClient[RenderChunk]
+
+func (c *kittenRendererClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, KittenRenderer_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// KittenRendererServer is the server API for KittenRenderer service.
+// All implementations must embed UnimplementedKittenRendererServer
+// for forward compatibility.
+//
+// KittenRenderer generates memes for backend services
+type KittenRendererServer interface {
+	// Render generates a meme and answers it whole
+	Render(context.Context, *RenderRequest) (*RenderResponse, error)
+	// RenderStream generates a meme and answers it in chunks, frame by frame for gifs, ending with its metadata
+	RenderStream(*RenderRequest, grpc.ServerStreamingServer[RenderChunk]) error
+	// Search finds a source image or gif for a query
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	mustEmbedUnimplementedKittenRendererServer()
+}
+
+// UnimplementedKittenRendererServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedKittenRendererServer struct{}
+
+func (UnimplementedKittenRendererServer) Render(context.Context, *RenderRequest) (*RenderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Render not implemented")
+}
+func (UnimplementedKittenRendererServer) RenderStream(*RenderRequest, grpc.ServerStreamingServer[RenderChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method RenderStream not implemented")
+}
+func (UnimplementedKittenRendererServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedKittenRendererServer) mustEmbedUnimplementedKittenRendererServer() {}
+func (UnimplementedKittenRendererServer) testEmbeddedByValue()                        {}
+
+// UnsafeKittenRendererServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to KittenRendererServer will
+// result in compilation errors.
+type UnsafeKittenRendererServer interface {
+	mustEmbedUnimplementedKittenRendererServer()
+}
+
+func RegisterKittenRendererServer(s grpc.ServiceRegistrar, srv KittenRendererServer) {
+	// If the following call pancis, it indicates UnimplementedKittenRendererServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&KittenRenderer_ServiceDesc, srv)
+}
+
+func _KittenRenderer_Render_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KittenRendererServer).Render(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KittenRenderer_Render_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KittenRendererServer).Render(ctx, req.(*RenderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _KittenRenderer_RenderStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RenderRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(KittenRendererServer).RenderStream(m, &grpc.GenericServerStream[RenderRequest, RenderChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type KittenRenderer_RenderStreamServer = grpc.ServerStreamingServer[RenderChunk]
+
+func _KittenRenderer_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KittenRendererServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: KittenRenderer_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KittenRendererServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// KittenRenderer_ServiceDesc is the grpc.ServiceDesc for KittenRenderer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var KittenRenderer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kitten.v1.KittenRenderer",
+	HandlerType: (*KittenRendererServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Render",
+			Handler:    _KittenRenderer_Render_Handler,
+		},
+		{
+			MethodName: "Search",
+			Handler:    _KittenRenderer_Search_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "RenderStream",
+			Handler:       _KittenRenderer_RenderStream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "kitten.proto",
+}
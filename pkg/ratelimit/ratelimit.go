@@ -0,0 +1,268 @@
+package ratelimit
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ViBiOh/flags"
+	"github.com/ViBiOh/httputils/v4/pkg/model"
+	"github.com/ViBiOh/httputils/v4/pkg/redis"
+	"github.com/ViBiOh/kitten/pkg/version"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type Scope string
+
+const (
+	IP   Scope = "ip"
+	User Scope = "user"
+	Team Scope = "team"
+)
+
+type quota struct {
+	rate  float64
+	burst float64
+}
+
+func newQuota(perMinute, burst int) quota {
+	return quota{rate: float64(perMinute) / time.Minute.Seconds(), burst: float64(max(burst, 1))}
+}
+
+func (q quota) enabled() bool {
+	return q.rate > 0
+}
+
+// tokenBucket refills, consumes and expires the bucket in a single step, keeping concurrent instances consistent.
+// It answers whether the token was consumed and the milliseconds to wait for the next one otherwise.
+var tokenBucket = goredis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+local clock = redis.call("TIME")
+local now = tonumber(clock[1]) * 1000000 + tonumber(clock[2])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1]) or burst
+local updated = tonumber(bucket[2]) or now
+
+tokens = math.min(burst, tokens + math.max(now - updated, 0) / 1000000 * rate)
+
+local allowed = 0
+local wait = 0
+
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+
+return {allowed, wait}
+`)
+
+type Service struct {
+	redisClient    redis.Client
+	quotas         map[Scope]quota
+	trustedProxies []netip.Prefix
+}
+
+type Config struct {
+	trustedProxies []string
+	ipPerMinute    int
+	ipBurst        int
+	userPerMinute  int
+	userBurst      int
+	teamPerMinute  int
+	teamBurst      int
+}
+
+func Flags(fs *flag.FlagSet, prefix string, overrides ...flags.Override) *Config {
+	var config Config
+
+	flags.New("IpPerMinute", "Renders per minute per client IP, 0 to disable").Prefix(prefix).DocPrefix("rateLimit").IntVar(fs, &config.ipPerMinute, 60, overrides)
+	flags.New("IpBurst", "Burst of renders per client IP").Prefix(prefix).DocPrefix("rateLimit").IntVar(fs, &config.ipBurst, 20, overrides)
+	flags.New("UserPerMinute", "Commands per minute per chat user, 0 to disable").Prefix(prefix).DocPrefix("rateLimit").IntVar(fs, &config.userPerMinute, 10, overrides)
+	flags.New("UserBurst", "Burst of commands per chat user").Prefix(prefix).DocPrefix("rateLimit").IntVar(fs, &config.userBurst, 5, overrides)
+	flags.New("TeamPerMinute", "Commands per minute per Slack team or Discord guild, 0 to disable").Prefix(prefix).DocPrefix("rateLimit").IntVar(fs, &config.teamPerMinute, 60, overrides)
+	flags.New("TeamBurst", "Burst of commands per Slack team or Discord guild").Prefix(prefix).DocPrefix("rateLimit").IntVar(fs, &config.teamBurst, 20, overrides)
+	flags.New("TrustedProxies", "IPs or CIDRs of the reverse-proxies setting X-Forwarded-For, ignored if empty").Prefix(prefix).DocPrefix("rateLimit").StringSliceVar(fs, &config.trustedProxies, nil, overrides)
+
+	return &config
+}
+
+func New(config *Config, redisClient redis.Client) (Service, error) {
+	trustedProxies, err := parsePrefixes(config.trustedProxies)
+	if err != nil {
+		return Service{}, fmt.Errorf("parse trusted proxies: %w", err)
+	}
+
+	return Service{
+		redisClient:    redisClient,
+		trustedProxies: trustedProxies,
+		quotas: map[Scope]quota{
+			IP:   newQuota(config.ipPerMinute, config.ipBurst),
+			User: newQuota(config.userPerMinute, config.userBurst),
+			Team: newQuota(config.teamPerMinute, config.teamBurst),
+		},
+	}, nil
+}
+
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	var output []netip.Prefix
+
+	for _, value := range values {
+		if value = strings.TrimSpace(value); len(value) == 0 {
+			continue
+		}
+
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, err
+			}
+
+			output = append(output, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+
+		output = append(output, prefix.Masked())
+	}
+
+	return output, nil
+}
+
+func (s Service) enabled() bool {
+	return !model.IsNil(s.redisClient) && s.redisClient.Enabled()
+}
+
+// Allow consumes a token from the bucket of the given scope, answering the duration to wait before retrying when empty.
+// Limiting fails open: any Redis error lets the request through.
+func (s Service) Allow(ctx context.Context, scope Scope, id string) (bool, time.Duration) {
+	limit, ok := s.quotas[scope]
+	if !ok || !limit.enabled() || !s.enabled() || len(id) == 0 {
+		return true, 0
+	}
+
+	result, err := s.redisClient.Eval(ctx, tokenBucket, []string{bucketKey(scope, id)}, []any{limit.rate, limit.burst})
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "consume rate limit bucket", slog.String("scope", string(scope)), slog.Any("error", err))
+		return true, 0
+	}
+
+	allowed, retryAfter, err := parseResult(result)
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "parse rate limit bucket", slog.String("scope", string(scope)), slog.Any("error", err))
+		return true, 0
+	}
+
+	return allowed, retryAfter
+}
+
+func parseResult(result any) (bool, time.Duration, error) {
+	values, ok := result.([]any)
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("unexpected result `%v`", result)
+	}
+
+	allowed, ok := values[0].(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected allowance `%v`", values[0])
+	}
+
+	wait, ok := values[1].(int64)
+	if !ok {
+		return false, 0, fmt.Errorf("unexpected wait `%v`", values[1])
+	}
+
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
+
+// Middleware limits requests per client IP, answering 429 with a Retry-After header when exceeded
+func (s Service) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, retryAfter := s.Allow(r.Context(), IP, s.ClientIP(r)); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(RetryAfterSeconds(retryAfter)))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientIP extracts the client IP from the connection, or from the right-most X-Forwarded-For hop not set by a trusted proxy
+func (s Service) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !s.trusted(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+
+	for index := len(hops) - 1; index >= 0; index-- {
+		hop := strings.TrimSpace(hops[index])
+		if len(hop) == 0 {
+			continue
+		}
+
+		host = hop
+
+		if !s.trusted(hop) {
+			break
+		}
+	}
+
+	return host
+}
+
+func (s Service) trusted(host string) bool {
+	if len(s.trustedProxies) == 0 {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+
+	addr = addr.Unmap()
+
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RetryAfterSeconds rounds up the given duration to whole seconds
+func RetryAfterSeconds(retryAfter time.Duration) int {
+	return int(math.Ceil(retryAfter.Seconds()))
+}
+
+// bucketKey is the Redis hash of the token bucket of the id in the scope, e.g. `ratelimit:bucket:ip:192.0.2.1` under the version prefix
+func bucketKey(scope Scope, id string) string {
+	return version.Redis(fmt.Sprintf("ratelimit:bucket:%s:%s", scope, id))
+}
@@ -0,0 +1,266 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ViBiOh/httputils/v4/pkg/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type fakeRedis struct {
+	redis.Client
+	result any
+	err    error
+	keys   []string
+	args   []any
+}
+
+func (fr *fakeRedis) Enabled() bool {
+	return true
+}
+
+func (fr *fakeRedis) Eval(_ context.Context, _ *goredis.Script, keys []string, args []any) (any, error) {
+	fr.keys = keys
+	fr.args = args
+
+	return fr.result, fr.err
+}
+
+func TestAllow(t *testing.T) {
+	t.Parallel()
+
+	config := &Config{ipPerMinute: 60, ipBurst: 10}
+
+	cases := map[string]struct {
+		config      *Config
+		scope       Scope
+		id          string
+		result      any
+		err         error
+		want        bool
+		wantRetry   time.Duration
+		wantScripts bool
+	}{
+		"disabled scope": {
+			config: &Config{},
+			scope:  IP,
+			id:     "192.0.2.1",
+			want:   true,
+		},
+		"unknown scope": {
+			config: config,
+			scope:  Scope("unknown"),
+			id:     "192.0.2.1",
+			want:   true,
+		},
+		"empty id": {
+			config: config,
+			scope:  IP,
+			want:   true,
+		},
+		"token consumed": {
+			config:      config,
+			scope:       IP,
+			id:          "192.0.2.1",
+			result:      []any{int64(1), int64(0)},
+			want:        true,
+			wantScripts: true,
+		},
+		"bucket empty": {
+			config:      config,
+			scope:       IP,
+			id:          "192.0.2.1",
+			result:      []any{int64(0), int64(1500)},
+			want:        false,
+			wantRetry:   time.Millisecond * 1500,
+			wantScripts: true,
+		},
+		"redis error": {
+			config:      config,
+			scope:       IP,
+			id:          "192.0.2.1",
+			err:         errors.New("connection refused"),
+			want:        true,
+			wantScripts: true,
+		},
+		"unexpected result": {
+			config:      config,
+			scope:       IP,
+			id:          "192.0.2.1",
+			result:      "OK",
+			want:        true,
+			wantScripts: true,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			client := &fakeRedis{result: testCase.result, err: testCase.err}
+
+			service, err := New(testCase.config, client)
+			if err != nil {
+				t.Fatalf("New() = `%s`", err)
+			}
+
+			got, gotRetry := service.Allow(context.Background(), testCase.scope, testCase.id)
+			if got != testCase.want || gotRetry != testCase.wantRetry {
+				t.Errorf("Allow() = (%t, %s), want (%t, %s)", got, gotRetry, testCase.want, testCase.wantRetry)
+			}
+
+			if called := client.keys != nil; called != testCase.wantScripts {
+				t.Fatalf("Allow() evaluated script %t, want %t", called, testCase.wantScripts)
+			}
+
+			if !testCase.wantScripts {
+				return
+			}
+
+			if len(client.keys) != 1 || client.keys[0] != bucketKey(testCase.scope, testCase.id) {
+				t.Errorf("Allow() keys = %v, want [%s]", client.keys, bucketKey(testCase.scope, testCase.id))
+			}
+
+			if len(client.args) != 2 || client.args[0] != 1.0 || client.args[1] != 10.0 {
+				t.Errorf("Allow() args = %v, want [1 10]", client.args)
+			}
+		})
+	}
+}
+
+func TestNewQuota(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		perMinute int
+		burst     int
+		want      quota
+		enabled   bool
+	}{
+		"disabled": {
+			0,
+			10,
+			quota{rate: 0, burst: 10},
+			false,
+		},
+		"per second": {
+			120,
+			5,
+			quota{rate: 2, burst: 5},
+			true,
+		},
+		"burst of at least one": {
+			30,
+			0,
+			quota{rate: 0.5, burst: 1},
+			true,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			got := newQuota(testCase.perMinute, testCase.burst)
+			if got != testCase.want || got.enabled() != testCase.enabled {
+				t.Errorf("newQuota() = %+v enabled %t, want %+v enabled %t", got, got.enabled(), testCase.want, testCase.enabled)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		trusted    []string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		"remote address": {
+			remoteAddr: "192.0.2.1:1234",
+			want:       "192.0.2.1",
+		},
+		"forwarded without trusted proxy": {
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  []string{"203.0.113.7"},
+			want:       "192.0.2.1",
+		},
+		"forwarded from untrusted peer": {
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  []string{"203.0.113.7"},
+			want:       "192.0.2.1",
+		},
+		"forwarded by trusted proxy": {
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		"spoofed hop ignored": {
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"198.51.100.9, 203.0.113.7"},
+			want:       "203.0.113.7",
+		},
+		"chain of trusted proxies": {
+			trusted:    []string{"10.0.0.0/8", "172.16.0.1"},
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"198.51.100.9, 203.0.113.7", "172.16.0.1"},
+			want:       "203.0.113.7",
+		},
+		"only trusted hops": {
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			forwarded:  []string{"10.0.0.5, 10.0.0.3"},
+			want:       "10.0.0.5",
+		},
+		"ipv6 mapped proxy": {
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "[::ffff:10.0.0.2]:1234",
+			forwarded:  []string{"2001:db8::1"},
+			want:       "2001:db8::1",
+		},
+		"no forwarded header": {
+			trusted:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.2:1234",
+			want:       "10.0.0.2",
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			service, err := New(&Config{trustedProxies: testCase.trusted}, nil)
+			if err != nil {
+				t.Fatalf("New() = `%s`", err)
+			}
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = testCase.remoteAddr
+
+			for _, value := range testCase.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := service.ClientIP(req); got != testCase.want {
+				t.Errorf("ClientIP() = `%s`, want `%s`", got, testCase.want)
+			}
+		})
+	}
+}
+
+func TestNewInvalidTrustedProxies(t *testing.T) {
+	t.Parallel()
+
+	if _, err := New(&Config{trustedProxies: []string{"not-an-ip"}}, nil); err == nil {
+		t.Error("New() = nil, want an error")
+	}
+}
@@ -0,0 +1,104 @@
+// Package rediscache lists and evicts the entries a cache.Cache stores in Redis, for the cache administration
+package rediscache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ViBiOh/httputils/v4/pkg/cache"
+	"github.com/ViBiOh/httputils/v4/pkg/model"
+	"github.com/ViBiOh/httputils/v4/pkg/redis"
+)
+
+const scanSize = 64
+
+// Index manages the entries of a cache keyed by id
+type Index[V any] struct {
+	redisClient redis.Client
+	cache       *cache.Cache[string, V]
+	toKey       func(string) string
+}
+
+// New creates an index of the cache, toKey being the key function given to the cache
+func New[V any](redisClient redis.Client, cache *cache.Cache[string, V], toKey func(string) string) Index[V] {
+	return Index[V]{
+		redisClient: redisClient,
+		cache:       cache,
+		toKey:       toKey,
+	}
+}
+
+func (i Index[V]) enabled() bool {
+	return !model.IsNil(i.redisClient) && i.redisClient.Enabled()
+}
+
+// Cached lists the ids stored in cache
+func (i Index[V]) Cached(ctx context.Context) ([]string, error) {
+	if !i.enabled() {
+		return nil, nil
+	}
+
+	keys := make(chan string, scanSize)
+	scanned := make(chan struct{})
+	done := make(chan struct{})
+	prefix := i.toKey("")
+
+	var ids []string
+
+	// the scan may or may not close the channel, so its return is signaled apart and the buffered keys drained then
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case key, ok := <-keys:
+				if !ok {
+					return
+				}
+
+				ids = append(ids, strings.TrimPrefix(key, prefix))
+
+			case <-scanned:
+				for {
+					select {
+					case key, ok := <-keys:
+						if !ok {
+							return
+						}
+
+						ids = append(ids, strings.TrimPrefix(key, prefix))
+
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	err := i.redisClient.Scan(ctx, i.toKey("*"), keys, scanSize)
+	close(scanned)
+	<-done
+
+	if err != nil {
+		return nil, fmt.Errorf("scan `%s`: %w", prefix, err)
+	}
+
+	return ids, nil
+}
+
+// Evict removes the given ids from cache
+func (i Index[V]) Evict(ctx context.Context, ids ...string) error {
+	if !i.enabled() || len(ids) == 0 {
+		return nil
+	}
+
+	for _, id := range ids {
+		if err := i.cache.EvictOnSuccess(ctx, id, nil); err != nil {
+			return fmt.Errorf("evict `%s`: %w", id, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ViBiOh/httputils/v4/pkg/cache"
+	"github.com/ViBiOh/httputils/v4/pkg/redis"
+)
+
+var errScan = errors.New("scan failed")
+
+// fakeRedis scans its keys matching the pattern prefix, closing the output or not like the client may
+type fakeRedis struct {
+	redis.Client
+	err     error
+	keys    []string
+	closing bool
+	enabled bool
+}
+
+func (fr fakeRedis) Enabled() bool {
+	return fr.enabled
+}
+
+func (fr fakeRedis) Scan(_ context.Context, pattern string, output chan<- string, _ int64) error {
+	if fr.closing {
+		defer close(output)
+	}
+
+	prefix := strings.TrimSuffix(pattern, "*")
+
+	for _, key := range fr.keys {
+		if strings.HasPrefix(key, prefix) {
+			output <- key
+		}
+	}
+
+	return fr.err
+}
+
+func toKey(id string) string {
+	return "kitten:test:" + id
+}
+
+func TestCached(t *testing.T) {
+	t.Parallel()
+
+	// more keys than the channel buffers, for the scan to block on a slow reader
+	var many, manyIDs []string
+	for index := range scanSize * 3 {
+		id := string(rune('a'+index%26)) + string(rune('a'+index/26))
+
+		many = append(many, toKey(id))
+		manyIDs = append(manyIDs, id)
+	}
+
+	cases := map[string]struct {
+		client  redis.Client
+		want    []string
+		wantErr error
+	}{
+		"no client": {
+			nil,
+			nil,
+			nil,
+		},
+		"disabled": {
+			fakeRedis{keys: []string{toKey("a")}},
+			nil,
+			nil,
+		},
+		"closed by the scan": {
+			fakeRedis{enabled: true, closing: true, keys: []string{toKey("a"), "other:b", toKey("c")}},
+			[]string{"a", "c"},
+			nil,
+		},
+		"left open by the scan": {
+			fakeRedis{enabled: true, keys: []string{toKey("a"), "other:b", toKey("c")}},
+			[]string{"a", "c"},
+			nil,
+		},
+		"more than a page": {
+			fakeRedis{enabled: true, keys: many},
+			manyIDs,
+			nil,
+		},
+		"empty": {
+			fakeRedis{enabled: true, closing: true},
+			nil,
+			nil,
+		},
+		"error": {
+			fakeRedis{enabled: true, keys: []string{toKey("a")}, err: errScan},
+			nil,
+			errScan,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			index := New(testCase.client, cache.New[string, string](nil, toKey, nil, nil), toKey)
+
+			got, err := index.Cached(context.Background())
+
+			if !errors.Is(err, testCase.wantErr) {
+				t.Errorf("Cached() = `%v`, want `%v`", err, testCase.wantErr)
+			}
+
+			if !slices.Equal(got, testCase.want) {
+				t.Errorf("Cached() = %v, want %v", got, testCase.want)
+			}
+		})
+	}
+}
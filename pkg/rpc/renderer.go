@@ -0,0 +1,172 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ViBiOh/kitten/pkg/kitten"
+	"github.com/ViBiOh/kitten/pkg/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const chunkSize = 64 << 10
+
+type renderer struct {
+	pb.UnimplementedKittenRendererServer
+	kitten kitten.Service
+}
+
+func (r renderer) Render(ctx context.Context, request *pb.RenderRequest) (*pb.RenderResponse, error) {
+	var buffer bytes.Buffer
+
+	metadata, err := r.kitten.Render(ctx, toRenderRequest(request), &buffer, noFlush)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.RenderResponse{
+		Metadata: fromRenderMetadata(metadata),
+		Content:  buffer.Bytes(),
+	}, nil
+}
+
+func (r renderer) RenderStream(request *pb.RenderRequest, stream grpc.ServerStreamingServer[pb.RenderChunk]) error {
+	writer := &chunkWriter{stream: stream}
+
+	metadata, err := r.kitten.Render(stream.Context(), toRenderRequest(request), writer, writer.flush)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	return stream.Send(&pb.RenderChunk{Payload: &pb.RenderChunk_Metadata{Metadata: fromRenderMetadata(metadata)}})
+}
+
+func (r renderer) Search(ctx context.Context, request *pb.SearchRequest) (*pb.SearchResponse, error) {
+	var kind string
+
+	switch request.GetKind() {
+	case pb.Kind_KIND_IMAGE:
+		kind = "image"
+	case pb.Kind_KIND_GIF:
+		kind = "gif"
+	default:
+		return nil, status.Error(codes.InvalidArgument, "kind is required")
+	}
+
+	result, err := r.kitten.Search(ctx, kind, request.GetQuery(), request.GetPos())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.SearchResponse{
+		Kind: request.GetKind(),
+		Source: &pb.Source{
+			Provider: result.Source.Provider,
+			Id:       result.Source.ID,
+			Url:      result.Source.URL,
+		},
+		Attribution: fromAttribution(result.Attribution),
+		Next:        result.Next,
+	}, nil
+}
+
+// chunkWriter sends what is written as content chunks, buffering until flushed
+type chunkWriter struct {
+	stream grpc.ServerStreamingServer[pb.RenderChunk]
+	buffer bytes.Buffer
+}
+
+func (cw *chunkWriter) Write(content []byte) (int, error) {
+	cw.buffer.Write(content)
+
+	for cw.buffer.Len() >= chunkSize {
+		if err := cw.send(cw.buffer.Next(chunkSize)); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(content), nil
+}
+
+func (cw *chunkWriter) flush() error {
+	if cw.buffer.Len() == 0 {
+		return nil
+	}
+
+	return cw.send(cw.buffer.Next(cw.buffer.Len()))
+}
+
+func (cw *chunkWriter) send(content []byte) error {
+	return cw.stream.Send(&pb.RenderChunk{Payload: &pb.RenderChunk_Content{Content: bytes.Clone(content)}})
+}
+
+func noFlush() error {
+	return nil
+}
+
+func toRenderRequest(request *pb.RenderRequest) kitten.RenderRequest {
+	output := kitten.RenderRequest{
+		Source: kitten.RenderSource{
+			Provider: request.GetSource().GetProvider(),
+			ID:       request.GetSource().GetId(),
+			URL:      request.GetSource().GetUrl(),
+		},
+		Captions: request.GetCaptions(),
+		Layout:   request.GetLayout(),
+		Format:   request.GetFormat(),
+		Width:    int(request.GetWidth()),
+		Quality:  int(request.GetQuality()),
+	}
+
+	if style := request.GetStyle(); style != nil {
+		output.Style = &kitten.Style{Font: style.GetFont(), Stroke: style.GetStroke()}
+	}
+
+	return output
+}
+
+func fromRenderMetadata(metadata kitten.RenderMetadata) *pb.RenderMetadata {
+	return &pb.RenderMetadata{
+		Attribution: fromAttribution(metadata.Attribution),
+		Url:         metadata.URL,
+		CacheKey:    metadata.CacheKey,
+		Format:      metadata.Format,
+		ContentType: "image/" + metadata.Format,
+		Width:       int32(metadata.Width),
+		Height:      int32(metadata.Height),
+		Size:        metadata.Size,
+	}
+}
+
+func fromAttribution(attribution kitten.Attribution) *pb.Attribution {
+	return &pb.Attribution{
+		Provider:  attribution.Provider,
+		Url:       attribution.URL,
+		Author:    attribution.Author,
+		AuthorUrl: attribution.AuthorURL,
+	}
+}
+
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	switch kitten.RenderErrorStatus(err) {
+	case http.StatusBadRequest:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case http.StatusNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case http.StatusServiceUnavailable:
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,161 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/ViBiOh/flags"
+	"github.com/ViBiOh/kitten/pkg/apikey"
+	"github.com/ViBiOh/kitten/pkg/kitten"
+	"github.com/ViBiOh/kitten/pkg/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var scopes = map[string]apikey.Scope{
+	pb.KittenRenderer_Render_FullMethodName:       apikey.Render,
+	pb.KittenRenderer_RenderStream_FullMethodName: apikey.Render,
+	pb.KittenRenderer_Search_FullMethodName:       apikey.Search,
+}
+
+type Service struct {
+	server  *grpc.Server
+	done    chan struct{}
+	address string
+}
+
+type Config struct {
+	address string
+	port    uint
+}
+
+func Flags(fs *flag.FlagSet, prefix string, overrides ...flags.Override) *Config {
+	var config Config
+
+	flags.New("Address", "Listen address").Prefix(prefix).DocPrefix("grpc").StringVar(fs, &config.address, "", overrides)
+	flags.New("Port", "Listen port, 0 to disable").Prefix(prefix).DocPrefix("grpc").UintVar(fs, &config.port, 0, overrides)
+
+	return &config
+}
+
+func New(config *Config, kittenService kitten.Service, apiKeyService apikey.Service) Service {
+	service := Service{
+		done: make(chan struct{}),
+	}
+
+	if config.port == 0 {
+		return service
+	}
+
+	service.address = net.JoinHostPort(config.address, fmt.Sprintf("%d", config.port))
+
+	service.server = newServer(kittenService, apiKeyService)
+
+	return service
+}
+
+func newServer(kittenService kitten.Service, apiKeyService apikey.Service) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryAuthenticate(apiKeyService)),
+		grpc.ChainStreamInterceptor(streamAuthenticate(apiKeyService)),
+	)
+
+	pb.RegisterKittenRendererServer(server, renderer{kitten: kittenService})
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+
+	return server
+}
+
+// Start serves gRPC requests until the context is done, then waits for pending ones
+func (s Service) Start(ctx context.Context) {
+	defer close(s.done)
+
+	if s.server == nil {
+		return
+	}
+
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "listen gRPC", slog.String("address", s.address), slog.Any("error", err))
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.server.GracefulStop()
+	}()
+
+	slog.LogAttrs(ctx, slog.LevelInfo, "gRPC server listening", slog.String("address", s.address))
+
+	if err = s.server.Serve(listener); err != nil {
+		slog.LogAttrs(ctx, slog.LevelError, "serve gRPC", slog.Any("error", err))
+	}
+}
+
+// Done is closed when the server is stopped
+func (s Service) Done() <-chan struct{} {
+	return s.done
+}
+
+func unaryAuthenticate(apiKeyService apikey.Service) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, apiKeyService, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func streamAuthenticate(apiKeyService apikey.Service) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(stream.Context(), apiKeyService, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticate requires an API key on the renderer methods, whether API keys are enabled on the render API or not
+func authenticate(ctx context.Context, apiKeyService apikey.Service, method string) (context.Context, error) {
+	scope, ok := scopes[method]
+	if !ok {
+		return ctx, nil
+	}
+
+	ctx, err := apiKeyService.Authenticate(ctx, strings.Join(metadata.ValueFromIncomingContext(ctx, strings.ToLower(apikey.Header)), ""), scope)
+
+	switch {
+	case err == nil:
+		return ctx, nil
+	case errors.Is(err, apikey.ErrMissingKey), errors.Is(err, apikey.ErrUnknownKey):
+		return ctx, status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, apikey.ErrMissingScope):
+		return ctx, status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, apikey.ErrUnavailable):
+		return ctx, status.Error(codes.Unavailable, err.Error())
+	default:
+		return ctx, status.Error(codes.Internal, err.Error())
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (as authenticatedStream) Context() context.Context {
+	return as.ctx
+}
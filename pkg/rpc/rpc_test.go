@@ -0,0 +1,507 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image/gif"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ViBiOh/kitten/pkg/apikey"
+	"github.com/ViBiOh/kitten/pkg/fakeproviders"
+	"github.com/ViBiOh/kitten/pkg/fetch"
+	"github.com/ViBiOh/kitten/pkg/kitten"
+	"github.com/ViBiOh/kitten/pkg/klipy"
+	"github.com/ViBiOh/kitten/pkg/pb"
+	"github.com/ViBiOh/kitten/pkg/ratelimit"
+	"github.com/ViBiOh/kitten/pkg/unsplash"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	renderKey = "render-key"
+	searchKey = "search-key"
+)
+
+// newClient serves the renderer over an in-memory connection, its providers pointing at the fake providers server
+func newClient(t *testing.T) pb.KittenRendererClient {
+	t.Helper()
+
+	ctx := context.Background()
+
+	providersServer := httptest.NewServer(fakeproviders.New().Handler())
+	t.Cleanup(providersServer.Close)
+
+	keysFile := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(keysFile, fmt.Appendf(nil, "render:%s:render,search\nsearch:%s:search\n", apikey.Hash(renderKey), apikey.Hash(searchKey)), 0o600); err != nil {
+		t.Fatalf("write keys: %s", err)
+	}
+
+	fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+
+	unsplashConfig := unsplash.Flags(fs, "unsplash")
+	klipyConfig := klipy.Flags(fs, "klipy")
+	fetchConfig := fetch.Flags(fs, "fetch")
+	apiKeyConfig := apikey.Flags(fs, "apiKey")
+
+	if err := fs.Parse([]string{
+		"-unsplashURL", providersServer.URL + "/unsplash",
+		"-unsplashAccessKey", "fake",
+		"-klipyURL", providersServer.URL + "/klipy",
+		"-klipyApiKey", "fake",
+		"-apiKeyFile", keysFile,
+		"-fetchAllowedSchemes", "http",
+		"-fetchAllowPrivate",
+	}); err != nil {
+		t.Fatalf("parse flags: %s", err)
+	}
+
+	apiKeyService, err := apikey.New(apiKeyConfig, nil, nil)
+	if err != nil {
+		t.Fatalf("create api keys: %s", err)
+	}
+
+	registry := kitten.NewRegistry([]string{"unsplash"}, []string{"klipy"}).
+		WithImage(kitten.NewUnsplashProvider(unsplash.New(ctx, unsplashConfig, nil, nil))).
+		WithGif(kitten.NewKlipyProvider(klipy.New(ctx, klipyConfig, nil, nil)))
+
+	kittenService := kitten.New(&kitten.Config{
+		TmpFolder:        t.TempDir(),
+		RenderWorkers:    2,
+		RenderQueue:      time.Second * 5,
+		RenderTimeout:    time.Second * 30,
+		MaxSourceSize:    4 << 20,
+		MaxPixels:        4096 * 4096,
+		MaxFrames:        300,
+		MaxFramePixels:   100_000_000,
+		MaxCaption:       280,
+		ProviderFailures: 3,
+		ProviderCooldown: time.Minute,
+		BatchWorkers:     1,
+	}, registry, ratelimit.Service{}, fetch.New(fetchConfig, nil, nil), nil, nil, nil, "http://kitten.example")
+
+	listener := bufconn.Listen(1 << 20)
+	server := newServer(kittenService, apiKeyService)
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %s", err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return pb.NewKittenRendererClient(conn)
+}
+
+func withKey(key string) context.Context {
+	if len(key) == 0 {
+		return context.Background()
+	}
+
+	return metadata.AppendToOutgoingContext(context.Background(), apikey.Header, key)
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(t)
+
+	cases := map[string]struct {
+		key        string
+		request    *pb.RenderRequest
+		wantCode   codes.Code
+		wantFormat string
+	}{
+		"image": {
+			renderKey,
+			&pb.RenderRequest{Source: &pb.Source{Provider: "unsplash", Id: "abc"}, Captions: []string{"hello"}},
+			codes.OK,
+			"jpeg",
+		},
+		"missing key": {
+			"",
+			&pb.RenderRequest{Source: &pb.Source{Provider: "unsplash", Id: "abc"}},
+			codes.Unauthenticated,
+			"",
+		},
+		"unknown key": {
+			"unknown",
+			&pb.RenderRequest{Source: &pb.Source{Provider: "unsplash", Id: "abc"}},
+			codes.Unauthenticated,
+			"",
+		},
+		"missing scope": {
+			searchKey,
+			&pb.RenderRequest{Source: &pb.Source{Provider: "unsplash", Id: "abc"}},
+			codes.PermissionDenied,
+			"",
+		},
+		"unknown provider": {
+			renderKey,
+			&pb.RenderRequest{Source: &pb.Source{Provider: "unknown", Id: "abc"}},
+			codes.InvalidArgument,
+			"",
+		},
+		"not found": {
+			renderKey,
+			&pb.RenderRequest{Source: &pb.Source{Provider: "unsplash", Id: fakeproviders.NotFoundQuery}},
+			codes.NotFound,
+			"",
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			response, err := client.Render(withKey(testCase.key), testCase.request)
+			if got := status.Code(err); got != testCase.wantCode {
+				t.Fatalf("Render() = %s, want %s: %v", got, testCase.wantCode, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got := response.GetMetadata().GetFormat(); got != testCase.wantFormat {
+				t.Errorf("Render() format = `%s`, want `%s`", got, testCase.wantFormat)
+			}
+
+			if got, want := response.GetMetadata().GetSize(), int64(len(response.GetContent())); got != want {
+				t.Errorf("Render() size = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestRenderStream(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(t)
+
+	cases := map[string]struct {
+		key      string
+		request  *pb.RenderRequest
+		wantCode codes.Code
+	}{
+		"gif": {
+			renderKey,
+			&pb.RenderRequest{Source: &pb.Source{Provider: "klipy", Id: "abc"}, Captions: []string{"hello"}},
+			codes.OK,
+		},
+		"missing key": {
+			"",
+			&pb.RenderRequest{Source: &pb.Source{Provider: "klipy", Id: "abc"}},
+			codes.Unauthenticated,
+		},
+		"not found": {
+			renderKey,
+			&pb.RenderRequest{Source: &pb.Source{Provider: "klipy", Id: fakeproviders.NotFoundQuery}},
+			codes.NotFound,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			stream, err := client.RenderStream(withKey(testCase.key), testCase.request)
+			if err != nil {
+				t.Fatalf("RenderStream(): %s", err)
+			}
+
+			var (
+				content     bytes.Buffer
+				contents    int
+				rendered    *pb.RenderMetadata
+				streamError error
+			)
+
+			for {
+				chunk, err := stream.Recv()
+				if err != nil {
+					streamError = err
+					break
+				}
+
+				if rendered != nil {
+					t.Fatalf("RenderStream() sent a chunk after the metadata")
+				}
+
+				switch payload := chunk.GetPayload().(type) {
+				case *pb.RenderChunk_Content:
+					contents++
+					content.Write(payload.Content)
+				case *pb.RenderChunk_Metadata:
+					rendered = payload.Metadata
+				}
+			}
+
+			if testCase.wantCode != codes.OK {
+				if got := status.Code(streamError); got != testCase.wantCode {
+					t.Errorf("RenderStream() = %s, want %s: %v", got, testCase.wantCode, streamError)
+				}
+
+				return
+			}
+
+			if rendered == nil {
+				t.Fatalf("RenderStream() ended with %v before the metadata", streamError)
+			}
+
+			if contents == 0 {
+				t.Fatalf("RenderStream() sent no content")
+			}
+
+			if rendered.GetFormat() != "gif" {
+				t.Errorf("RenderStream() format = `%s`, want `gif`", rendered.GetFormat())
+			}
+
+			if _, err = gif.DecodeAll(&content); err != nil {
+				t.Errorf("decode streamed gif: %s", err)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	client := newClient(t)
+
+	cases := map[string]struct {
+		key          string
+		request      *pb.SearchRequest
+		wantCode     codes.Code
+		wantProvider string
+	}{
+		"image": {
+			searchKey,
+			&pb.SearchRequest{Kind: pb.Kind_KIND_IMAGE, Query: "cat"},
+			codes.OK,
+			"unsplash",
+		},
+		"gif": {
+			searchKey,
+			&pb.SearchRequest{Kind: pb.Kind_KIND_GIF, Query: "cat"},
+			codes.OK,
+			"klipy",
+		},
+		"missing kind": {
+			searchKey,
+			&pb.SearchRequest{Query: "cat"},
+			codes.InvalidArgument,
+			"",
+		},
+		"missing key": {
+			"",
+			&pb.SearchRequest{Kind: pb.Kind_KIND_IMAGE, Query: "cat"},
+			codes.Unauthenticated,
+			"",
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			response, err := client.Search(withKey(testCase.key), testCase.request)
+			if got := status.Code(err); got != testCase.wantCode {
+				t.Fatalf("Search() = %s, want %s: %v", got, testCase.wantCode, err)
+			}
+
+			if got := response.GetSource().GetProvider(); got != testCase.wantProvider {
+				t.Errorf("Search() provider = `%s`, want `%s`", got, testCase.wantProvider)
+			}
+		})
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		err  error
+		want codes.Code
+	}{
+		"canceled": {
+			fmt.Errorf("render: %w", context.Canceled),
+			codes.Canceled,
+		},
+		"deadline": {
+			fmt.Errorf("render: %w", context.DeadlineExceeded),
+			codes.DeadlineExceeded,
+		},
+		"limit": {
+			fmt.Errorf("caption: %w", kitten.ErrLimitExceeded),
+			codes.InvalidArgument,
+		},
+		"forbidden host": {
+			fetch.ErrForbiddenHost,
+			codes.InvalidArgument,
+		},
+		"not found": {
+			kitten.ErrNotFound,
+			codes.NotFound,
+		},
+		"overloaded": {
+			kitten.ErrOverloaded,
+			codes.Unavailable,
+		},
+		"unavailable": {
+			kitten.ErrUnavailable,
+			codes.Unavailable,
+		},
+		"unknown": {
+			errors.New("boom"),
+			codes.Internal,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			if got := status.Code(toStatus(testCase.err)); got != testCase.want {
+				t.Errorf("toStatus() = %s, want %s", got, testCase.want)
+			}
+		})
+	}
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	err    error
+	chunks [][]byte
+}
+
+func (fs *fakeStream) Send(chunk *pb.RenderChunk) error {
+	if fs.err != nil {
+		return fs.err
+	}
+
+	fs.chunks = append(fs.chunks, chunk.GetContent())
+
+	return nil
+}
+
+func TestChunkWriter(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		err        error
+		writes     []int
+		wantChunks []int
+		wantErr    bool
+	}{
+		"nothing": {
+			nil,
+			nil,
+			nil,
+			false,
+		},
+		"buffered until flush": {
+			nil,
+			[]int{10, 20},
+			[]int{30},
+			false,
+		},
+		"exact chunk": {
+			nil,
+			[]int{chunkSize},
+			[]int{chunkSize},
+			false,
+		},
+		"split": {
+			nil,
+			[]int{chunkSize - 10, 30},
+			[]int{chunkSize, 20},
+			false,
+		},
+		"several chunks in one write": {
+			nil,
+			[]int{chunkSize*2 + 5},
+			[]int{chunkSize, chunkSize, 5},
+			false,
+		},
+		"send error": {
+			errors.New("broken stream"),
+			[]int{chunkSize},
+			nil,
+			true,
+		},
+	}
+
+	for intention, testCase := range cases {
+		t.Run(intention, func(t *testing.T) {
+			t.Parallel()
+
+			stream := &fakeStream{err: testCase.err}
+			writer := &chunkWriter{stream: stream}
+
+			var (
+				written bytes.Buffer
+				err     error
+			)
+
+			for index, size := range testCase.writes {
+				content := bytes.Repeat([]byte{byte(index + 1)}, size)
+				written.Write(content)
+
+				if _, err = writer.Write(content); err != nil {
+					break
+				}
+			}
+
+			if err == nil {
+				err = writer.flush()
+			}
+
+			if gotErr := err != nil; gotErr != testCase.wantErr {
+				t.Fatalf("chunkWriter error = %v, want one %t", err, testCase.wantErr)
+			}
+
+			if testCase.wantErr {
+				return
+			}
+
+			var sizes []int
+			for _, chunk := range stream.chunks {
+				sizes = append(sizes, len(chunk))
+			}
+
+			if fmt.Sprint(sizes) != fmt.Sprint(testCase.wantChunks) {
+				t.Errorf("chunkWriter sent chunks of %v, want %v", sizes, testCase.wantChunks)
+			}
+
+			if got := bytes.Join(stream.chunks, nil); !bytes.Equal(got, written.Bytes()) {
+				t.Errorf("chunkWriter sent %d bytes, want the %d written", len(got), written.Len())
+			}
+		})
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/ViBiOh/httputils/v4/pkg/httpjson"
 	"github.com/ViBiOh/httputils/v4/pkg/redis"
 	"github.com/ViBiOh/httputils/v4/pkg/request"
+	"github.com/ViBiOh/kitten/pkg/rediscache"
 	"github.com/ViBiOh/kitten/pkg/version"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -46,9 +47,8 @@ type unsplashResponse struct {
 	ID    string            `json:"id"`
 }
 
-const root = "https://api.unsplash.com"
-
 var (
+	ErrNotFound          = errors.New("no image found")
 	ErrRateLimitExceeded = errors.New("rate limit exceeded")
 
 	cacheDuration = time.Hour * 24 * 7
@@ -56,6 +56,7 @@ var (
 
 type Service struct {
 	cache       *cache.Cache[string, Image]
+	index       rediscache.Index[Image]
 	appName     string
 	req         request.Request
 	downloadReq request.Request
@@ -64,6 +65,7 @@ type Service struct {
 type Config struct {
 	appName   string
 	accessKey string
+	url       string
 }
 
 func Flags(fs *flag.FlagSet, prefix string, overrides ...flags.Override) *Config {
@@ -71,13 +73,14 @@ func Flags(fs *flag.FlagSet, prefix string, overrides ...flags.Override) *Config
 
 	flags.New("Name", "Unsplash App name").Prefix(prefix).DocPrefix("unsplash").StringVar(fs, &config.appName, "SayIt", overrides)
 	flags.New("AccessKey", "Unsplash Access Key").Prefix(prefix).DocPrefix("unsplash").StringVar(fs, &config.accessKey, "", overrides)
+	flags.New("URL", "Unsplash API base URL").Prefix(prefix).DocPrefix("unsplash").StringVar(fs, &config.url, "https://api.unsplash.com", overrides)
 
 	return &config
 }
 
 func New(ctx context.Context, config *Config, redisClient redis.Client, tracerProvider trace.TracerProvider) Service {
 	service := Service{
-		req:         request.Get(root).Header("Authorization", fmt.Sprintf("Client-ID %s", config.accessKey)).WithClient(request.CreateClient(time.Second*30, request.NoRedirection)),
+		req:         request.Get(strings.TrimSuffix(config.url, "/")).Header("Authorization", fmt.Sprintf("Client-ID %s", config.accessKey)).WithClient(request.CreateClient(time.Second*30, request.NoRedirection)),
 		downloadReq: request.New().Header("Authorization", fmt.Sprintf("Client-ID %s", config.accessKey)),
 		appName:     config.appName,
 	}
@@ -85,11 +88,7 @@ func New(ctx context.Context, config *Config, redisClient redis.Client, tracerPr
 	service.cache = cache.New(redisClient, cacheID, func(ctx context.Context, id string) (Image, error) {
 		resp, err := service.req.Path("/photos/%s", url.PathEscape(id)).Send(ctx, nil)
 		if err != nil {
-			if strings.Contains(err.Error(), "Rate Limit Exceeded") {
-				return Image{}, ErrRateLimitExceeded
-			}
-
-			return Image{}, httperror.FromResponse(resp, fmt.Errorf("get image `%s`: %w", id, err))
+			return Image{}, handleError(resp, fmt.Errorf("get image `%s`: %w", id, err))
 		}
 
 		return service.getImageFromResponse(resp)
@@ -98,6 +97,8 @@ func New(ctx context.Context, config *Config, redisClient redis.Client, tracerPr
 		WithExtendOnHit(ctx, cacheDuration/4, 50).
 		WithClientSideCaching(ctx, "kitten_unsplash", 50)
 
+	service.index = rediscache.New(redisClient, service.cache, cacheID)
+
 	return service
 }
 
@@ -116,21 +117,13 @@ func (s Service) Get(ctx context.Context, id string) (Image, error) {
 func (s Service) Search(ctx context.Context, query string) (Image, error) {
 	resp, err := s.req.Path("/photos/random?query=%s&orientation=landscape", url.QueryEscape(query)).Send(ctx, nil)
 	if err != nil {
-		if strings.Contains(err.Error(), "Rate Limit Exceeded") {
-			return Image{}, ErrRateLimitExceeded
-		}
-
-		if httpError, ok := errors.AsType[request.Error](err); ok && httpError.StatusCode == http.StatusNotFound {
-			return Image{}, httperror.FromResponse(resp, fmt.Errorf("nothing was found for the query `%s`", query))
-		}
-
-		return Image{}, httperror.FromResponse(resp, fmt.Errorf("get random image for `%s`: %w", query, err))
+		return Image{}, handleError(resp, fmt.Errorf("get random image for `%s`: %w", query, err))
 	}
 
 	image, err := s.getImageFromResponse(resp)
-	if err != nil {
+	if err == nil {
 		go func(ctx context.Context) {
-			if err = s.cache.Store(ctx, image.ID, image); err != nil {
+			if err := s.cache.Store(ctx, image.ID, image); err != nil {
 				slog.LogAttrs(ctx, slog.LevelError, "save image in cache", slog.Any("error", err))
 			}
 		}(context.WithoutCancel(ctx))
@@ -155,6 +148,28 @@ func (s Service) getImageFromResponse(resp *http.Response) (Image, error) {
 	}, nil
 }
 
+// Cached lists the ids stored in cache
+func (s Service) Cached(ctx context.Context) ([]string, error) {
+	return s.index.Cached(ctx)
+}
+
+// Evict removes the given ids from cache
+func (s Service) Evict(ctx context.Context, ids ...string) error {
+	return s.index.Evict(ctx, ids...)
+}
+
+func handleError(resp *http.Response, err error) error {
+	if strings.Contains(err.Error(), "Rate Limit Exceeded") {
+		return ErrRateLimitExceeded
+	}
+
+	if httpError, ok := errors.AsType[request.Error](err); ok && httpError.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+
+	return httperror.FromResponse(resp, err)
+}
+
 func cacheID(id string) string {
 	return version.Redis("unsplash:" + id)
 }